@@ -0,0 +1,116 @@
+// +build darwin
+
+package mptcp
+
+import (
+	"context"
+	"net"
+	"syscall"
+)
+
+const (
+	// sysctlMPTCPEnable is the name of the Darwin-specific sysctl which
+	// reports whether MPTCP is enabled.
+	sysctlMPTCPEnable = "net.inet.mptcp.enable"
+)
+
+// mptcpEnabled uses the net.inet.mptcp.enable sysctl to determine if the
+// current host supports MPTCP, until ctx is done.
+var mptcpEnabled = func(ctx context.Context) (bool, error) {
+	// Do not query the sysctl if ctx is already done
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	return mptcpSysctlEnabledDarwin(sysctlMPTCPEnable)
+}
+
+// mptcpSysctlEnabledDarwin reads the sysctl with the input name, and reports
+// whether its value is nonzero.  If the sysctl does not exist, as on
+// releases without MPTCP support, false is returned without an error.
+func mptcpSysctlEnabledDarwin(name string) (bool, error) {
+	v, err := syscall.SysctlUint32(name)
+	if err != nil {
+		if err == syscall.ENOENT {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return v != 0, nil
+}
+
+// checkMPTCP checks if an input host string and uint16 port are present in
+// this Darwin machine's MPTCP active connections, until ctx is done.
+var checkMPTCP = func(ctx context.Context, host string, port uint16) (bool, error) {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false, ErrInvalidIPAddress
+	}
+
+	// Do not query the sysctl if ctx is already done
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	conns, err := listPCBListConnections()
+	if err != nil {
+		return false, err
+	}
+
+	return len(findConnections(conns, ip, port)) > 0, nil
+}
+
+// checkLocalMPTCP checks if an input host string and uint16 port are the
+// local address of any of this Darwin machine's MPTCP active connections.
+var checkLocalMPTCP = func(host string, port uint16) (bool, error) {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false, ErrInvalidIPAddress
+	}
+
+	conns, err := listPCBListConnections()
+	if err != nil {
+		return false, err
+	}
+
+	for _, c := range conns {
+		if c.LocalAddr.IP.Equal(ip) && c.LocalAddr.Port == int(port) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// listConnections uses the Darwin net.inet.mptcp.pcblist sysctl to retrieve
+// all active MPTCP connections.
+var listConnections = func() ([]Connection, error) {
+	return defaultChecker.listConnections()
+}
+
+// listConnections uses the Darwin net.inet.mptcp.pcblist sysctl to retrieve
+// all active MPTCP connections.  Darwin has no connections table, so the
+// Checker's table options do not apply.
+func (c *Checker) listConnections() ([]Connection, error) {
+	return listPCBListConnections()
+}
+
+// forEachConnection uses the Darwin net.inet.mptcp.pcblist sysctl to retrieve
+// each active MPTCP connection, invoking fn with each connection until fn
+// returns false.
+var forEachConnection = func(fn func(c Connection) bool) error {
+	conns, err := listPCBListConnections()
+	if err != nil {
+		return err
+	}
+
+	for _, c := range conns {
+		if !fn(c) {
+			break
+		}
+	}
+
+	return nil
+}
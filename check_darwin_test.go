@@ -0,0 +1,55 @@
+// +build darwin
+
+package mptcp
+
+import (
+	"context"
+	"syscall"
+	"testing"
+)
+
+// TestDarwin_mptcpEnabled verifies that mptcpEnabled reports the value of
+// the net.inet.mptcp.enable sysctl.
+func TestDarwin_mptcpEnabled(t *testing.T) {
+	enabled, err := mptcpEnabled(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := syscall.SysctlUint32(sysctlMPTCPEnable)
+	if err == syscall.ENOENT {
+		if enabled {
+			t.Fatalf("%s does not exist, but mptcpEnabled returned true", sysctlMPTCPEnable)
+		}
+
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := v != 0; enabled != want {
+		t.Fatalf("%s is %d, but mptcpEnabled returned %v", sysctlMPTCPEnable, v, enabled)
+	}
+}
+
+// TestDarwin_mptcpSysctlEnabledDarwin verifies that a missing sysctl is
+// reported as disabled, without an error.
+func TestDarwin_mptcpSysctlEnabledDarwin(t *testing.T) {
+	ok, err := mptcpSysctlEnabledDarwin("net.inet.mptcp.nonexistent")
+	if ok || err != nil {
+		t.Fatalf("missing sysctl should return (false, nil), but returned: (%v, %v)", ok, err)
+	}
+}
+
+// TestDarwin_mptcpEnabledCanceled verifies that mptcpEnabled returns the
+// error of a context which is already done.
+func TestDarwin_mptcpEnabledCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ok, err := mptcpEnabled(ctx)
+	if ok || err != context.Canceled {
+		t.Fatalf("canceled mptcpEnabled should return (false, %v), but returned: (%v, %v)", context.Canceled, ok, err)
+	}
+}
@@ -5,13 +5,18 @@ package mptcp
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"math"
 	"net"
 	"os"
+	"strconv"
 	"strings"
+	"syscall"
 )
 
 const (
@@ -19,16 +24,71 @@ const (
 	// the active MPTCP connections table.
 	procMPTCP = "/proc/net/mptcp"
 
+	// procSysMPTCP is the location of the sysctl directory which is present
+	// on Linux kernels with mainline MPTCP support.
+	procSysMPTCP = "/proc/sys/net/mptcp"
+
+	// procSysMPTCPEnabled is the location of the net.mptcp.enabled sysctl,
+	// which reports whether MPTCP sockets may be created on Linux kernels
+	// with mainline MPTCP support.
+	procSysMPTCPEnabled = procSysMPTCP + "/enabled"
+
 	// mptcpTableColumns is the number of columns in a valid Linux MPTCP
 	// connections table.
 	mptcpTableColumns = 10
+
+	// mptcpExtendedTableColumns is the number of columns in a Linux MPTCP
+	// connections table which also reports timer and retransmit state.
+	mptcpExtendedTableColumns = 12
 )
 
 var (
 	// mptcpTableHeader is the header from the top of a MPTCP connections table.
 	mptcpTableHeader = []byte(`  sl  loc_tok  rem_tok  v6 local_address                         remote_address                        st ns tx_queue rx_queue inode`)
+
+	// mptcpExtendedTableHeader is the header from the top of a MPTCP
+	// connections table which appends the timer and retransmit columns of
+	// the kernel's TCP table to each row.
+	mptcpExtendedTableHeader = []byte(`  sl  loc_tok  rem_tok  v6 local_address                         remote_address                        st ns tx_queue rx_queue inode tr tm->when retrnsmt`)
 )
 
+// A mptcpTableFormat is a known layout of a Linux MPTCP connections table.
+type mptcpTableFormat struct {
+	version FormatVersion
+	header  []byte
+	columns int
+}
+
+// mptcpTableFormats are the known layouts of a Linux MPTCP connections table.
+var mptcpTableFormats = []mptcpTableFormat{
+	{version: FormatV1, header: mptcpTableHeader, columns: mptcpTableColumns},
+	{version: FormatV2, header: mptcpExtendedTableHeader, columns: mptcpExtendedTableColumns},
+}
+
+// headerForFormat returns a copy of the header for the known layout of a
+// Linux MPTCP connections table with the input version.
+var headerForFormat = func(v FormatVersion) []byte {
+	for _, f := range mptcpTableFormats {
+		if f.version == v {
+			return append([]byte(nil), f.header...)
+		}
+	}
+
+	return nil
+}
+
+// lookupMPTCPTableFormat returns the known layout of a Linux MPTCP connections
+// table which uses the input header line.
+func lookupMPTCPTableFormat(header []byte) (mptcpTableFormat, bool) {
+	for _, f := range mptcpTableFormats {
+		if bytes.Equal(header, f.header) {
+			return f, true
+		}
+	}
+
+	return mptcpTableFormat{}, false
+}
+
 var (
 	// errInvalidMPTCPEntry is returned when an input MPTCP connection
 	// entry is not in the expected format.
@@ -39,27 +99,277 @@ var (
 	errInvalidMPTCPTable = errors.New("invalid MPTCP connections table")
 )
 
+// checkFlight coalesces concurrent checks for identical hex host:port pairs,
+// so they share a single scan of the MPTCP connections table.
+var checkFlight flightGroup
+
 // checkMPTCP checks if an input host string and uint16 port are present
-// in this Linux machine's MPTCP active connections.
-var checkMPTCP = func(host string, port uint16) (bool, error) {
-	// Get hex representation of host
-	hexHost, err := hostToHex(host)
+// in this Linux machine's MPTCP active connections, until ctx is done.
+var checkMPTCP = func(ctx context.Context, host string, port uint16) (bool, error) {
+	// Get hex representation of host and port
+	hexHostPort, err := hostPortToHex(host, port)
 	if err != nil {
 		return false, err
 	}
 
-	// Combine hex host and port, convert to uppercase
-	hexHostPort := strings.ToUpper(net.JoinHostPort(hexHost, u16PortToHex(port)))
+	lookup := func() (bool, error) {
+		ok, err := lookupMPTCPLinux(ctx, hexHostPort)
+		if defaultChecker.tableMissing(err) {
+			// Mainline kernels do not publish a connections table
+			if err := ctx.Err(); err != nil {
+				return false, err
+			}
+
+			return lookupNetlinkLinux(host, port)
+		}
+
+		return ok, err
+	}
+
+	// A check which may be canceled cannot share its result, as canceling
+	// its context would fail every other caller waiting on the same scan
+	if ctx.Done() != nil {
+		return lookup()
+	}
+
+	// Use lookup function to check for results, sharing the results of any
+	// identical check which is already in progress
+	return checkFlight.do(hexHostPort, lookup)
+}
+
+// lookupNetlinkLinux uses the Linux netlink sock_diag interface to attempt to
+// detect active MPTCP connections from the input host and port.
+//
+// This implementation is swappable for testing with a mock data source.
+var lookupNetlinkLinux = func(host string, port uint16) (bool, error) {
+	conns, err := listNetlinkConnections()
+	if err != nil {
+		return false, err
+	}
+
+	return len(findConnections(conns, net.ParseIP(host), port)) > 0, nil
+}
+
+// lookupNetlinkLocalLinux uses the Linux netlink sock_diag interface to
+// attempt to detect active MPTCP connections with the input local host
+// and port.
+func lookupNetlinkLocalLinux(host string, port uint16) (bool, error) {
+	conns, err := listNetlinkConnections()
+	if err != nil {
+		return false, err
+	}
+
+	ip := net.ParseIP(host)
+	for _, c := range conns {
+		if c.LocalAddr.IP.Equal(ip) && c.LocalAddr.Port == int(port) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// listNetlinkConnections uses the Linux netlink sock_diag interface to
+// retrieve all active MPTCP connections.  If the kernel does not support
+// MPTCP sock_diag, no connections and no error are returned.
+var listNetlinkConnections = func() ([]Connection, error) {
+	conns, err := NetlinkSource().ListConnections()
+	if errors.Is(err, syscall.ENOENT) || errors.Is(err, syscall.EPROTONOSUPPORT) || errors.Is(err, syscall.EAFNOSUPPORT) {
+		return nil, nil
+	}
+
+	return conns, err
+}
+
+// tableMissing reports whether the input error, returned while opening a
+// connections table, indicates that the Checker should retrieve connections
+// using netlink instead.  This is only the case when the default table does
+// not exist, as on mainline kernels, and never for a configured path.
+func (c *Checker) tableMissing(err error) bool {
+	return c.procPath == "" && errors.Is(err, os.ErrNotExist)
+}
+
+// forEachConnection uses the Linux /proc filesystem to retrieve each active
+// MPTCP connection, invoking fn with each connection as it is read, until fn
+// returns false.
+var forEachConnection = func(fn func(c Connection) bool) error {
+	// Open Linux MPTCP table, or use netlink if the kernel does not
+	// publish one
+	mptcpFile, err := defaultChecker.openTable()
+	if defaultChecker.tableMissing(err) {
+		conns, err := listNetlinkConnections()
+		if err != nil {
+			return err
+		}
+
+		for _, c := range conns {
+			if !fn(c) {
+				break
+			}
+		}
+
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer mptcpFile.Close()
 
-	// Use lookup function to check for results
-	return lookupMPTCPLinux(hexHostPort)
+	// Read from input stream
+	return mptcpEachConnectionLinux(mptcpFile, defaultChecker.table, fn)
 }
 
-// mptcpEnabled uses the Linux /proc filesystem to determine if
-// the current host supports MPTCP.
-var mptcpEnabled = func() (bool, error) {
+// matcherRemotes uses the Linux /proc filesystem to retrieve the hex remote
+// host:port pairs of all active MPTCP connections, for use with a Matcher.
+var matcherRemotes = func() (map[string]struct{}, error) {
+	// Open Linux MPTCP table, or use netlink if the kernel does not
+	// publish one
+	mptcpFile, err := defaultChecker.openTable()
+	if defaultChecker.tableMissing(err) {
+		return netlinkRemotesLinux()
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer mptcpFile.Close()
+
+	// Read from input stream
+	return mptcpRemotesReaderLinux(mptcpFile)
+}
+
+// netlinkRemotesLinux uses the Linux netlink sock_diag interface to retrieve
+// the remote addresses of all active MPTCP connections, in the same hex
+// host:port form as they appear in a MPTCP connections table.
+func netlinkRemotesLinux() (map[string]struct{}, error) {
+	conns, err := listNetlinkConnections()
+	if err != nil {
+		return nil, err
+	}
+
+	remotes := make(map[string]struct{}, len(conns))
+	for _, c := range conns {
+		remotes[tcpAddrToHex(c.RemoteAddr, c.IsIPv6)] = struct{}{}
+	}
+
+	return remotes, nil
+}
+
+// mptcpEnabled uses the net.mptcp.enabled sysctl and the capability signals
+// of the Linux kernel to determine if the current host supports MPTCP, until
+// ctx is done.
+var mptcpEnabled = func(ctx context.Context) (bool, error) {
+	return mptcpEnabledLinux(ctx, procSysMPTCPEnabled, mptcpCapabilities)
+}
+
+// mptcpEnabledLinux determines if MPTCP is enabled using the net.mptcp.enabled
+// sysctl at the input path.  If the sysctl is present, its value is
+// authoritative, as mainline kernels expose the other signals even when MPTCP
+// is disabled.  Otherwise, the input capability signals are consulted.
+func mptcpEnabledLinux(ctx context.Context, path string, signals []func() (bool, error)) (bool, error) {
+	// Do not open the sysctl if ctx is already done
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	enabled, ok, err := mptcpSysctlEnabledLinux(path)
+	switch {
+	case err != nil:
+		// Report the sysctl error only if no other signal indicates support
+		capable, cErr := mptcpCapableLinux(ctx, signals)
+		if capable {
+			return true, nil
+		}
+
+		return false, errors.Join(err, cErr)
+	case ok:
+		return enabled, nil
+	}
+
+	return mptcpCapableLinux(ctx, signals)
+}
+
+// mptcpSysctlEnabledLinux reads the net.mptcp.enabled sysctl at the input
+// path, reporting whether MPTCP is enabled, and whether the sysctl exists.
+func mptcpSysctlEnabledLinux(path string) (enabled bool, ok bool, err error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, false, nil
+		}
+
+		return false, false, err
+	}
+
+	v, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		return false, false, fmt.Errorf("invalid value for %s: %q", path, b)
+	}
+
+	return v != 0, true, nil
+}
+
+// mptcpCapabilities are the signals which indicate that the Linux kernel
+// supports MPTCP, in priority order.  Mainline kernels expose a sysctl
+// directory and a path manager netlink family regardless of whether any
+// connections are active, while out-of-tree kernels expose only a
+// connections table.
+var mptcpCapabilities = []func() (bool, error){
+	func() (bool, error) { return mptcpSysctlExistsLinux(procSysMPTCP) },
+	func() (bool, error) { return genericFamilyExists(mptcpPMFamilyName) },
+	func() (bool, error) { return mptcpTableExistsLinux(procMPTCP) },
+}
+
+// mptcpCapableLinux consults each of the input capability signals in order,
+// and reports whether any of them indicate that MPTCP is supported.  Errors
+// are only returned if no signal indicates support, and at least one signal
+// could not be consulted.  If ctx is done before a signal is consulted, its
+// error is returned.
+func mptcpCapableLinux(ctx context.Context, signals []func() (bool, error)) (bool, error) {
+	var errs []error
+	for _, fn := range signals {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+
+		ok, err := fn()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if ok {
+			return true, nil
+		}
+	}
+
+	return false, errors.Join(errs...)
+}
+
+// mptcpSysctlExistsLinux determines if the MPTCP sysctl directory exists at
+// the input path.
+func mptcpSysctlExistsLinux(path string) (bool, error) {
+	fi, err := os.Stat(path)
+	if err == nil {
+		return fi.IsDir(), nil
+	}
+
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+
+	return false, err
+}
+
+// mptcpTableExistsLinux determines if a MPTCP connections table exists
+// at the input path.
+//
+// In some container environments, the table may be a symlink to a
+// bind-mounted file.  os.Stat is used instead of os.Lstat so that symlinks
+// are followed, exactly as they are by os.Open when the table is read.  A
+// dangling symlink is treated the same as a missing table.
+func mptcpTableExistsLinux(path string) (bool, error) {
 	// Check for presence of MPTCP connections table
-	_, err := os.Stat(procMPTCP)
+	_, err := os.Stat(path)
 	if err == nil {
 		// MPTCP capable
 		return true, nil
@@ -75,113 +385,759 @@ var mptcpEnabled = func() (bool, error) {
 	return false, err
 }
 
+// openRawTable opens the Linux MPTCP connections table in the /proc
+// filesystem.
+var openRawTable = func() (io.ReadCloser, error) {
+	return openMPTCPTableLinux(procMPTCP)
+}
+
+// openMPTCPTableLinux opens the Linux MPTCP connections table at the input
+// path.
+func openMPTCPTableLinux(path string) (io.ReadCloser, error) {
+	mptcpFile, err := os.Open(path)
+	if err != nil {
+		// Avoid returning a non-nil io.ReadCloser containing a nil *os.File
+		return nil, err
+	}
+
+	return mptcpFile, nil
+}
+
 // hostToHex converts an input host IP address into its equivalent hex form,
 // for use with MPTCP connection lookup.
+//
+// IPv6 addresses are always encoded in their full 32 character form, exactly
+// as the kernel writes them, regardless of whether the input address is
+// written in compressed or expanded form.
 func hostToHex(host string) (string, error) {
 	// Parse IP address from host
 	ip := net.ParseIP(host)
 
 	// If result is not nil, we assume this is IPv4
 	if ip4 := ip.To4(); ip4 != nil && len(ip4) == net.IPv4len {
-		// For IPv4, grab the IPv4 hex representation of the address
-		return fmt.Sprintf("%02x%02x%02x%02x", ip4[3], ip4[2], ip4[1], ip4[0]), nil
+		return ipToHex(ip4, tableByteOrder), nil
 	}
 
 	// Check for IPv6 address
 	if ip6 := ip.To16(); ip6 != nil && len(ip6) == net.IPv6len {
-		// TODO(mdlayher): attempt to check for IPv6 address
-		return "", ErrIPv6NotImplemented
+		return ipToHex(ip6, tableByteOrder), nil
 	}
 
 	// IP address is not valid
 	return "", ErrInvalidIPAddress
 }
 
-// u16PortToHex converts an input uint16 port into its equivalent hex form,
-// for use with MPTCP connection lookup.
-func u16PortToHex(port uint16) string {
-	// Store uint16 in buffer using little endian byte order
-	portBuf := [2]byte{}
-	binary.LittleEndian.PutUint16(portBuf[:], port)
+// hostPortToHex converts an input host IP address and uint16 port into
+// their equivalent hex host:port form, exactly as it appears in a MPTCP
+// connections table.
+func hostPortToHex(host string, port uint16) (string, error) {
+	// Get hex representation of host
+	hexHost, err := hostToHex(host)
+	if err != nil {
+		return "", err
+	}
+
+	return joinHexHostPort(hexHost, port), nil
+}
 
-	// Retrieve hex representation of uint16 port
-	return fmt.Sprintf("%02x%02x", portBuf[1], portBuf[0])
+// joinHexHostPort combines an input hex host and uint16 port into their
+// equivalent hex host:port form, exactly as it appears in a MPTCP
+// connections table.
+func joinHexHostPort(hexHost string, port uint16) string {
+	// Combine hex host and port, convert to uppercase
+	return strings.ToUpper(net.JoinHostPort(hexHost, u16PortToHex(port)))
 }
 
 // lookupMPTCPLinux uses the Linux /proc filesystem to attempt to detect
-// active MPTCP connections matching the input hex host:port pair.
+// active MPTCP connections matching the input hex host:port pair, until ctx
+// is done.
 //
 // This implementation is swappable for testing with a mock data source.
-var lookupMPTCPLinux = func(hexHostPort string) (bool, error) {
+var lookupMPTCPLinux = func(ctx context.Context, hexHostPort string) (bool, error) {
+	// Do not open Linux MPTCP table if ctx is already done
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
 	// Open Linux MPTCP table
-	mptcpFile, err := os.Open(procMPTCP)
+	mptcpFile, err := defaultChecker.openTable()
 	if err != nil {
 		return false, err
 	}
 	defer mptcpFile.Close()
 
 	// Read from input stream
-	return mptcpTableReaderLinux(mptcpFile, hexHostPort)
+	return mptcpTableReaderLinux(ctx, mptcpFile, hexHostPort)
 }
 
-// mptcpTableReaderLinux reads a MPTCP connections table from an input stream.
-// This function allows easier testability with table parsing.
-func mptcpTableReaderLinux(r io.Reader, hexHostPort string) (bool, error) {
+// checkLocalMPTCP checks if an input host string and uint16 port are the
+// local address of any of this Linux machine's MPTCP active connections.
+var checkLocalMPTCP = func(host string, port uint16) (bool, error) {
+	// Get hex representation of host and port
+	hexHostPort, err := hostPortToHex(host, port)
+	if err != nil {
+		return false, err
+	}
+
+	// Open Linux MPTCP table, or use netlink if the kernel does not
+	// publish one
+	mptcpFile, err := defaultChecker.openTable()
+	if defaultChecker.tableMissing(err) {
+		return lookupNetlinkLocalLinux(host, port)
+	}
+	if err != nil {
+		return false, err
+	}
+	defer mptcpFile.Close()
+
+	// Read from input stream
+	return mptcpLocalTableReaderLinux(mptcpFile, hexHostPort)
+}
+
+// listConnections uses the Linux /proc filesystem to retrieve all active
+// MPTCP connections.
+var listConnections = func() ([]Connection, error) {
+	return defaultChecker.listConnections()
+}
+
+// listConnections uses the Linux /proc filesystem to retrieve all active
+// MPTCP connections, using the Checker's options.
+func (c *Checker) listConnections() ([]Connection, error) {
+	// Open Linux MPTCP table, or use netlink if the kernel does not
+	// publish one
+	mptcpFile, err := c.openTable()
+	if c.tableMissing(err) {
+		return listNetlinkConnections()
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer mptcpFile.Close()
+
+	// Read from input stream
+	return mptcpConnectionsReaderLinux(mptcpFile, c.table)
+}
+
+// listLazyConnections uses the Linux /proc filesystem to retrieve all active
+// MPTCP connections without decoding their fields, using the Checker's
+// options.
+func (c *Checker) listLazyConnections() ([]*LazyConnection, error) {
+	// Open Linux MPTCP table, or use netlink if the kernel does not
+	// publish one, whose connections are already decoded
+	mptcpFile, err := c.openTable()
+	if c.tableMissing(err) {
+		conns, err := listNetlinkConnections()
+		if err != nil {
+			return nil, err
+		}
+
+		lazy := make([]*LazyConnection, 0, len(conns))
+		for _, conn := range conns {
+			lazy = append(lazy, newLazyConnection(decodedEntry{c: conn}, conn.IsIPv6))
+		}
+
+		return lazy, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer mptcpFile.Close()
+
+	// Read from input stream
+	return mptcpLazyReaderLinux(mptcpFile, c.table)
+}
+
+// mptcpLazyReaderLinux reads all entries from a MPTCP connections table from
+// an input stream using the input options, and wraps them as LazyConnections
+// without decoding their fields.
+func mptcpLazyReaderLinux(r io.Reader, opts tableOptions) ([]*LazyConnection, error) {
+	var lazy []*LazyConnection
+	err := scanMPTCPTableLinux(r, opts, func(mptcpEntry *mptcpTableEntry) (bool, error) {
+		lazy = append(lazy, newLazyConnection(mptcpEntry, mptcpEntry.IsIPv6))
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return lazy, nil
+}
+
+// mptcpTableReaderLinux reads a MPTCP connections table from an input stream,
+// until ctx is done.  This function allows easier testability with table
+// parsing.
+func mptcpTableReaderLinux(ctx context.Context, r io.Reader, hexHostPort string) (bool, error) {
+	return mptcpAddrReaderLinux(ctx, r, hexHostPort, func(mptcpEntry *mptcpTableEntry) string {
+		return mptcpEntry.RemoteAddr
+	})
+}
+
+// mptcpLocalTableReaderLinux reads a MPTCP connections table from an input
+// stream, and reports whether an entry with the input hex local host:port
+// pair is present.
+func mptcpLocalTableReaderLinux(r io.Reader, hexHostPort string) (bool, error) {
+	return mptcpAddrReaderLinux(context.Background(), r, hexHostPort, func(mptcpEntry *mptcpTableEntry) string {
+		return mptcpEntry.LocalAddr
+	})
+}
+
+// mptcpAddrReaderLinux reads a MPTCP connections table from an input stream,
+// and reports whether an entry is present for which addr returns the input
+// hex host:port pair.  If ctx is done before the scan completes, its error is
+// returned.
+func mptcpAddrReaderLinux(ctx context.Context, r io.Reader, hexHostPort string, addr func(mptcpEntry *mptcpTableEntry) string) (bool, error) {
+	// IPv6 hex hosts are always written in their full 32 character form, so
+	// only entries of the same address family as the input may match
+	isIPv6 := strings.IndexByte(hexHostPort, ':') == 2*net.IPv6len
+
+	// Iterate until EOF or entry found
+	var found bool
+	err := scanMPTCPTableLinux(r, tableOptions{}, func(mptcpEntry *mptcpTableEntry) (bool, error) {
+		// Stop between lines if ctx is done
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+
+		// Check for address which matches input
+		if mptcpEntry.IsIPv6 == isIPv6 && addr(mptcpEntry) == hexHostPort {
+			found = true
+			return false, nil
+		}
+
+		return true, nil
+	})
+
+	return found, err
+}
+
+// mptcpRemotesReaderLinux reads the hex remote host:port pairs of all entries
+// from a MPTCP connections table from an input stream.
+func mptcpRemotesReaderLinux(r io.Reader) (map[string]struct{}, error) {
+	// Iterate until EOF, storing each remote address
+	remotes := make(map[string]struct{})
+	err := scanMPTCPTableLinux(r, tableOptions{}, func(mptcpEntry *mptcpTableEntry) (bool, error) {
+		remotes[mptcpEntry.RemoteAddr] = struct{}{}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return remotes, nil
+}
+
+// mptcpInodeReaderLinux reads a MPTCP connections table from an input stream,
+// and reports whether an entry with the input socket inode is present.
+func mptcpInodeReaderLinux(r io.Reader, inode uint64) (bool, error) {
+	// Inodes are written in decimal
+	strInode := strconv.FormatUint(inode, 10)
+
+	// Iterate until EOF or entry found
+	var found bool
+	err := scanMPTCPTableLinux(r, tableOptions{}, func(mptcpEntry *mptcpTableEntry) (bool, error) {
+		if mptcpEntry.Inode == strInode {
+			found = true
+			return false, nil
+		}
+
+		return true, nil
+	})
+
+	return found, err
+}
+
+// mptcpConnectionsReaderLinux reads all entries from a MPTCP connections
+// table from an input stream using the input options, and decodes them
+// into Connections.
+func mptcpConnectionsReaderLinux(r io.Reader, opts tableOptions) ([]Connection, error) {
+	// Iterate until EOF, storing each connection
+	var conns []Connection
+	err := mptcpEachConnectionLinux(r, opts, func(c Connection) bool {
+		conns = append(conns, c)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return conns, nil
+}
+
+// totalQueuedBytes uses the Linux /proc filesystem to sum the transmit and
+// receive queue lengths of all active MPTCP connections.
+var totalQueuedBytes = func() (uint64, uint64, error) {
+	// Open Linux MPTCP table.  Queue lengths are not reported by netlink,
+	// so no bytes are queued if the kernel does not publish a table
+	mptcpFile, err := defaultChecker.openTable()
+	if defaultChecker.tableMissing(err) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	defer mptcpFile.Close()
+
+	// Read from input stream
+	return mptcpQueuesReaderLinux(mptcpFile, defaultChecker.table)
+}
+
+// mptcpQueuesReaderLinux reads all entries from a MPTCP connections table
+// from an input stream using the input options, and sums their transmit and
+// receive queue lengths.  Sums which would overflow are capped at the
+// maximum uint64 value.
+func mptcpQueuesReaderLinux(r io.Reader, opts tableOptions) (tx uint64, rx uint64, err error) {
+	err = scanMPTCPTableLinux(r, opts, func(mptcpEntry *mptcpTableEntry) (bool, error) {
+		txq, rxq, err := hexToQueues(mptcpEntry.Queues)
+		if err != nil {
+			return false, err
+		}
+
+		tx = addSaturating(tx, txq)
+		rx = addSaturating(rx, rxq)
+		return true, nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return tx, rx, nil
+}
+
+// hexToQueues converts an input hex tx_queue:rx_queue pair from a MPTCP
+// connections table into its equivalent queue lengths.
+func hexToQueues(hexQueues string) (uint64, uint64, error) {
+	i := strings.IndexByte(hexQueues, ':')
+	if i == -1 {
+		return 0, 0, errInvalidMPTCPEntry
+	}
+
+	tx, err := strconv.ParseUint(hexQueues[:i], 16, 32)
+	if err != nil {
+		return 0, 0, errInvalidMPTCPEntry
+	}
+
+	rx, err := strconv.ParseUint(hexQueues[i+1:], 16, 32)
+	if err != nil {
+		return 0, 0, errInvalidMPTCPEntry
+	}
+
+	return tx, rx, nil
+}
+
+// addSaturating adds two uint64 values, returning the maximum uint64 value
+// instead of wrapping on overflow.
+func addSaturating(a, b uint64) uint64 {
+	if a > math.MaxUint64-b {
+		return math.MaxUint64
+	}
+
+	return a + b
+}
+
+// mptcpEachConnectionLinux reads entries from a MPTCP connections table from
+// an input stream using the input options, decoding each into a Connection and invoking fn with it,
+// until fn returns false or EOF is reached.
+func mptcpEachConnectionLinux(r io.Reader, opts tableOptions, fn func(c Connection) bool) error {
+	return scanMPTCPTableLinux(r, opts, func(mptcpEntry *mptcpTableEntry) (bool, error) {
+		c, err := mptcpEntry.connection()
+		if err != nil {
+			return false, err
+		}
+
+		return fn(c), nil
+	})
+}
+
+// scanMPTCPTableLinux reads a MPTCP connections table from an input stream
+// using the input options, invoking fn with each table entry until fn returns
+// false or an error, or EOF is reached.
+func scanMPTCPTableLinux(r io.Reader, opts tableOptions, fn func(mptcpEntry *mptcpTableEntry) (bool, error)) error {
 	// Open text scanner to split lines, skip header line
 	scanner := bufio.NewScanner(r)
-	scanner.Split(bufio.ScanLines)
+	scanner.Split(opts.splitFunc())
 	if !scanner.Scan() {
+		// Report any read error before assuming the file was empty
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+
 		// If file was empty, return unexpected EOF
-		return false, io.ErrUnexpectedEOF
+		return io.ErrUnexpectedEOF
 	}
 
-	// Ensure first line was valid MPTCP connections table header
-	if !bytes.Equal(scanner.Bytes(), mptcpTableHeader) {
-		return false, errInvalidMPTCPTable
+	// Ensure first line was a known MPTCP connections table header
+	format, ok := lookupMPTCPTableFormat([]byte(opts.line(scanner.Text())))
+	if !ok {
+		return errInvalidMPTCPTable
 	}
 
-	// Iterate until EOF or entry found
+	// Iterate until EOF, or until fn stops iteration
+	var (
+		rows, parsed int
+		firstErr     error
+	)
 	for scanner.Scan() {
+		rows++
+
 		// Scan fields into mptcpTableEntry
-		mptcpEntry, err := newMPTCPTableEntry(strings.Fields(scanner.Text()))
+		more, err := scanMPTCPEntryLinux(opts.line(scanner.Text()), format, fn)
 		if err != nil {
-			return false, err
+			// A read error leaves a truncated final line, so report the
+			// read error instead of the resulting invalid entry
+			if sErr := scanner.Err(); sErr != nil {
+				return sErr
+			}
+
+			if !opts.skipInvalid {
+				return err
+			}
+
+			// Skip the invalid row, but remember why the first row failed
+			if firstErr == nil {
+				firstErr = fmt.Errorf("row %d: %w", rows, err)
+			}
+			continue
 		}
+		parsed++
 
-		// Check for remote address which matches input
-		if mptcpEntry.RemoteAddr == hexHostPort {
-			return true, nil
+		if !more {
+			return nil
 		}
 	}
 
-	// No result found
-	return false, nil
+	// Report any read error which stopped the scan early
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	// A table with rows, none of which could be parsed, is not empty
+	if rows > 0 && parsed == 0 {
+		return fmt.Errorf("%w: %w", ErrNoParseableRows, firstErr)
+	}
+
+	return nil
+}
+
+// scanMPTCPEntryLinux parses a single line from a MPTCP connections table
+// with the input format into a mptcpTableEntry, and invokes fn with it.
+func scanMPTCPEntryLinux(line string, format mptcpTableFormat, fn func(mptcpEntry *mptcpTableEntry) (bool, error)) (bool, error) {
+	mptcpEntry, err := newMPTCPTableEntry(strings.Fields(line), format)
+	if err != nil {
+		return false, err
+	}
+
+	return fn(mptcpEntry)
+}
+
+// validateTable reads the header and a sample of rows from a Linux MPTCP
+// connections table, and reports its format and any anomalies.
+var validateTable = func(r io.Reader) (FormatInfo, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Split(bufio.ScanLines)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return FormatInfo{}, err
+		}
+
+		return FormatInfo{}, io.ErrUnexpectedEOF
+	}
+
+	format, ok := lookupMPTCPTableFormat(scanner.Bytes())
+	if !ok {
+		return FormatInfo{}, errInvalidMPTCPTable
+	}
+
+	info := FormatInfo{
+		Version: format.version,
+		Columns: format.columns,
+	}
+
+	// Inspect each sampled row, without retaining decoded connections
+	for info.Rows < validateSampleRows && scanner.Scan() {
+		info.Rows++
+		if a := validateMPTCPEntryLinux(scanner.Text(), format); a != "" {
+			info.Anomalies = append(info.Anomalies, fmt.Sprintf("row %d: %s", info.Rows, a))
+		}
+	}
+
+	return info, scanner.Err()
+}
+
+// validateMPTCPEntryLinux checks a single line from a MPTCP connections table
+// with the input format, returning a description of its problem, or an empty
+// string if the line is valid.
+func validateMPTCPEntryLinux(line string, format mptcpTableFormat) string {
+	fields := strings.Fields(line)
+	if len(fields) != format.columns {
+		return fmt.Sprintf("expected %d columns, found %d", format.columns, len(fields))
+	}
+
+	if fields[3] != "0" && fields[3] != "1" {
+		return fmt.Sprintf("invalid v6 flag %q", fields[3])
+	}
+
+	m, err := newMPTCPTableEntry(fields, format)
+	if err != nil {
+		return err.Error()
+	}
+
+	c, err := m.connection()
+	if err != nil {
+		return err.Error()
+	}
+
+	// The v6 flag must agree with the length of each address
+	if (len(c.LocalAddr.IP) == net.IPv6len) != c.IsIPv6 || (len(c.RemoteAddr.IP) == net.IPv6len) != c.IsIPv6 {
+		return "address length does not match v6 flag"
+	}
+
+	return ""
 }
 
 // mptcpTableEntry contains parsed information from a Linux MPTCP connections
 // table entry.  While numerous fields are available, we only make use of
-// a couple of them.
+// some of them.
 type mptcpTableEntry struct {
-	IsIPv6     bool
-	RemoteAddr string
+	LocalToken  string
+	RemoteToken string
+	IsIPv6      bool
+	LocalAddr   string
+	RemoteAddr  string
+	State       string
+	Subflows    string
+	Queues      string
+	Inode       string
+
+	// Timer and Retransmits are only set for tables in the extended format.
+	Timer       string
+	Retransmits string
 }
 
-// newMPTCPTableEntry creates a new mptcpTableEntry from a slice of strings.
-func newMPTCPTableEntry(fields []string) (*mptcpTableEntry, error) {
+// newMPTCPTableEntry creates a new mptcpTableEntry from a slice of strings,
+// using the input table format.
+func newMPTCPTableEntry(fields []string, format mptcpTableFormat) (*mptcpTableEntry, error) {
 	// Check for proper number of fields, though most of them will not be
 	// kept for this library's purposes.
-	if len(fields) != mptcpTableColumns {
+	if len(fields) != format.columns {
 		return nil, errInvalidMPTCPEntry
 	}
 
+	// Scan hex encoded local and remote tokens
+	m := &mptcpTableEntry{
+		LocalToken:  fields[1],
+		RemoteToken: fields[2],
+	}
+
 	// Check for IPv6 connectivity
-	m := &mptcpTableEntry{}
 	if fields[3] == "1" {
 		m.IsIPv6 = true
 	}
 
-	// Scan hex encoded remote address
+	// Scan hex encoded local and remote addresses
+	m.LocalAddr = fields[4]
 	m.RemoteAddr = fields[5]
 
+	// Scan hex encoded connection state and number of subflows
+	m.State = fields[6]
+	m.Subflows = fields[7]
+
+	// Scan hex encoded transmit and receive queue lengths
+	m.Queues = fields[8]
+
+	// Scan decimal socket inode
+	m.Inode = fields[9]
+
+	// Scan hex encoded tr:tm->when timer state and retransmit count, if
+	// present
+	if format.columns == mptcpExtendedTableColumns {
+		m.Timer = fields[10]
+		m.Retransmits = fields[11]
+	}
+
 	return m, nil
 }
+
+// tokens decodes the hex local and remote tokens of a mptcpTableEntry.
+func (m *mptcpTableEntry) tokens() (uint32, uint32, error) {
+	localToken, err := strconv.ParseUint(m.LocalToken, 16, 32)
+	if err != nil {
+		return 0, 0, errInvalidMPTCPEntry
+	}
+
+	remoteToken, err := strconv.ParseUint(m.RemoteToken, 16, 32)
+	if err != nil {
+		return 0, 0, errInvalidMPTCPEntry
+	}
+
+	return uint32(localToken), uint32(remoteToken), nil
+}
+
+// localAddr decodes the hex local address of a mptcpTableEntry.
+func (m *mptcpTableEntry) localAddr() (*net.TCPAddr, error) {
+	return hexToTCPAddr(m.LocalAddr)
+}
+
+// remoteAddr decodes the hex remote address of a mptcpTableEntry.
+func (m *mptcpTableEntry) remoteAddr() (*net.TCPAddr, error) {
+	return hexToTCPAddr(m.RemoteAddr)
+}
+
+// connection decodes the hex fields of a mptcpTableEntry into a Connection.
+func (m *mptcpTableEntry) connection() (Connection, error) {
+	localToken, remoteToken, err := m.tokens()
+	if err != nil {
+		return Connection{}, err
+	}
+
+	localAddr, err := m.localAddr()
+	if err != nil {
+		return Connection{}, err
+	}
+
+	remoteAddr, err := m.remoteAddr()
+	if err != nil {
+		return Connection{}, err
+	}
+
+	state, err := strconv.ParseUint(m.State, 16, 8)
+	if err != nil {
+		return Connection{}, errInvalidMPTCPEntry
+	}
+
+	subflows, err := strconv.ParseUint(m.Subflows, 16, 8)
+	if err != nil {
+		return Connection{}, errInvalidMPTCPEntry
+	}
+
+	txQueue, rxQueue, err := hexToQueues(m.Queues)
+	if err != nil {
+		return Connection{}, err
+	}
+
+	inode, err := strconv.ParseUint(m.Inode, 10, 64)
+	if err != nil {
+		return Connection{}, errInvalidMPTCPEntry
+	}
+
+	c := Connection{
+		LocalToken:  localToken,
+		RemoteToken: remoteToken,
+		IsIPv6:      m.IsIPv6,
+		LocalAddr:   localAddr,
+		RemoteAddr:  remoteAddr,
+		State:       State(state),
+		Subflows:    int(subflows),
+		Inode:       inode,
+		TxQueue:     uint32(txQueue),
+		RxQueue:     uint32(rxQueue),
+	}
+
+	// Timer and retransmit state is only present in the extended format
+	if m.Timer == "" {
+		return c, nil
+	}
+
+	c.TimerActive, err = hexToTimerActive(m.Timer)
+	if err != nil {
+		return Connection{}, err
+	}
+
+	retransmits, err := strconv.ParseUint(m.Retransmits, 16, 32)
+	if err != nil {
+		return Connection{}, errInvalidMPTCPEntry
+	}
+	c.Retransmits = uint32(retransmits)
+
+	return c, nil
+}
+
+// hexToTimerActive converts an input hex tr:tm->when pair from a MPTCP
+// connections table into whether a timer is pending for the connection.  A
+// tr value of zero indicates that no timer is pending.
+func hexToTimerActive(hexTimer string) (bool, error) {
+	i := strings.IndexByte(hexTimer, ':')
+	if i == -1 {
+		return false, errInvalidMPTCPEntry
+	}
+
+	tr, err := strconv.ParseUint(hexTimer[:i], 16, 8)
+	if err != nil {
+		return false, errInvalidMPTCPEntry
+	}
+
+	// The expiry time is not exposed, but must still be well-formed
+	if _, err := strconv.ParseUint(hexTimer[i+1:], 16, 32); err != nil {
+		return false, errInvalidMPTCPEntry
+	}
+
+	return tr != 0, nil
+}
+
+// hexToTCPAddr converts an input hex host:port pair from a MPTCP connections
+// table into its equivalent TCP address.
+func hexToTCPAddr(hexHostPort string) (*net.TCPAddr, error) {
+	// Split hex host and port
+	hexHost, hexPort, err := net.SplitHostPort(hexHostPort)
+	if err != nil {
+		return nil, errInvalidMPTCPEntry
+	}
+
+	ip, err := hexToHost(hexHost)
+	if err != nil {
+		return nil, err
+	}
+
+	port, err := hexToU16Port(hexPort)
+	if err != nil {
+		return nil, err
+	}
+
+	return &net.TCPAddr{
+		IP:   ip,
+		Port: int(port),
+	}, nil
+}
+
+// hexToHost converts an input hex host from a MPTCP connections table into
+// its equivalent IP address.  It is the inverse of hostToHex.
+func hexToHost(hexHost string) (net.IP, error) {
+	return hexToIP(hexHost, tableByteOrder)
+}
+
+// hexToIP converts an input hex host into its equivalent IP address, reading
+// each 32-bit word of the address as an integer in the input byte order.  It
+// is the inverse of ipToHex.
+func hexToIP(hexHost string, order binary.ByteOrder) (net.IP, error) {
+	// Hex hosts must be the proper length for IPv4 or IPv6
+	if len(hexHost) != 2*net.IPv4len && len(hexHost) != 2*net.IPv6len {
+		return nil, errInvalidMPTCPEntry
+	}
+
+	ip := make(net.IP, len(hexHost)/2)
+	for i := 0; i < len(ip); i += 4 {
+		word, err := strconv.ParseUint(hexHost[2*i:2*i+8], 16, 32)
+		if err != nil {
+			return nil, errInvalidMPTCPEntry
+		}
+
+		order.PutUint32(ip[i:i+4], uint32(word))
+	}
+
+	return ip, nil
+}
+
+// hexToU16Port converts an input hex port from a MPTCP connections table
+// into its equivalent uint16 form.  It is the inverse of u16PortToHex.
+func hexToU16Port(hexPort string) (uint16, error) {
+	// Ports are always written as four hex digits
+	if len(hexPort) != 4 {
+		return 0, errInvalidMPTCPEntry
+	}
+
+	port, err := strconv.ParseUint(hexPort, 16, 16)
+	if err != nil {
+		return 0, errInvalidMPTCPEntry
+	}
+
+	return uint16(port), nil
+}
@@ -3,12 +3,24 @@
 package mptcp
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
 	"io"
+	"io/ioutil"
+	"math"
 	"net"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 var (
@@ -17,6 +29,10 @@ var (
 	testIPv6MPTCPEntry = []byte(" 0: F6635734 353F1E98  1 80A80426100000080000000001C07400:1F90 80A80426100000080000000001208902:93A5 01 01 00000000:00000000 39893")
 )
 
+// realLookupMPTCPLinux is the table lookup function, saved before it is
+// swapped for a mock, for tests which replace only the opened table.
+var realLookupMPTCPLinux = lookupMPTCPLinux
+
 // Swap in mock MPTCP lookup function for tests
 func init() {
 	lookupMPTCPLinux = generateMockLookupMPTCPLinux()
@@ -26,33 +42,218 @@ func init() {
 // multipath TCP functionality on the current Linux system.
 func TestLinux_mptcpEnabled(t *testing.T) {
 	// Check function result immediately
-	enabled, err := mptcpEnabled()
+	enabled, err := mptcpEnabled(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	// Check if multipath TCP is available by checking for
-	// connections table
-	_, err = os.Stat(procMPTCP)
-	if os.IsNotExist(err) {
-		if enabled {
-			t.Fatalf("could not find %s, but mptcpEnabled returned true", procMPTCP)
+	// If present, the enabled sysctl determines the result
+	b, err := ioutil.ReadFile(procSysMPTCPEnabled)
+	if err == nil {
+		want := strings.TrimSpace(string(b)) != "0"
+		if enabled != want {
+			t.Fatalf("%s contains %q, but mptcpEnabled returned %v", procSysMPTCPEnabled, b, enabled)
 		}
 
 		return
 	}
+	if !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
 
-	// Fatal on other errors
-	if err != nil {
+	// Check if multipath TCP is available by checking for the sysctl
+	// directory or connections table
+	var found bool
+	for _, p := range []string{procSysMPTCP, procMPTCP} {
+		_, err := os.Stat(p)
+		if err == nil {
+			found = true
+			continue
+		}
+
+		// Fatal on errors other than a missing file
+		if !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+	}
+
+	// The netlink family may indicate support even if neither file exists
+	if found && !enabled {
+		t.Fatalf("found %s or %s, but mptcpEnabled returned false", procSysMPTCP, procMPTCP)
+	}
+}
+
+// TestLinux_mptcpCapableLinux verifies that mptcpCapableLinux consults each
+// capability signal in order, and only returns errors if no signal indicates
+// MPTCP support.
+func TestLinux_mptcpCapableLinux(t *testing.T) {
+	errSignal := errors.New("signal failed")
+
+	var (
+		yes  = func() (bool, error) { return true, nil }
+		no   = func() (bool, error) { return false, nil }
+		fail = func() (bool, error) { return false, errSignal }
+	)
+
+	var tests = []struct {
+		desc    string
+		signals []func() (bool, error)
+		ok      bool
+		err     bool
+	}{
+		{"no signals", nil, false, false},
+		{"sysctl directory", []func() (bool, error){yes, no, no}, true, false},
+		{"netlink family", []func() (bool, error){no, yes, no}, true, false},
+		{"proc table fallback", []func() (bool, error){no, no, yes}, true, false},
+		{"no capability", []func() (bool, error){no, no, no}, false, false},
+		{"error before capability", []func() (bool, error){fail, yes, no}, true, false},
+		{"error without capability", []func() (bool, error){no, fail, no}, false, true},
+	}
+
+	for i, test := range tests {
+		ok, err := mptcpCapableLinux(context.Background(), test.signals)
+		if (err != nil) != test.err {
+			t.Fatalf("[%02d] unexpected err: %v [test: %v]", i, err, test.desc)
+		}
+
+		if test.err && !errors.Is(err, errSignal) {
+			t.Fatalf("[%02d] unexpected err: %v != %v [test: %v]", i, err, errSignal, test.desc)
+		}
+
+		if ok != test.ok {
+			t.Fatalf("[%02d] unexpected ok: %v != %v [test: %v]", i, ok, test.ok, test.desc)
+		}
+	}
+}
+
+// TestLinux_mptcpSysctlExistsLinux verifies that mptcpSysctlExistsLinux only
+// reports a sysctl directory which exists.
+func TestLinux_mptcpSysctlExistsLinux(t *testing.T) {
+	dir := t.TempDir()
+
+	file := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(file, nil, 0644); err != nil {
 		t.Fatal(err)
 	}
 
-	// Verify multipath TCP is enabled
-	if !enabled {
-		t.Fatalf("found %s, but mptcpEnabled returned false", procMPTCP)
+	var tests = []struct {
+		path string
+		ok   bool
+	}{
+		{dir, true},
+		{file, false},
+		{filepath.Join(dir, "missing"), false},
+	}
+
+	for i, test := range tests {
+		ok, err := mptcpSysctlExistsLinux(test.path)
+		if err != nil {
+			t.Fatalf("[%02d] unexpected err: %v", i, err)
+		}
+
+		if ok != test.ok {
+			t.Fatalf("[%02d] unexpected ok: %v != %v [path: %s]", i, ok, test.ok, test.path)
+		}
+	}
+}
+
+// TestLinux_mptcpSysctlEnabledLinux verifies that mptcpSysctlEnabledLinux
+// reports the value of the enabled sysctl, and whether it exists.
+func TestLinux_mptcpSysctlEnabledLinux(t *testing.T) {
+	dir := t.TempDir()
+
+	var tests = []struct {
+		contents string
+		missing  bool
+		enabled  bool
+		ok       bool
+		err      bool
+	}{
+		{contents: "1\n", enabled: true, ok: true},
+		{contents: "0\n", enabled: false, ok: true},
+		{contents: "2", enabled: true, ok: true},
+		{contents: "foo\n", err: true},
+		{contents: "", err: true},
+		{missing: true},
+	}
+
+	for i, test := range tests {
+		path := filepath.Join(dir, strconv.Itoa(i))
+		if !test.missing {
+			if err := ioutil.WriteFile(path, []byte(test.contents), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		enabled, ok, err := mptcpSysctlEnabledLinux(path)
+		if (err != nil) != test.err {
+			t.Fatalf("[%02d] unexpected err: %v [contents: %q]", i, err, test.contents)
+		}
+
+		if enabled != test.enabled {
+			t.Fatalf("[%02d] unexpected enabled: %v != %v [contents: %q]", i, enabled, test.enabled, test.contents)
+		}
+
+		if ok != test.ok {
+			t.Fatalf("[%02d] unexpected ok: %v != %v [contents: %q]", i, ok, test.ok, test.contents)
+		}
+	}
+}
+
+// TestLinux_mptcpEnabledLinux verifies that mptcpEnabledLinux treats the
+// enabled sysctl as authoritative when present, and otherwise falls back to
+// the capability signals.
+func TestLinux_mptcpEnabledLinux(t *testing.T) {
+	dir := t.TempDir()
+	errSignal := errors.New("signal failed")
+
+	var (
+		yes  = []func() (bool, error){func() (bool, error) { return true, nil }}
+		no   = []func() (bool, error){func() (bool, error) { return false, nil }}
+		fail = []func() (bool, error){func() (bool, error) { return false, errSignal }}
+	)
+
+	var tests = []struct {
+		desc     string
+		contents *string
+		signals  []func() (bool, error)
+		ok       bool
+		err      bool
+	}{
+		{"sysctl enabled", stringPtr("1\n"), no, true, false},
+		{"sysctl disabled", stringPtr("0\n"), yes, false, false},
+		{"sysctl disabled, signal error", stringPtr("0\n"), fail, false, false},
+		{"sysctl missing, capable", nil, yes, true, false},
+		{"sysctl missing, not capable", nil, no, false, false},
+		{"sysctl missing, signal error", nil, fail, false, true},
+		{"sysctl invalid, capable", stringPtr("foo"), yes, true, false},
+		{"sysctl invalid, not capable", stringPtr("foo"), no, false, true},
+	}
+
+	for i, test := range tests {
+		path := filepath.Join(dir, strconv.Itoa(i))
+		if test.contents != nil {
+			if err := ioutil.WriteFile(path, []byte(*test.contents), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		ok, err := mptcpEnabledLinux(context.Background(), path, test.signals)
+		if (err != nil) != test.err {
+			t.Fatalf("[%02d] unexpected err: %v [test: %v]", i, err, test.desc)
+		}
+
+		if ok != test.ok {
+			t.Fatalf("[%02d] unexpected ok: %v != %v [test: %v]", i, ok, test.ok, test.desc)
+		}
 	}
 }
 
+// stringPtr returns a pointer to the input string.
+func stringPtr(s string) *string {
+	return &s
+}
+
 // TestLinux_hostToHex verifies that hostToHex generates the proper hex
 // representation of an input IP address string.
 func TestLinux_hostToHex(t *testing.T) {
@@ -75,11 +276,11 @@ func TestLinux_hostToHex(t *testing.T) {
 		{"192.168.1.1", "0101a8c0", nil},
 		{"255.255.255.0", "00ffffff", nil},
 
-		// Valid IPv6 addresses (not yet implemented)
-		{"0000:0000:0000::0000", "", ErrIPv6NotImplemented},
-		{"1111:1111:1111::1111", "", ErrIPv6NotImplemented},
-		{"2001:4860:4860::8844", "", ErrIPv6NotImplemented},
-		{"2001:4860:4860::8888", "", ErrIPv6NotImplemented},
+		// Valid IPv6 addresses
+		{"0000:0000:0000::0000", "00000000000000000000000000000000", nil},
+		{"1111:1111:1111::1111", "11111111000011110000000011110000", nil},
+		{"2001:4860:4860::8844", "60480120000060480000000044880000", nil},
+		{"2001:4860:4860::8888", "60480120000060480000000088880000", nil},
 	}
 
 	for i, test := range tests {
@@ -95,6 +296,107 @@ func TestLinux_hostToHex(t *testing.T) {
 	}
 }
 
+// TestLinux_hostToHexIPv6Forms verifies that hostToHex produces identical
+// output for compressed and expanded forms of the same IPv6 address, which
+// matches the address exactly as it appears in a real MPTCP connections table.
+func TestLinux_hostToHexIPv6Forms(t *testing.T) {
+	var tests = []struct {
+		hosts   []string
+		hexHost string
+		inRow   bool
+	}{
+		// Remote address of testIPv6MPTCPEntry
+		{
+			inRow: true,
+			hosts: []string{
+				"2604:a880:800:10::289:2001",
+				"2604:a880:0800:0010:0000:0000:0289:2001",
+				"2604:A880:800:10:0:0:289:2001",
+			},
+			hexHost: "80A80426100000080000000001208902",
+		},
+		// Local address of testIPv6MPTCPEntry
+		{
+			inRow: true,
+			hosts: []string{
+				"2604:a880:800:10::74:c001",
+				"2604:a880:0800:0010:0000:0000:0074:c001",
+			},
+			hexHost: "80A80426100000080000000001C07400",
+		},
+		{
+			hosts: []string{
+				"2001:db8::1",
+				"2001:0db8:0000:0000:0000:0000:0000:0001",
+			},
+			hexHost: "B80D0120000000000000000001000000",
+		},
+	}
+
+	for i, test := range tests {
+		for _, host := range test.hosts {
+			hexHost, err := hostToHex(host)
+			if err != nil {
+				t.Fatalf("[%02d] unexpected err: %v [host: %s]", i, err, host)
+			}
+
+			// Tables are written in uppercase hex
+			if hexHost = strings.ToUpper(hexHost); hexHost != test.hexHost {
+				t.Fatalf("[%02d] unexpected hexHost: %v != %v [host: %s]", i, hexHost, test.hexHost, host)
+			}
+
+			// Encoded address must appear in the real table row
+			if test.inRow && !bytes.Contains(testIPv6MPTCPEntry, []byte(hexHost)) {
+				t.Fatalf("[%02d] hexHost %v not found in table row [host: %s]", i, hexHost, host)
+			}
+		}
+	}
+}
+
+// TestLinux_tableByteOrder verifies that addresses are encoded and decoded
+// using the table byte order of both little and big endian hosts, and that
+// ports are encoded identically regardless of byte order.
+func TestLinux_tableByteOrder(t *testing.T) {
+	orig := tableByteOrder
+	defer func() { tableByteOrder = orig }()
+
+	var tests = []struct {
+		order binary.ByteOrder
+		host  string
+		port  uint16
+		hex   string
+	}{
+		// Addresses as written by a little endian host
+		{binary.LittleEndian, "104.131.14.231", 22, "E70E8368:0016"},
+		{binary.LittleEndian, "2604:a880:800:10::289:2001", 37797, "80A80426100000080000000001208902:93A5"},
+		// Addresses as written by a big endian host
+		{binary.BigEndian, "104.131.14.231", 22, "68830EE7:0016"},
+		{binary.BigEndian, "2604:a880:800:10::289:2001", 37797, "2604A880080000100000000002892001:93A5"},
+	}
+
+	for i, test := range tests {
+		tableByteOrder = test.order
+
+		hexHostPort, err := hostPortToHex(test.host, test.port)
+		if err != nil {
+			t.Fatalf("[%02d] unexpected err: %v", i, err)
+		}
+
+		if hexHostPort != test.hex {
+			t.Fatalf("[%02d] unexpected hex host:port: %v != %v [test: %v]", i, hexHostPort, test.hex, test)
+		}
+
+		addr, err := hexToTCPAddr(test.hex)
+		if err != nil {
+			t.Fatalf("[%02d] unexpected err: %v", i, err)
+		}
+
+		if want := net.ParseIP(test.host); !addr.IP.Equal(want) || addr.Port != int(test.port) {
+			t.Fatalf("[%02d] unexpected address: %v != %v [test: %v]", i, addr, net.JoinHostPort(test.host, strconv.Itoa(int(test.port))), test)
+		}
+	}
+}
+
 // TestLinux_u16PortToHex verifies that u16PortToHex generates the proper hex
 // representation of an input uint16.
 func TestLinux_u16PortToHex(t *testing.T) {
@@ -122,6 +424,75 @@ func TestLinux_u16PortToHex(t *testing.T) {
 	}
 }
 
+// TestLinux_hexToU16Port verifies that hexToU16Port decodes ports in the same
+// order as u16PortToHex encodes them, and as the kernel writes them, so that
+// ports are never byte-swapped.
+func TestLinux_hexToU16Port(t *testing.T) {
+	var tests = []struct {
+		hexPort string
+		port    uint16
+		err     error
+	}{
+		// Invalid ports
+		{"", 0, errInvalidMPTCPEntry},
+		{"1BB", 0, errInvalidMPTCPEntry},
+		{"001BB", 0, errInvalidMPTCPEntry},
+		{"ZZZZ", 0, errInvalidMPTCPEntry},
+
+		// Valid ports, in either case
+		{"0016", 22, nil},
+		{"01BB", 443, nil},
+		{"01bb", 443, nil},
+		{"BBE8", 48104, nil},
+		{"FFFF", 65535, nil},
+	}
+
+	for i, test := range tests {
+		port, err := hexToU16Port(test.hexPort)
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected err: %v != %v [test: %v]", i, err, test.err, test)
+		}
+		if err != nil {
+			continue
+		}
+
+		if port != test.port {
+			t.Fatalf("[%02d] unexpected port: %v != %v [test: %v]", i, port, test.port, test)
+		}
+
+		// Encoding the port must produce the original hex form
+		if hexPort := u16PortToHex(port); !strings.EqualFold(hexPort, test.hexPort) {
+			t.Fatalf("[%02d] unexpected hexPort: %v != %v [test: %v]", i, hexPort, test.hexPort, test)
+		}
+	}
+
+	// A known row with a remote port of 443 must not decode as the
+	// byte-swapped port 47873
+	row := bytes.Replace(testIPv4MPTCPEntry, []byte("1134B018:BBE8"), []byte("1134B018:01BB"), 1)
+	conns, err := mptcpConnectionsReaderLinux(bytes.NewReader(append(append(mptcpTableHeader, '\n'), row...)), tableOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l := len(conns); l != 1 {
+		t.Fatalf("unexpected connection count: %v != %v", l, 1)
+	}
+	if port := conns[0].RemoteAddr.Port; port != 443 {
+		t.Fatalf("unexpected remote port: %v != %v", port, 443)
+	}
+}
+
+// mustHostPortToHex converts an input host and port into their hex form,
+// failing the test if the host is invalid.
+func mustHostPortToHex(t *testing.T, host string, port uint16) string {
+	hexHostPort, err := hostPortToHex(host, port)
+	if err != nil {
+		t.Fatalf("failed to convert %q to hex: %v", host, err)
+	}
+
+	return hexHostPort
+}
+
 // TestLinux_mptcpTableReaderLinux verifies that mptcpTableReaderLinux can properly
 // parse a Linux MPTCP connections table for entries.
 func TestLinux_mptcpTableReaderLinux(t *testing.T) {
@@ -147,6 +518,14 @@ func TestLinux_mptcpTableReaderLinux(t *testing.T) {
 		{[][]byte{mptcpTableHeader, testIPv6MPTCPEntry}, "80A80426100000080000000001208902:FFFF", false, nil},
 		// Header, good IPv6 entry
 		{[][]byte{mptcpTableHeader, testIPv6MPTCPEntry}, "80A80426100000080000000001208902:93A5", true, nil},
+		// Header, IPv6 entry which matches a known IPv6 remote address
+		{[][]byte{mptcpTableHeader, testIPv4MPTCPEntry, testIPv6MPTCPEntry}, mustHostPortToHex(t, "2604:a880:800:10::289:2001", 37797), true, nil},
+		// Header, IPv4 lookup does not match an IPv4 address in an entry
+		// flagged as IPv6
+		{[][]byte{mptcpTableHeader, bytes.Replace(testIPv4MPTCPEntry, []byte("  0 "), []byte("  1 "), 1)}, "1134B018:BBE8", false, nil},
+		// Header, IPv6 lookup does not match an IPv6 address in an entry
+		// flagged as IPv4
+		{[][]byte{mptcpTableHeader, bytes.Replace(testIPv6MPTCPEntry, []byte("  1 "), []byte("  0 "), 1)}, "80A80426100000080000000001208902:93A5", false, nil},
 	}
 
 	for i, test := range tests {
@@ -159,7 +538,7 @@ func TestLinux_mptcpTableReaderLinux(t *testing.T) {
 		}
 
 		// Attempt to check MPTCP table for entry
-		ok, err := mptcpTableReaderLinux(buf, test.entry)
+		ok, err := mptcpTableReaderLinux(context.Background(), buf, test.entry)
 		if err != test.err {
 			t.Fatalf("[%02d] unexpected err: %v != %v [test: %v]", i, err, test.err, test)
 		}
@@ -170,30 +549,1715 @@ func TestLinux_mptcpTableReaderLinux(t *testing.T) {
 	}
 }
 
-// generateMockLookupMPTCPLinux generates a mock Linux MPTCP lookup table, using
-// known data.
-func generateMockLookupMPTCPLinux() func(string) (bool, error) {
-	// Generate lookup table from known hosts and ports
-	lookupSet := make(map[string]struct{})
-	for host, port := range hostPorts {
-		// Convert host to hex
-		hexHost, err := hostToHex(host)
-		if err != nil {
-			if err == ErrIPv6NotImplemented {
-				continue
+// TestLinux_mptcpConnectionsReaderLinux verifies that mptcpConnectionsReaderLinux
+// can properly parse and decode all entries from a Linux MPTCP connections table.
+func TestLinux_mptcpConnectionsReaderLinux(t *testing.T) {
+	// Decoded forms of the test entries
+	ipv4Conn := Connection{
+		LocalToken:  0x9C290BF6,
+		RemoteToken: 0x4CC0A727,
+		LocalAddr:   &net.TCPAddr{IP: net.IPv4(104, 131, 14, 231).To4(), Port: 22},
+		RemoteAddr:  &net.TCPAddr{IP: net.IPv4(24, 176, 52, 17).To4(), Port: 48104},
+		State:       StateEstablished,
+		Subflows:    1,
+		Inode:       15666,
+	}
+	queuedConn := ipv4Conn
+	queuedConn.TxQueue = 0x400
+	queuedConn.RxQueue = 0x2A
+	ipv6Conn := Connection{
+		LocalToken:  0xF6635734,
+		RemoteToken: 0x353F1E98,
+		IsIPv6:      true,
+		LocalAddr:   &net.TCPAddr{IP: net.ParseIP("2604:a880:800:10::74:c001"), Port: 8080},
+		RemoteAddr:  &net.TCPAddr{IP: net.ParseIP("2604:a880:800:10::289:2001"), Port: 37797},
+		State:       StateEstablished,
+		Subflows:    1,
+		Inode:       39893,
+	}
+
+	var tests = []struct {
+		lines [][]byte
+		conns []Connection
+		err   error
+	}{
+		// Empty file
+		{nil, nil, io.ErrUnexpectedEOF},
+		// Invalid header
+		{[][]byte{[]byte("foobar")}, nil, errInvalidMPTCPTable},
+		// Header only, no entries
+		{[][]byte{mptcpTableHeader}, nil, nil},
+		// Header, bad entry
+		{[][]byte{mptcpTableHeader, []byte("foobar")}, nil, errInvalidMPTCPEntry},
+		// Header, bad token
+		{[][]byte{mptcpTableHeader, bytes.Replace(testIPv4MPTCPEntry, []byte("9C290BF6"), []byte("ZZZZZZZZ"), 1)}, nil, errInvalidMPTCPEntry},
+		// Header, bad address
+		{[][]byte{mptcpTableHeader, bytes.Replace(testIPv4MPTCPEntry, []byte("E70E8368:0016"), []byte("E70E83:0016"), 1)}, nil, errInvalidMPTCPEntry},
+		// Header, bad state
+		{[][]byte{mptcpTableHeader, bytes.Replace(testIPv4MPTCPEntry, []byte(" 01 01 "), []byte(" ZZ 01 "), 1)}, nil, errInvalidMPTCPEntry},
+		// Header, bad number of subflows
+		{[][]byte{mptcpTableHeader, bytes.Replace(testIPv4MPTCPEntry, []byte(" 01 01 "), []byte(" 01 ZZ "), 1)}, nil, errInvalidMPTCPEntry},
+		// Header, bad inode
+		{[][]byte{mptcpTableHeader, bytes.Replace(testIPv4MPTCPEntry, []byte(" 15666"), []byte(" foo"), 1)}, nil, errInvalidMPTCPEntry},
+		// Header, bad queues
+		{[][]byte{mptcpTableHeader, bytes.Replace(testIPv4MPTCPEntry, []byte("00000000:00000000"), []byte("00000000:ZZ"), 1)}, nil, errInvalidMPTCPEntry},
+		// Header, IPv4 entry with queued bytes
+		{[][]byte{mptcpTableHeader, bytes.Replace(testIPv4MPTCPEntry, []byte("00000000:00000000"), []byte("00000400:0000002A"), 1)}, []Connection{queuedConn}, nil},
+		// Header, good IPv4 entry
+		{[][]byte{mptcpTableHeader, testIPv4MPTCPEntry}, []Connection{ipv4Conn}, nil},
+		// Header, good IPv4 and IPv6 entries
+		{[][]byte{mptcpTableHeader, testIPv4MPTCPEntry, testIPv6MPTCPEntry}, []Connection{ipv4Conn, ipv6Conn}, nil},
+	}
+
+	for i, test := range tests {
+		// Store input lines in a buffer, appending each with newline
+		buf := bytes.NewBuffer(nil)
+		for _, l := range test.lines {
+			if _, err := buf.Write(append(l, '\n')); err != nil {
+				t.Fatal(err)
 			}
+		}
 
-			panic(err)
+		// Attempt to decode all entries from MPTCP table
+		conns, err := mptcpConnectionsReaderLinux(buf, tableOptions{})
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected err: %v != %v [test: %v]", i, err, test.err, test)
 		}
 
-		// Generate table key with host and port in hex
-		key := strings.ToUpper(net.JoinHostPort(hexHost, u16PortToHex(port)))
-		lookupSet[key] = struct{}{}
+		if !reflect.DeepEqual(conns, test.conns) {
+			t.Fatalf("[%02d] unexpected conns: %v != %v [test: %v]", i, conns, test.conns, test)
+		}
 	}
+}
 
-	// Return function which does lookups with mock data
-	return func(hexHostPort string) (bool, error) {
-		_, ok := lookupSet[hexHostPort]
-		return ok, nil
+// TestLinux_mptcpConnectionsReaderLinuxExtended verifies that
+// mptcpConnectionsReaderLinux decodes timer and retransmit state from a Linux
+// MPTCP connections table in the extended format.
+func TestLinux_mptcpConnectionsReaderLinuxExtended(t *testing.T) {
+	// extended appends timer and retransmit columns to a test entry
+	extended := func(entry []byte, timer string) []byte {
+		return append(append([]byte(nil), entry...), []byte(timer)...)
+	}
+
+	ipv4Conn := Connection{
+		LocalToken:  0x9C290BF6,
+		RemoteToken: 0x4CC0A727,
+		LocalAddr:   &net.TCPAddr{IP: net.IPv4(104, 131, 14, 231).To4(), Port: 22},
+		RemoteAddr:  &net.TCPAddr{IP: net.IPv4(24, 176, 52, 17).To4(), Port: 48104},
+		State:       StateEstablished,
+		Subflows:    1,
+		Inode:       15666,
+		TimerActive: true,
+		Retransmits: 0x1A,
+	}
+	idleConn := ipv4Conn
+	idleConn.TimerActive = false
+	idleConn.Retransmits = 0
+
+	var tests = []struct {
+		lines [][]byte
+		conns []Connection
+		err   error
+	}{
+		// Extended header only, no entries
+		{[][]byte{mptcpExtendedTableHeader}, nil, nil},
+		// Extended header, basic entry
+		{[][]byte{mptcpExtendedTableHeader, testIPv4MPTCPEntry}, nil, errInvalidMPTCPEntry},
+		// Basic header, extended entry
+		{[][]byte{mptcpTableHeader, extended(testIPv4MPTCPEntry, " 01:00000014 0000001A")}, nil, errInvalidMPTCPEntry},
+		// Extended header, bad timer
+		{[][]byte{mptcpExtendedTableHeader, extended(testIPv4MPTCPEntry, " 01-00000014 0000001A")}, nil, errInvalidMPTCPEntry},
+		{[][]byte{mptcpExtendedTableHeader, extended(testIPv4MPTCPEntry, " ZZ:00000014 0000001A")}, nil, errInvalidMPTCPEntry},
+		{[][]byte{mptcpExtendedTableHeader, extended(testIPv4MPTCPEntry, " 01:ZZZZZZZZ 0000001A")}, nil, errInvalidMPTCPEntry},
+		// Extended header, bad retransmits
+		{[][]byte{mptcpExtendedTableHeader, extended(testIPv4MPTCPEntry, " 01:00000014 ZZZZZZZZ")}, nil, errInvalidMPTCPEntry},
+		// Extended header, active timer with retransmits
+		{[][]byte{mptcpExtendedTableHeader, extended(testIPv4MPTCPEntry, " 01:00000014 0000001A")}, []Connection{ipv4Conn}, nil},
+		// Extended header, no timer pending
+		{[][]byte{mptcpExtendedTableHeader, extended(testIPv4MPTCPEntry, " 00:00000000 00000000")}, []Connection{idleConn}, nil},
+	}
+
+	for i, test := range tests {
+		// Store input lines in a buffer, appending each with newline
+		buf := bytes.NewBuffer(nil)
+		for _, l := range test.lines {
+			if _, err := buf.Write(append(l, '\n')); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		conns, err := mptcpConnectionsReaderLinux(buf, tableOptions{})
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected err: %v != %v", i, err, test.err)
+		}
+
+		if !reflect.DeepEqual(conns, test.conns) {
+			t.Fatalf("[%02d] unexpected conns: %v != %v", i, conns, test.conns)
+		}
+	}
+}
+
+// TestLinux_openMPTCPTableLinux verifies that openMPTCPTableLinux returns an
+// io.ReadCloser which streams the raw contents of a MPTCP connections table.
+func TestLinux_openMPTCPTableLinux(t *testing.T) {
+	table := testLargeMPTCPTable(16)
+
+	f, err := ioutil.TempFile("", "mptcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(table); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Read raw table contents through the returned io.ReadCloser
+	rc, err := openMPTCPTableLinux(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(raw, table) {
+		t.Fatalf("unexpected raw table contents:\n%s\n!=\n%s", raw, table)
+	}
+
+	// Missing tables must not return a non-nil io.ReadCloser
+	rc, err = openMPTCPTableLinux(f.Name() + ".missing")
+	if rc != nil || !os.IsNotExist(err) {
+		t.Fatalf("expected (nil, not exist error), but got: (%v, %v)", rc, err)
+	}
+}
+
+// TestLinux_mptcpTableSymlink verifies that a MPTCP connections table which
+// is a symlink to another file is detected and read correctly, and that a
+// dangling symlink is treated as a missing table.
+func TestLinux_mptcpTableSymlink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mptcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Create a table fixture, and a symlink which points to it
+	fixture := filepath.Join(dir, "mptcp.fixture")
+	table := append(append(mptcpTableHeader, '\n'), append(testIPv4MPTCPEntry, '\n')...)
+	if err := ioutil.WriteFile(fixture, table, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	symlink := filepath.Join(dir, "mptcp")
+	if err := os.Symlink(fixture, symlink); err != nil {
+		t.Fatal(err)
+	}
+
+	ok, err := mptcpTableExistsLinux(symlink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatalf("found symlinked %s, but mptcpTableExistsLinux returned false", symlink)
+	}
+
+	// Read the table through the symlink
+	rc, err := openMPTCPTableLinux(symlink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+
+	conns, err := mptcpConnectionsReaderLinux(rc, tableOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(conns) != 1 || conns[0].LocalToken != 0x9C290BF6 {
+		t.Fatalf("unexpected connections read through symlink: %v", conns)
+	}
+
+	// Remove the fixture, leaving a dangling symlink
+	if err := os.Remove(fixture); err != nil {
+		t.Fatal(err)
+	}
+
+	ok, err = mptcpTableExistsLinux(symlink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatalf("found dangling symlink %s, but mptcpTableExistsLinux returned true", symlink)
+	}
+}
+
+// TestLinux_CheckerMaxReadBytes verifies that a Checker stops reading an
+// oversized MPTCP connections table, and returns ErrTableTooLarge.
+func TestLinux_CheckerMaxReadBytes(t *testing.T) {
+	table := testLargeMPTCPTable(1024)
+
+	orig := openRawTable
+	defer func() { openRawTable = orig }()
+	openRawTable = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(table)), nil
+	}
+
+	var tests = []struct {
+		limit int64
+		count int
+		err   error
+	}{
+		// Limit far smaller than table
+		{int64(len(mptcpTableHeader)), 0, ErrTableTooLarge},
+		// Limit one byte smaller than table
+		{int64(len(table) - 1), 0, ErrTableTooLarge},
+		// Limit exactly equal to table
+		{int64(len(table)), 1025, nil},
+		// Default limit
+		{DefaultMaxReadBytes, 1025, nil},
+	}
+
+	for i, test := range tests {
+		conns, err := NewChecker(WithMaxReadBytes(test.limit)).ListConnections()
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected err: %v != %v [test: %v]", i, err, test.err, test)
+		}
+
+		if len(conns) != test.count {
+			t.Fatalf("[%02d] unexpected connection count: %v != %v [test: %v]", i, len(conns), test.count, test)
+		}
+	}
+}
+
+// TestLinux_CheckerCheck verifies that a Checker detects connections from
+// the remote addresses of a MPTCP connections table.
+func TestLinux_CheckerCheck(t *testing.T) {
+	table := testLargeMPTCPTable(1)
+
+	orig := openRawTable
+	defer func() { openRawTable = orig }()
+	openRawTable = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(table)), nil
+	}
+
+	var tests = []struct {
+		hostport string
+		ok       bool
+		err      error
+	}{
+		// Invalid IP address
+		{"foobar:80", false, ErrInvalidIPAddress},
+		// Local address of entry
+		{"104.131.14.231:22", false, nil},
+		// Remote host, wrong port
+		{"24.176.52.17:22", false, nil},
+		// Remote host and port of IPv4 entry
+		{"24.176.52.17:48104", true, nil},
+		// Remote host and port of IPv6 entry
+		{"[2604:a880:800:10::289:2001]:37797", true, nil},
+	}
+
+	c := NewChecker()
+	for i, test := range tests {
+		ok, err := c.Check(test.hostport)
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected err: %v != %v [test: %v]", i, err, test.err, test)
+		}
+
+		if ok != test.ok {
+			t.Fatalf("[%02d] unexpected ok: %v != %v [test: %v]", i, ok, test.ok, test)
+		}
+	}
+}
+
+// TestLinux_CheckIPv6 verifies that Check matches IPv6 peers in a MPTCP
+// connections table containing both IPv4 and IPv6 entries, regardless of how
+// the peer's address is written.
+func TestLinux_CheckIPv6(t *testing.T) {
+	table := testLargeMPTCPTable(1)
+
+	origLookup := lookupMPTCPLinux
+	origOpen := openRawTable
+	defer func() {
+		lookupMPTCPLinux = origLookup
+		openRawTable = origOpen
+	}()
+	lookupMPTCPLinux = realLookupMPTCPLinux
+	openRawTable = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(table)), nil
+	}
+
+	var tests = []struct {
+		hostport string
+		ok       bool
+	}{
+		// Compressed form of IPv6 entry
+		{"[2604:a880:800:10::289:2001]:37797", true},
+		// Expanded, uppercase form of IPv6 entry
+		{"[2604:A880:0800:0010:0000:0000:0289:2001]:37797", true},
+		// IPv6 entry, wrong port
+		{"[2604:a880:800:10::289:2001]:37798", false},
+		// Local address of IPv6 entry
+		{"[2604:a880:800:10::74:c001]:8080", false},
+		// IPv4-mapped IPv6 form of IPv4 entry is looked up as IPv4
+		{"[::ffff:24.176.52.17]:48104", true},
+		// IPv4 entry
+		{"24.176.52.17:48104", true},
+	}
+
+	for i, test := range tests {
+		ok, err := Check(test.hostport)
+		if err != nil {
+			t.Fatalf("[%02d] unexpected err: %v [test: %v]", i, err, test)
+		}
+
+		if ok != test.ok {
+			t.Fatalf("[%02d] unexpected ok: %v != %v [test: %v]", i, ok, test.ok, test)
+		}
+	}
+}
+
+// TestLinux_CheckerProcPath verifies that a Checker reads the connections
+// table from the path set by WithProcPath, then ProcPathEnv, and then the
+// default path.
+func TestLinux_CheckerProcPath(t *testing.T) {
+	dir := t.TempDir()
+
+	// Tables containing one and two entries, to distinguish which was read
+	envPath := filepath.Join(dir, "env")
+	optionPath := filepath.Join(dir, "option")
+	for path, table := range map[string][]byte{
+		envPath:    testLargeMPTCPTable(0),
+		optionPath: testLargeMPTCPTable(1),
+	} {
+		if err := ioutil.WriteFile(path, table, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The default table is mocked, and contains no entries
+	orig := openRawTable
+	defer func() { openRawTable = orig }()
+	openRawTable = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(append(mptcpTableHeader, '\n'))), nil
+	}
+
+	var tests = []struct {
+		desc    string
+		env     string
+		options []Option
+		count   int
+	}{
+		{"default path", "", nil, 0},
+		{"environment variable", envPath, nil, 1},
+		{"option overrides environment variable", envPath, []Option{WithProcPath(optionPath)}, 2},
+		{"option without environment variable", "", []Option{WithProcPath(optionPath)}, 2},
+	}
+
+	for i, test := range tests {
+		t.Setenv(ProcPathEnv, test.env)
+
+		conns, err := NewChecker(test.options...).ListConnections()
+		if err != nil {
+			t.Fatalf("[%02d] unexpected err: %v [test: %v]", i, err, test.desc)
+		}
+
+		if len(conns) != test.count {
+			t.Fatalf("[%02d] unexpected connection count: %v != %v [test: %v]", i, len(conns), test.count, test.desc)
+		}
+	}
+
+	// The environment variable is only read when a Checker is created
+	t.Setenv(ProcPathEnv, envPath)
+	c := NewChecker()
+	t.Setenv(ProcPathEnv, filepath.Join(dir, "missing"))
+
+	if _, err := c.ListConnections(); err != nil {
+		t.Fatalf("unexpected err after changing environment: %v", err)
+	}
+}
+
+// TestLinux_OpenRawTableProcPath verifies that OpenRawTable opens the table
+// at the path set by ProcPathEnv, and otherwise the default table.
+func TestLinux_OpenRawTableProcPath(t *testing.T) {
+	envPath := filepath.Join(t.TempDir(), "env")
+	envTable := testLargeMPTCPTable(0)
+	if err := ioutil.WriteFile(envPath, envTable, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The default table is mocked, and contains no entries
+	defaultTable := append(mptcpTableHeader, '\n')
+	origOpen, origChecker := openRawTable, defaultChecker
+	defer func() { openRawTable, defaultChecker = origOpen, origChecker }()
+	openRawTable = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(defaultTable)), nil
+	}
+
+	var tests = []struct {
+		desc  string
+		env   string
+		table []byte
+	}{
+		{"default path", "", defaultTable},
+		{"environment variable", envPath, envTable},
+	}
+
+	for i, test := range tests {
+		// The package-level Checker reads the environment variable when the
+		// program starts
+		t.Setenv(ProcPathEnv, test.env)
+		defaultChecker = NewChecker()
+
+		rc, err := OpenRawTable()
+		if err != nil {
+			t.Fatalf("[%02d] unexpected err: %v [test: %v]", i, err, test.desc)
+		}
+
+		b, err := ioutil.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			t.Fatalf("[%02d] unexpected read err: %v [test: %v]", i, err, test.desc)
+		}
+
+		if !bytes.Equal(b, test.table) {
+			t.Fatalf("[%02d] unexpected table:\n%s\n!=\n%s [test: %v]", i, string(b), string(test.table), test.desc)
+		}
+	}
+}
+
+// TestLinux_CheckerProcRoot verifies that a Checker configured using
+// WithProcRoot reads the connections table beneath the proc root.
+func TestLinux_CheckerProcRoot(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "net"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	table := testLargeMPTCPTable(1)
+	if err := ioutil.WriteFile(filepath.Join(root, "net", "mptcp"), table, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	conns, err := NewChecker(WithProcRoot(root)).ListConnections()
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	if len(conns) != 2 {
+		t.Fatalf("unexpected connection count: %v != %v", len(conns), 2)
+	}
+}
+
+// TestLinux_CheckerLinePrefixStripper verifies that a Checker can parse a
+// MPTCP connections table captured with a syslog-style prefix on each line.
+func TestLinux_CheckerLinePrefixStripper(t *testing.T) {
+	const prefix = "Oct 14 12:00:00 myhost mptcp-dump[1234]: "
+
+	var buf bytes.Buffer
+	for _, l := range [][]byte{mptcpTableHeader, testIPv4MPTCPEntry} {
+		buf.WriteString(prefix)
+		buf.Write(append(l, '\n'))
+	}
+	table := buf.Bytes()
+
+	orig := openRawTable
+	defer func() { openRawTable = orig }()
+	openRawTable = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(table)), nil
+	}
+
+	// Strip everything up to and including the syslog tag
+	strip := func(line string) string {
+		if i := strings.Index(line, "]: "); i != -1 {
+			return line[i+len("]: "):]
+		}
+
+		return line
+	}
+
+	var tests = []struct {
+		options []Option
+		count   int
+		err     error
+	}{
+		// No stripper, prefixed header is invalid
+		{nil, 0, errInvalidMPTCPTable},
+		// Stripper removes prefix from each line
+		{[]Option{WithLinePrefixStripper(strip)}, 1, nil},
+	}
+
+	for i, test := range tests {
+		conns, err := NewChecker(test.options...).ListConnections()
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected err: %v != %v [test: %v]", i, err, test.err, test)
+		}
+
+		if len(conns) != test.count {
+			t.Fatalf("[%02d] unexpected connection count: %v != %v [test: %v]", i, len(conns), test.count, test)
+		}
+
+		if test.count > 0 && conns[0].RemoteAddr.String() != "24.176.52.17:48104" {
+			t.Fatalf("[%02d] unexpected remote address: %v", i, conns[0].RemoteAddr)
+		}
+	}
+}
+
+// TestLinux_CheckerLineSplitter verifies that a Checker can parse a MPTCP
+// connections table using a custom line splitter.
+func TestLinux_CheckerLineSplitter(t *testing.T) {
+	// join concatenates the header and test entries using sep
+	join := func(sep string) []byte {
+		return bytes.Join([][]byte{mptcpTableHeader, testIPv4MPTCPEntry, testIPv6MPTCPEntry, nil}, []byte(sep))
+	}
+
+	// scanNUL splits a capture into rows separated by NUL bytes
+	scanNUL := func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.IndexByte(data, 0); i != -1 {
+			return i + 1, data[:i], nil
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+
+		return 0, nil, nil
+	}
+
+	var tests = []struct {
+		table   []byte
+		options []Option
+		count   int
+		err     error
+	}{
+		// Default splitter, newline separated rows
+		{join("\n"), nil, 2, nil},
+		// Default splitter strips carriage returns
+		{join("\r\n"), nil, 2, nil},
+		// Default splitter, NUL separated rows are one invalid header
+		{join("\x00"), nil, 0, errInvalidMPTCPTable},
+		// Custom splitter, NUL separated rows
+		{join("\x00"), []Option{WithLineSplitter(scanNUL)}, 2, nil},
+		// Custom splitter, explicit default
+		{join("\r\n"), []Option{WithLineSplitter(bufio.ScanLines)}, 2, nil},
+	}
+
+	orig := openRawTable
+	defer func() { openRawTable = orig }()
+
+	for i, test := range tests {
+		table := test.table
+		openRawTable = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(table)), nil
+		}
+
+		conns, err := NewChecker(test.options...).ListConnections()
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected err: %v != %v", i, err, test.err)
+		}
+
+		if len(conns) != test.count {
+			t.Fatalf("[%02d] unexpected connection count: %v != %v", i, len(conns), test.count)
+		}
+	}
+}
+
+// TestLinux_CheckerSkipInvalidRows verifies that a Checker configured to skip
+// invalid rows distinguishes a table with no parseable rows from an empty
+// table.
+func TestLinux_CheckerSkipInvalidRows(t *testing.T) {
+	garbage := []byte("\x00\x13 garbage 7f3a")
+
+	var tests = []struct {
+		lines   [][]byte
+		options []Option
+		count   int
+		err     error
+	}{
+		// Header only is an empty table
+		{[][]byte{mptcpTableHeader}, []Option{WithSkipInvalidRows()}, 0, nil},
+		// All garbage rows fail on the first row by default
+		{[][]byte{mptcpTableHeader, garbage, garbage}, nil, 0, errInvalidMPTCPEntry},
+		// All garbage rows are reported as unparseable
+		{[][]byte{mptcpTableHeader, garbage, garbage}, []Option{WithSkipInvalidRows()}, 0, ErrNoParseableRows},
+		{[][]byte{mptcpTableHeader, garbage, garbage}, []Option{WithSkipInvalidRows()}, 0, errInvalidMPTCPEntry},
+		// Rows which fail to decode are also unparseable
+		{[][]byte{mptcpTableHeader, bytes.Replace(testIPv4MPTCPEntry, []byte("9C290BF6"), []byte("ZZZZZZZZ"), 1)}, []Option{WithSkipInvalidRows()}, 0, ErrNoParseableRows},
+		// Garbage rows are skipped if any row can be parsed
+		{[][]byte{mptcpTableHeader, garbage, testIPv4MPTCPEntry, garbage, testIPv6MPTCPEntry}, []Option{WithSkipInvalidRows()}, 2, nil},
+	}
+
+	orig := openRawTable
+	defer func() { openRawTable = orig }()
+
+	for i, test := range tests {
+		buf := bytes.NewBuffer(nil)
+		for _, l := range test.lines {
+			buf.Write(append(l, '\n'))
+		}
+		table := buf.Bytes()
+
+		openRawTable = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(table)), nil
+		}
+
+		conns, err := NewChecker(test.options...).ListConnections()
+		if !errors.Is(err, test.err) {
+			t.Fatalf("[%02d] unexpected err: %v != %v", i, err, test.err)
+		}
+
+		if len(conns) != test.count {
+			t.Fatalf("[%02d] unexpected connection count: %v != %v", i, len(conns), test.count)
+		}
+	}
+
+	// The first row's error is identified in the returned error
+	_, err := mptcpConnectionsReaderLinux(bytes.NewReader(bytes.Join([][]byte{mptcpTableHeader, garbage, garbage, nil}, []byte("\n"))), tableOptions{skipInvalid: true})
+	if want := "no parseable rows in connections table: row 1: invalid MPTCP connection entry"; err == nil || err.Error() != want {
+		t.Fatalf("unexpected error: %v != %v", err, want)
+	}
+}
+
+// TestLinux_Matcher verifies that a Matcher built from a Linux MPTCP
+// connections table properly reports which remote hosts it contains.
+func TestLinux_Matcher(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	for _, l := range [][]byte{mptcpTableHeader, testIPv4MPTCPEntry, testIPv6MPTCPEntry} {
+		if _, err := buf.Write(append(l, '\n')); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	remotes, err := mptcpRemotesReaderLinux(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Results must be identical with and without a host cache
+	matchers := []*Matcher{
+		{remotes: remotes},
+		{remotes: remotes, cache: newHexCache(2)},
+	}
+
+	var tests = []struct {
+		host string
+		port uint16
+		ok   bool
+	}{
+		// Invalid IP addresses
+		{badIPHostOne, 0, false},
+		// Remote host, wrong port
+		{"24.176.52.17", 22, false},
+		// Local address of entry
+		{"104.131.14.231", 22, false},
+		// Remote host and port of entry
+		{"24.176.52.17", 48104, true},
+		// Remote host and port of IPv6 entry
+		{"2604:a880:800:10::289:2001", 37797, true},
+		// Expanded form of IPv6 remote host
+		{"2604:a880:0800:0010:0000:0000:0289:2001", 37797, true},
+		// IPv6 remote host, wrong port
+		{"2604:a880:800:10::289:2001", 22, false},
+	}
+
+	for _, m := range matchers {
+		// Check each host twice, to exercise cached results
+		for j := 0; j < 2; j++ {
+			for i, test := range tests {
+				if ok := m.Contains(test.host, test.port); ok != test.ok {
+					t.Fatalf("[%02d] unexpected ok: %v != %v [cache: %v, test: %v]", i, ok, test.ok, m.cache != nil, test)
+				}
+			}
+		}
+	}
+}
+
+// BenchmarkLinux_MatcherContains measures the cost of checking a host
+// against a Matcher built from a large MPTCP connections table.
+func BenchmarkLinux_MatcherContains(b *testing.B) {
+	remotes, err := mptcpRemotesReaderLinux(bytes.NewReader(testLargeMPTCPTable(1024)))
+	if err != nil {
+		b.Fatal(err)
+	}
+	m := &Matcher{remotes: remotes}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !m.Contains("24.176.52.17", 48104) {
+			b.Fatal("expected match")
+		}
+	}
+}
+
+// BenchmarkLinux_MatcherContainsDuplicateHosts measures the cost of checking
+// many duplicate hosts against a Matcher, with and without a host cache.
+func BenchmarkLinux_MatcherContainsDuplicateHosts(b *testing.B) {
+	remotes, err := mptcpRemotesReaderLinux(bytes.NewReader(testLargeMPTCPTable(1024)))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	hosts := []string{ipv4HostOne, ipv4HostTwo, ipv4BadHostOne, ipv4BadHostTwo, "24.176.52.17"}
+
+	var benchmarks = []struct {
+		name string
+		m    *Matcher
+	}{
+		{"no cache", &Matcher{remotes: remotes}},
+		{"cache", &Matcher{remotes: remotes, cache: newHexCache(len(hosts))}},
+	}
+
+	for _, bench := range benchmarks {
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				bench.m.Contains(hosts[i%len(hosts)], 48104)
+			}
+		})
+	}
+}
+
+// BenchmarkLinux_Check measures the cost of repeatedly checking a host with
+// Check, which reads a large MPTCP connections table on every call.
+func BenchmarkLinux_Check(b *testing.B) {
+	table := testLargeMPTCPTable(1024)
+
+	lookup := lookupMPTCPLinux
+	defer func() { lookupMPTCPLinux = lookup }()
+	lookupMPTCPLinux = func(ctx context.Context, hexHostPort string) (bool, error) {
+		return mptcpTableReaderLinux(ctx, bytes.NewReader(table), hexHostPort)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ok, err := Check("24.176.52.17:48104")
+		if err != nil {
+			b.Fatal(err)
+		}
+		if !ok {
+			b.Fatal("expected match")
+		}
+	}
+}
+
+// TestLinux_CheckCoalesces verifies that concurrent Check calls for the same
+// host:port share a single table scan.
+func TestLinux_CheckCoalesces(t *testing.T) {
+	const n = 32
+
+	var (
+		scans   int32
+		release = make(chan struct{})
+	)
+
+	lookup := lookupMPTCPLinux
+	defer func() { lookupMPTCPLinux = lookup }()
+	lookupMPTCPLinux = func(ctx context.Context, hexHostPort string) (bool, error) {
+		atomic.AddInt32(&scans, 1)
+		<-release
+		return hexHostPort == "1134B018:BBE8", nil
+	}
+
+	var started, done sync.WaitGroup
+	started.Add(n)
+	done.Add(n)
+
+	for i := 0; i < n; i++ {
+		go func() {
+			defer done.Done()
+			started.Done()
+
+			ok, err := Check("24.176.52.17:48104")
+			if err != nil || !ok {
+				t.Errorf("unexpected result: %v, %v", ok, err)
+			}
+		}()
+	}
+
+	started.Wait()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	done.Wait()
+
+	if s := atomic.LoadInt32(&scans); s != 1 {
+		t.Fatalf("unexpected scan count: %v != %v", s, 1)
+	}
+}
+
+// BenchmarkLinux_CheckHotKey benchmarks concurrent Check calls which all
+// query a single host:port.
+func BenchmarkLinux_CheckHotKey(b *testing.B) {
+	table := testLargeMPTCPTable(1024)
+
+	lookup := lookupMPTCPLinux
+	defer func() { lookupMPTCPLinux = lookup }()
+	lookupMPTCPLinux = func(ctx context.Context, hexHostPort string) (bool, error) {
+		return mptcpTableReaderLinux(ctx, bytes.NewReader(table), hexHostPort)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			ok, err := Check("24.176.52.17:48104")
+			if err != nil {
+				b.Error(err)
+				return
+			}
+			if !ok {
+				b.Error("expected match")
+				return
+			}
+		}
+	})
+}
+
+// testLargeMPTCPTable generates a MPTCP connections table with n copies of
+// the IPv6 test entry, followed by the IPv4 test entry.
+func testLargeMPTCPTable(n int) []byte {
+	buf := bytes.NewBuffer(nil)
+	buf.Write(append(mptcpTableHeader, '\n'))
+	for i := 0; i < n; i++ {
+		buf.Write(append(testIPv6MPTCPEntry, '\n'))
+	}
+	buf.Write(append(testIPv4MPTCPEntry, '\n'))
+
+	return buf.Bytes()
+}
+
+// generateMockLookupMPTCPLinux generates a mock Linux MPTCP lookup table, using
+// known data.
+func generateMockLookupMPTCPLinux() func(context.Context, string) (bool, error) {
+	// Generate lookup table from known hosts and ports
+	lookupSet := make(map[string]struct{})
+	for host, port := range hostPorts {
+		// Convert host to hex
+		hexHost, err := hostToHex(host)
+		if err != nil {
+			panic(err)
+		}
+
+		// Generate table key with host and port in hex
+		key := strings.ToUpper(net.JoinHostPort(hexHost, u16PortToHex(port)))
+		lookupSet[key] = struct{}{}
+	}
+
+	// Return function which does lookups with mock data
+	return func(ctx context.Context, hexHostPort string) (bool, error) {
+		_, ok := lookupSet[hexHostPort]
+		return ok, nil
+	}
+}
+
+// TestLinux_ValidateTable verifies that ValidateTable detects the format of
+// a captured MPTCP connections table, and reports anomalies in its rows.
+func TestLinux_ValidateTable(t *testing.T) {
+	// Captures with more rows than are sampled are only partially inspected
+	many := [][]byte{mptcpTableHeader}
+	for i := 0; i < validateSampleRows+1; i++ {
+		many = append(many, testIPv4MPTCPEntry)
+	}
+
+	var tests = []struct {
+		lines [][]byte
+		info  FormatInfo
+		err   error
+	}{
+		// Empty capture
+		{nil, FormatInfo{}, io.ErrUnexpectedEOF},
+		// Wrong header
+		{[][]byte{[]byte("  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode")}, FormatInfo{}, errInvalidMPTCPTable},
+		// Header only
+		{[][]byte{mptcpTableHeader}, FormatInfo{Version: FormatV1, Columns: 10}, nil},
+		// Valid IPv4 and IPv6 rows
+		{[][]byte{mptcpTableHeader, testIPv4MPTCPEntry, testIPv6MPTCPEntry}, FormatInfo{Version: FormatV1, Columns: 10, Rows: 2}, nil},
+		// Wrong number of columns
+		{
+			[][]byte{mptcpTableHeader, bytes.Replace(testIPv4MPTCPEntry, []byte(" 15666"), nil, 1), testIPv6MPTCPEntry, append(testIPv4MPTCPEntry, []byte(" 0")...)},
+			FormatInfo{Version: FormatV1, Columns: 10, Rows: 3, Anomalies: []string{
+				"row 1: expected 10 columns, found 9",
+				"row 3: expected 10 columns, found 11",
+			}},
+			nil,
+		},
+		// Undecodable field
+		{
+			[][]byte{mptcpTableHeader, bytes.Replace(testIPv4MPTCPEntry, []byte("9C290BF6"), []byte("ZZZZZZZZ"), 1)},
+			FormatInfo{Version: FormatV1, Columns: 10, Rows: 1, Anomalies: []string{
+				"row 1: " + errInvalidMPTCPEntry.Error(),
+			}},
+			nil,
+		},
+		// Invalid or inconsistent v6 flag
+		{
+			[][]byte{mptcpTableHeader, bytes.Replace(testIPv4MPTCPEntry, []byte("  0 "), []byte("  2 "), 1), bytes.Replace(testIPv6MPTCPEntry, []byte("  1 "), []byte("  0 "), 1)},
+			FormatInfo{Version: FormatV1, Columns: 10, Rows: 2, Anomalies: []string{
+				`row 1: invalid v6 flag "2"`,
+				"row 2: address length does not match v6 flag",
+			}},
+			nil,
+		},
+		// Extended format
+		{
+			[][]byte{mptcpExtendedTableHeader, append(append([]byte(nil), testIPv4MPTCPEntry...), []byte(" 00:00000000 00000000")...), testIPv4MPTCPEntry},
+			FormatInfo{Version: FormatV2, Columns: 12, Rows: 2, Anomalies: []string{
+				"row 2: expected 12 columns, found 10",
+			}},
+			nil,
+		},
+		// Only a sample of rows is inspected
+		{many, FormatInfo{Version: FormatV1, Columns: 10, Rows: validateSampleRows}, nil},
+	}
+
+	for i, test := range tests {
+		// Store input lines in a buffer, appending each with newline
+		buf := bytes.NewBuffer(nil)
+		for _, l := range test.lines {
+			if _, err := buf.Write(append(l, '\n')); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		info, err := ValidateTable(buf)
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected err: %v != %v", i, err, test.err)
+		}
+
+		if !reflect.DeepEqual(info, test.info) {
+			t.Fatalf("[%02d] unexpected info: %#v != %#v", i, info, test.info)
+		}
+	}
+}
+
+// TestLinux_mptcpQueuesReaderLinux verifies that mptcpQueuesReaderLinux sums
+// the transmit and receive queue lengths of all entries in a Linux MPTCP
+// connections table.
+func TestLinux_mptcpQueuesReaderLinux(t *testing.T) {
+	// queued replaces the queue lengths of a test entry
+	queued := func(entry []byte, queues string) []byte {
+		return bytes.Replace(entry, []byte("00000000:00000000"), []byte(queues), 1)
+	}
+
+	var tests = []struct {
+		lines  [][]byte
+		tx, rx uint64
+		err    error
+	}{
+		// Invalid header
+		{[][]byte{[]byte("foobar")}, 0, 0, errInvalidMPTCPTable},
+		// Header only, no entries
+		{[][]byte{mptcpTableHeader}, 0, 0, nil},
+		// Header, bad queues
+		{[][]byte{mptcpTableHeader, queued(testIPv4MPTCPEntry, "00000000-00000000")}, 0, 0, errInvalidMPTCPEntry},
+		{[][]byte{mptcpTableHeader, queued(testIPv4MPTCPEntry, "ZZZZZZZZ:00000000")}, 0, 0, errInvalidMPTCPEntry},
+		{[][]byte{mptcpTableHeader, queued(testIPv4MPTCPEntry, "00000000:ZZZZZZZZ")}, 0, 0, errInvalidMPTCPEntry},
+		// Header, empty queues
+		{[][]byte{mptcpTableHeader, testIPv4MPTCPEntry, testIPv6MPTCPEntry}, 0, 0, nil},
+		// Header, queued bytes summed across entries
+		{
+			[][]byte{
+				mptcpTableHeader,
+				queued(testIPv4MPTCPEntry, "00000100:00000010"),
+				queued(testIPv6MPTCPEntry, "FFFFFFFF:00000001"),
+			},
+			0x100 + 0xffffffff, 0x11, nil,
+		},
+	}
+
+	for i, test := range tests {
+		// Store input lines in a buffer, appending each with newline
+		buf := bytes.NewBuffer(nil)
+		for _, l := range test.lines {
+			if _, err := buf.Write(append(l, '\n')); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		tx, rx, err := mptcpQueuesReaderLinux(buf, tableOptions{})
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected err: %v != %v", i, err, test.err)
+		}
+
+		if tx != test.tx || rx != test.rx {
+			t.Fatalf("[%02d] unexpected queues: (%d, %d) != (%d, %d)", i, tx, rx, test.tx, test.rx)
+		}
+	}
+}
+
+// TestLinux_addSaturating verifies that addSaturating caps sums which would
+// overflow a uint64.
+func TestLinux_addSaturating(t *testing.T) {
+	var tests = []struct {
+		a, b, sum uint64
+	}{
+		{0, 0, 0},
+		{1, 2, 3},
+		{math.MaxUint64 - 1, 1, math.MaxUint64},
+		{math.MaxUint64, 1, math.MaxUint64},
+		{math.MaxUint64, math.MaxUint64, math.MaxUint64},
+	}
+
+	for i, test := range tests {
+		if sum := addSaturating(test.a, test.b); sum != test.sum {
+			t.Fatalf("[%02d] unexpected sum: %d != %d", i, sum, test.sum)
+		}
+	}
+}
+
+// TestLinux_HeaderForFormat verifies that HeaderForFormat returns a header
+// for each known format which is accepted by the Linux MPTCP connections table
+// parser as that format.
+func TestLinux_HeaderForFormat(t *testing.T) {
+	var tests = []struct {
+		v      FormatVersion
+		header []byte
+	}{
+		{FormatUnknown, nil},
+		{FormatV1, mptcpTableHeader},
+		{FormatV2, mptcpExtendedTableHeader},
+		{FormatVersion(99), nil},
+	}
+
+	for i, test := range tests {
+		header := HeaderForFormat(test.v)
+		if !bytes.Equal(header, test.header) {
+			t.Fatalf("[%02d] unexpected header for %v:\n%q\n!=\n%q", i, test.v, header, test.header)
+		}
+
+		if header == nil {
+			continue
+		}
+
+		// The header must be accepted by the parser as the same format
+		table := append(header, '\n')
+		if _, err := mptcpConnectionsReaderLinux(bytes.NewReader(table), tableOptions{}); err != nil {
+			t.Fatalf("[%02d] header for %v rejected by parser: %v", i, test.v, err)
+		}
+
+		info, err := ValidateTable(bytes.NewReader(table))
+		if err != nil || info.Version != test.v {
+			t.Fatalf("[%02d] header for %v validated as: (%v, %v)", i, test.v, info.Version, err)
+		}
+
+		// Modifying the returned header must not affect the parser
+		header[0] = 'X'
+		if !bytes.Equal(HeaderForFormat(test.v), test.header) {
+			t.Fatalf("[%02d] header for %v was modified by caller", i, test.v)
+		}
+	}
+
+	// Every known format must have a header
+	for _, f := range mptcpTableFormats {
+		if HeaderForFormat(f.version) == nil {
+			t.Fatalf("no header for known format %v", f.version)
+		}
+	}
+}
+
+// TestLinux_ListLazyConnections verifies that LazyConnections read from a
+// Linux MPTCP connections table decode each field only when it is accessed,
+// and that WithEagerDecode reports invalid entries when listing.
+func TestLinux_ListLazyConnections(t *testing.T) {
+	badToken := bytes.Replace(testIPv4MPTCPEntry, []byte("9C290BF6"), []byte("ZZZZZZZZ"), 1)
+	badRemote := bytes.Replace(testIPv6MPTCPEntry, []byte("80A80426100000080000000001208902:93A5"), []byte("80A8042610000008:93A5"), 1)
+
+	var buf bytes.Buffer
+	for _, l := range [][]byte{mptcpTableHeader, testIPv4MPTCPEntry, badToken, badRemote} {
+		buf.Write(append(l, '\n'))
+	}
+	table := buf.Bytes()
+
+	orig := openRawTable
+	defer func() { openRawTable = orig }()
+	openRawTable = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(table)), nil
+	}
+
+	// Invalid entries are not reported until their fields are decoded
+	lazy, err := NewChecker().ListLazyConnections()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lazy) != 3 {
+		t.Fatalf("unexpected number of connections: %d", len(lazy))
+	}
+
+	// Valid entry decodes each field
+	good := lazy[0]
+	local, err := good.LocalToken()
+	if err != nil || local != 0x9C290BF6 {
+		t.Fatalf("unexpected local token: (%x, %v)", local, err)
+	}
+	remote, err := good.RemoteToken()
+	if err != nil || remote != 0x4CC0A727 {
+		t.Fatalf("unexpected remote token: (%x, %v)", remote, err)
+	}
+	localAddr, err := good.LocalAddr()
+	if err != nil || localAddr.String() != "104.131.14.231:22" {
+		t.Fatalf("unexpected local address: (%v, %v)", localAddr, err)
+	}
+	remoteAddr, err := good.RemoteAddr()
+	if err != nil || remoteAddr.String() != "24.176.52.17:48104" {
+		t.Fatalf("unexpected remote address: (%v, %v)", remoteAddr, err)
+	}
+
+	// Decoded fields are cached
+	if again, _ := good.RemoteAddr(); again != remoteAddr {
+		t.Fatalf("remote address was decoded again: %p != %p", again, remoteAddr)
+	}
+
+	// Lazy and eager decoding agree
+	eager, err := mptcpConnectionsReaderLinux(bytes.NewReader(append(append(mptcpTableHeader, '\n'), testIPv4MPTCPEntry...)), tableOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, err := good.Connection(); err != nil || !reflect.DeepEqual(c, eager[0]) {
+		t.Fatalf("unexpected connection: (%v, %v) != %v", c, err, eager[0])
+	}
+
+	// Invalid fields only fail their own accessors
+	if _, err := lazy[1].LocalToken(); err != errInvalidMPTCPEntry {
+		t.Fatalf("unexpected local token err: %v", err)
+	}
+	if _, err := lazy[1].RemoteAddr(); err != nil {
+		t.Fatalf("unexpected remote address err: %v", err)
+	}
+	if !lazy[2].IsIPv6() {
+		t.Fatal("expected IPv6 connection")
+	}
+	if _, err := lazy[2].LocalAddr(); err != nil {
+		t.Fatalf("unexpected local address err: %v", err)
+	}
+	if _, err := lazy[2].RemoteAddr(); err != errInvalidMPTCPEntry {
+		t.Fatalf("unexpected remote address err: %v", err)
+	}
+	if _, err := lazy[2].Connection(); err != errInvalidMPTCPEntry {
+		t.Fatalf("unexpected connection err: %v", err)
+	}
+
+	// Eager decoding reports invalid entries when listing
+	if lazy, err := NewChecker(WithEagerDecode()).ListLazyConnections(); lazy != nil || err != errInvalidMPTCPEntry {
+		t.Fatalf("unexpected eager result: (%v, %v)", lazy, err)
+	}
+}
+
+// benchmarkLinuxTable returns a Linux MPTCP connections table with n IPv4
+// entries.
+func benchmarkLinuxTable(n int) []byte {
+	var buf bytes.Buffer
+	buf.Write(append(mptcpTableHeader, '\n'))
+	for i := 0; i < n; i++ {
+		buf.Write(append(testIPv4MPTCPEntry, '\n'))
+	}
+
+	return buf.Bytes()
+}
+
+// BenchmarkLinux_ListConnectionsEager measures decoding every field of each
+// entry of a large Linux MPTCP connections table.
+func BenchmarkLinux_ListConnectionsEager(b *testing.B) {
+	table := benchmarkLinuxTable(10000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		conns, err := mptcpConnectionsReaderLinux(bytes.NewReader(table), tableOptions{})
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		for _, c := range conns {
+			_ = c.RemoteAddr
+		}
+	}
+}
+
+// BenchmarkLinux_ListConnectionsLazy measures listing a large Linux MPTCP
+// connections table lazily, and decoding only the remote address of each
+// entry.
+func BenchmarkLinux_ListConnectionsLazy(b *testing.B) {
+	table := benchmarkLinuxTable(10000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		lazy, err := mptcpLazyReaderLinux(bytes.NewReader(table), tableOptions{})
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		for _, lc := range lazy {
+			if _, err := lc.RemoteAddr(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// TestLinux_Connections verifies that Connections returns an empty slice for
+// a Linux MPTCP connections table which contains only a header, and reports
+// an unexpected EOF for a table which is empty.
+func TestLinux_Connections(t *testing.T) {
+	var tests = []struct {
+		table []byte
+		conns []Connection
+		err   error
+	}{
+		{nil, nil, io.ErrUnexpectedEOF},
+		{append(append([]byte(nil), mptcpTableHeader...), '\n'), []Connection{}, nil},
+	}
+
+	orig := openRawTable
+	defer func() { openRawTable = orig }()
+
+	for i, test := range tests {
+		table := test.table
+		openRawTable = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(table)), nil
+		}
+
+		conns, err := Connections()
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected err: %v != %v", i, err, test.err)
+		}
+
+		if (conns == nil) != (test.conns == nil) || len(conns) != len(test.conns) {
+			t.Fatalf("[%02d] unexpected connections: %#v != %#v", i, conns, test.conns)
+		}
+	}
+}
+
+// TestLinux_CheckLocal verifies that CheckLocal matches only the local address
+// of entries in a Linux MPTCP connections table, and that Checker.Check
+// matches only the remote address.
+func TestLinux_CheckLocal(t *testing.T) {
+	var buf bytes.Buffer
+	for _, l := range [][]byte{mptcpTableHeader, testIPv4MPTCPEntry, testIPv6MPTCPEntry} {
+		buf.Write(append(l, '\n'))
+	}
+	table := buf.Bytes()
+
+	orig := openRawTable
+	defer func() { openRawTable = orig }()
+	openRawTable = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(table)), nil
+	}
+
+	var tests = []struct {
+		host       string
+		port       uint16
+		local, rmt bool
+		localErr   error
+	}{
+		// Invalid IP address
+		{"foobar", 22, false, false, ErrInvalidIPAddress},
+		// Local addresses are only found by the local check
+		{"104.131.14.231", 22, true, false, nil},
+		{"2604:a880:800:10::74:c001", 8080, true, false, nil},
+		// Remote addresses are only found by the remote check
+		{"24.176.52.17", 48104, false, true, nil},
+		{"2604:a880:800:10::289:2001", 37797, false, true, nil},
+		// Matching host with a different port
+		{"104.131.14.231", 23, false, false, nil},
+	}
+
+	for i, test := range tests {
+		local, err := CheckLocal(test.host, test.port)
+		if err != test.localErr {
+			t.Fatalf("[%02d] unexpected local err: %v != %v", i, err, test.localErr)
+		}
+		if local != test.local {
+			t.Fatalf("[%02d] unexpected local match: %v != %v", i, local, test.local)
+		}
+
+		if test.localErr != nil {
+			continue
+		}
+
+		// The package-level remote lookup is mocked for all tests, so use
+		// a Checker which reads the same table
+		remote, err := NewChecker().Check(net.JoinHostPort(test.host, strconv.Itoa(int(test.port))))
+		if err != nil {
+			t.Fatalf("[%02d] unexpected remote err: %v", i, err)
+		}
+		if remote != test.rmt {
+			t.Fatalf("[%02d] unexpected remote match: %v != %v", i, remote, test.rmt)
+		}
+	}
+}
+
+// TestLinux_mptcpLocalTableReaderLinux verifies that mptcpLocalTableReaderLinux
+// only matches entries of the same address family as the input.
+func TestLinux_mptcpLocalTableReaderLinux(t *testing.T) {
+	var tests = []struct {
+		lines [][]byte
+		entry string
+		ok    bool
+	}{
+		// Local address matches, remote address does not
+		{[][]byte{mptcpTableHeader, testIPv4MPTCPEntry}, "E70E8368:0016", true},
+		{[][]byte{mptcpTableHeader, testIPv4MPTCPEntry}, "1134B018:BBE8", false},
+		{[][]byte{mptcpTableHeader, testIPv6MPTCPEntry}, "80A80426100000080000000001C07400:1F90", true},
+		// Entry flagged as the wrong address family
+		{[][]byte{mptcpTableHeader, bytes.Replace(testIPv4MPTCPEntry, []byte("  0 "), []byte("  1 "), 1)}, "E70E8368:0016", false},
+	}
+
+	for i, test := range tests {
+		buf := bytes.NewBuffer(nil)
+		for _, l := range test.lines {
+			buf.Write(append(l, '\n'))
+		}
+
+		ok, err := mptcpLocalTableReaderLinux(buf, test.entry)
+		if err != nil {
+			t.Fatalf("[%02d] unexpected err: %v", i, err)
+		}
+
+		if ok != test.ok {
+			t.Fatalf("[%02d] unexpected ok: %v != %v", i, ok, test.ok)
+		}
+	}
+}
+
+// TestLinux_checkMPTCPNetlinkFallback verifies that checkMPTCP and a Checker
+// only retrieve connections using netlink when the default connections table
+// does not exist.
+func TestLinux_checkMPTCPNetlinkFallback(t *testing.T) {
+	table := testLargeMPTCPTable(1)
+	conns, err := mptcpConnectionsReaderLinux(bytes.NewReader(table), tableOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := conns[0].RemoteAddr
+
+	origLookup := lookupMPTCPLinux
+	origOpen := openRawTable
+	origList := listNetlinkConnections
+	defer func() {
+		lookupMPTCPLinux = origLookup
+		openRawTable = origOpen
+		listNetlinkConnections = origList
+	}()
+
+	// Use the real table lookup, rather than the global mock
+	lookupMPTCPLinux = realLookupMPTCPLinux
+
+	errOpen := errors.New("permission denied")
+
+	var tests = []struct {
+		desc    string
+		open    func() (io.ReadCloser, error)
+		netlink []Connection
+		ok      bool
+		count   int
+		err     error
+		calls   int
+	}{
+		{
+			desc: "table present",
+			open: func() (io.ReadCloser, error) {
+				return ioutil.NopCloser(bytes.NewReader(table)), nil
+			},
+			ok:    true,
+			count: len(conns),
+		},
+		{
+			desc: "table empty, netlink not consulted",
+			open: func() (io.ReadCloser, error) {
+				return ioutil.NopCloser(bytes.NewReader(mptcpTableHeader)), nil
+			},
+			netlink: conns,
+		},
+		{
+			desc:    "table missing, netlink connection",
+			open:    func() (io.ReadCloser, error) { return nil, os.ErrNotExist },
+			netlink: conns,
+			ok:      true,
+			count:   len(conns),
+			calls:   2,
+		},
+		{
+			desc:  "table missing, no netlink connections",
+			open:  func() (io.ReadCloser, error) { return nil, os.ErrNotExist },
+			calls: 2,
+		},
+		{
+			desc: "table error",
+			open: func() (io.ReadCloser, error) { return nil, errOpen },
+			err:  errOpen,
+		},
+	}
+
+	for i, test := range tests {
+		var calls int
+		openRawTable = test.open
+		listNetlinkConnections = func() ([]Connection, error) {
+			calls++
+			return test.netlink, nil
+		}
+
+		ok, err := checkMPTCP(context.Background(), remote.IP.String(), uint16(remote.Port))
+		if !errors.Is(err, test.err) {
+			t.Fatalf("[%02d] unexpected check err: %v != %v [test: %v]", i, err, test.err, test.desc)
+		}
+
+		if ok != test.ok {
+			t.Fatalf("[%02d] unexpected ok: %v != %v [test: %v]", i, ok, test.ok, test.desc)
+		}
+
+		list, err := NewChecker().ListConnections()
+		if !errors.Is(err, test.err) {
+			t.Fatalf("[%02d] unexpected list err: %v != %v [test: %v]", i, err, test.err, test.desc)
+		}
+
+		if len(list) != test.count {
+			t.Fatalf("[%02d] unexpected connection count: %v != %v [test: %v]", i, len(list), test.count, test.desc)
+		}
+
+		if calls != test.calls {
+			t.Fatalf("[%02d] unexpected netlink calls: %v != %v [test: %v]", i, calls, test.calls, test.desc)
+		}
+	}
+
+	// A configured table path is never replaced by netlink
+	listNetlinkConnections = func() ([]Connection, error) {
+		t.Fatal("netlink consulted for configured table path")
+		return nil, nil
+	}
+
+	_, err = NewChecker(WithProcPath(filepath.Join(t.TempDir(), "missing"))).ListConnections()
+	if !os.IsNotExist(err) {
+		t.Fatalf("unexpected err for missing configured table: %v", err)
+	}
+}
+
+// cancelReader is an io.Reader which cancels a context once the first read
+// from the underlying io.Reader completes.
+type cancelReader struct {
+	r      io.Reader
+	cancel context.CancelFunc
+	n      int
+}
+
+// Read implements io.Reader.
+func (r *cancelReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.n += n
+	r.cancel()
+	return n, err
+}
+
+// TestLinux_mptcpTableReaderLinuxCanceled verifies that mptcpTableReaderLinux
+// stops scanning a large MPTCP connections table once its context is
+// canceled, and returns context.Canceled.
+func TestLinux_mptcpTableReaderLinuxCanceled(t *testing.T) {
+	table := testLargeMPTCPTable(100000)
+	hexHostPort := mustHostPortToHex(t, "192.0.2.1", 80)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r := &cancelReader{r: bytes.NewReader(table), cancel: cancel}
+	ok, err := mptcpTableReaderLinux(ctx, r, hexHostPort)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected err: %v != %v", err, context.Canceled)
+	}
+	if ok {
+		t.Fatal("canceled scan reported a match")
+	}
+
+	if r.n >= len(table) {
+		t.Fatalf("canceled scan read entire table: %d bytes", r.n)
+	}
+}
+
+// TestLinux_CheckContext verifies that CheckContext returns the error of a
+// context which is done before or during a table scan, and does not open the
+// table if the context is already done.
+func TestLinux_CheckContext(t *testing.T) {
+	table := testLargeMPTCPTable(100000)
+
+	origLookup := lookupMPTCPLinux
+	origOpen := openRawTable
+	defer func() {
+		lookupMPTCPLinux = origLookup
+		openRawTable = origOpen
+	}()
+	lookupMPTCPLinux = realLookupMPTCPLinux
+
+	var opens int
+	var reader *cancelReader
+	var cancel context.CancelFunc
+	openRawTable = func() (io.ReadCloser, error) {
+		opens++
+		reader = &cancelReader{r: bytes.NewReader(table), cancel: cancel}
+		return ioutil.NopCloser(reader), nil
+	}
+
+	// Canceled mid-scan
+	var ctx context.Context
+	ctx, cancel = context.WithCancel(context.Background())
+	ok, err := CheckContext(ctx, "192.0.2.1", 80)
+	cancel()
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected err: %v != %v", err, context.Canceled)
+	}
+	if ok {
+		t.Fatal("canceled check reported a match")
+	}
+	if reader.n >= len(table) {
+		t.Fatalf("canceled check read entire table: %d bytes", reader.n)
+	}
+
+	// Deadline exceeded before the table is opened
+	opens = 0
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	_, err = CheckContext(ctx, "24.176.52.17", 48104)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("unexpected err: %v != %v", err, context.DeadlineExceeded)
+	}
+	if opens != 0 {
+		t.Fatalf("table opened %d times after deadline", opens)
+	}
+
+	// Context which is never done finds the final entry
+	cancel = func() {}
+	ok, err = CheckContext(context.Background(), "24.176.52.17", 48104)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("expected match")
+	}
+}
+
+// TestLinux_mptcpEnabledLinuxCanceled verifies that mptcpEnabledLinux does
+// not consult the sysctl or any capability signal once its context is done.
+func TestLinux_mptcpEnabledLinuxCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	signal := func() (bool, error) {
+		t.Fatal("capability signal consulted after cancellation")
+		return false, nil
+	}
+
+	ok, err := mptcpEnabledLinux(ctx, filepath.Join(t.TempDir(), "missing"), []func() (bool, error){signal})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected err: %v != %v", err, context.Canceled)
+	}
+	if ok {
+		t.Fatal("canceled check reported enabled")
+	}
+
+	ok, err = EnabledContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected EnabledContext err: %v != %v", err, context.Canceled)
+	}
+	if ok {
+		t.Fatal("canceled EnabledContext reported enabled")
+	}
+}
+
+// TestLinux_netlinkFallbackLookups verifies that each lookup which reads the
+// default connections table uses netlink when the table does not exist.
+func TestLinux_netlinkFallbackLookups(t *testing.T) {
+	conns, err := mptcpConnectionsReaderLinux(bytes.NewReader(testLargeMPTCPTable(1)), tableOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ipv4Conn := conns[len(conns)-1]
+
+	origOpen := openRawTable
+	origList := listNetlinkConnections
+	defer func() {
+		openRawTable = origOpen
+		listNetlinkConnections = origList
+	}()
+	openRawTable = func() (io.ReadCloser, error) {
+		return nil, os.ErrNotExist
+	}
+	listNetlinkConnections = func() ([]Connection, error) {
+		return conns, nil
+	}
+
+	// Local address of IPv4 entry
+	ok, err := CheckLocal(ipv4Conn.LocalAddr.IP.String(), uint16(ipv4Conn.LocalAddr.Port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("CheckLocal did not match local address of netlink connection")
+	}
+
+	// Remote address of IPv4 entry is not a local address
+	ok, err = CheckLocal(ipv4Conn.RemoteAddr.IP.String(), uint16(ipv4Conn.RemoteAddr.Port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("CheckLocal matched remote address of netlink connection")
+	}
+
+	// Remote addresses of all entries
+	m, err := NewMatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, c := range conns {
+		if !m.Contains(c.RemoteAddr.IP.String(), uint16(c.RemoteAddr.Port)) {
+			t.Fatalf("[%02d] Matcher does not contain netlink connection: %v", i, c.RemoteAddr)
+		}
+	}
+
+	// All entries, in order
+	connC, errC := ConnectionsChan(context.Background())
+	var streamed []Connection
+	for c := range connC {
+		streamed = append(streamed, c)
+	}
+	if err := <-errC; err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(streamed, conns) {
+		t.Fatalf("unexpected streamed connections:\n- want: %v\n-  got: %v", conns, streamed)
+	}
+
+	lazy, err := ListLazyConnections()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lazy) != len(conns) {
+		t.Fatalf("unexpected lazy connection count: %v != %v", len(lazy), len(conns))
+	}
+	for i := range lazy {
+		c, err := lazy[i].Connection()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(c, conns[i]) {
+			t.Fatalf("[%02d] unexpected lazy connection:\n- want: %v\n-  got: %v", i, conns[i], c)
+		}
+	}
+
+	// Queue lengths are not reported by netlink
+	tx, rx, err := TotalQueuedBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx != 0 || rx != 0 {
+		t.Fatalf("unexpected queued bytes: %v, %v", tx, rx)
 	}
 }
@@ -2,12 +2,125 @@
 
 package mptcp
 
-// checkMPTCP is not currently implemented on non-Linux platforms.
-var checkMPTCP = func(host string, port uint16) (bool, error) {
+import "io"
+
+// matcherRemotes is not currently implemented on non-Linux platforms.
+var matcherRemotes = func() (map[string]struct{}, error) {
+	return nil, ErrNotImplemented
+}
+
+// hostToHex is not currently implemented on non-Linux platforms.
+func hostToHex(host string) (string, error) {
+	return "", ErrNotImplemented
+}
+
+// joinHexHostPort is not currently implemented on non-Linux platforms.
+func joinHexHostPort(hexHost string, port uint16) string {
+	return ""
+}
+
+// checkFallback is not currently implemented on non-Linux platforms.
+var checkFallback = func(host string, port uint16) (FallbackResult, error) {
+	return FallbackResult{}, ErrNotImplemented
+}
+
+// openRawTable is not currently implemented on non-Linux platforms.
+var openRawTable = func() (io.ReadCloser, error) {
+	return nil, ErrNotImplemented
+}
+
+// listConnectionDetails is not currently implemented on non-Linux platforms.
+var listConnectionDetails = func() ([]ConnectionDetail, error) {
+	return nil, ErrNotImplemented
+}
+
+// listDiagConnections is not currently implemented on non-Linux platforms.
+var listDiagConnections = func() ([]ConnectionDetail, error) {
+	return nil, ErrNotImplemented
+}
+
+// isFDMPTCP is not currently implemented on non-Linux platforms.
+var isFDMPTCP = func(fd int) (bool, error) {
 	return false, ErrNotImplemented
 }
 
-// mptcpEnabled always returns false unless explicitly supported by a platform.
-var mptcpEnabled = func() (bool, error) {
-	return false, nil
+// fdInfo is not currently implemented on non-Linux platforms.
+var fdInfo = func(fd int) (*ConnInfo, error) {
+	return nil, ErrNotImplemented
+}
+
+// validateTable is not currently implemented on non-Linux platforms.
+var validateTable = func(r io.Reader) (FormatInfo, error) {
+	return FormatInfo{}, ErrNotImplemented
+}
+
+// totalQueuedBytes is not currently implemented on non-Linux platforms.
+var totalQueuedBytes = func() (uint64, uint64, error) {
+	return 0, 0, ErrNotImplemented
+}
+
+// headerForFormat always returns nil on non-Linux platforms, which have no
+// connections table.
+var headerForFormat = func(v FormatVersion) []byte {
+	return nil
+}
+
+// listLazyConnections is not currently implemented on non-Linux platforms.
+func (c *Checker) listLazyConnections() ([]*LazyConnection, error) {
+	return nil, ErrNotImplemented
+}
+
+// listAllSubflows is not currently implemented on non-Linux platforms.
+var listAllSubflows = func() ([]Subflow, error) {
+	return nil, ErrNotImplemented
+}
+
+// mptcpStats is not currently implemented on non-Linux platforms.
+var mptcpStats = func() (Counters, error) {
+	return nil, ErrNotImplemented
+}
+
+// dialMonitor is not currently implemented on non-Linux platforms.
+var dialMonitor = func() (eventReceiver, error) {
+	return nil, ErrNotImplemented
+}
+
+// runInNetNS is not currently implemented on non-Linux platforms.
+var runInNetNS = func(fd int, fn func() error) error {
+	return ErrNotImplemented
+}
+
+// openNetNSTable is not currently implemented on non-Linux platforms.
+var openNetNSTable = func() (io.ReadCloser, error) {
+	return nil, ErrNotImplemented
+}
+
+// pmEndpoints is not currently implemented on non-Linux platforms.
+var pmEndpoints = func() ([]Endpoint, error) {
+	return nil, ErrNotImplemented
+}
+
+// pmAddEndpoint is not currently implemented on non-Linux platforms.
+var pmAddEndpoint = func(e Endpoint) error {
+	return ErrNotImplemented
+}
+
+// pmDeleteEndpoint is not currently implemented on non-Linux platforms.
+var pmDeleteEndpoint = func(id uint8) error {
+	return ErrNotImplemented
+}
+
+// pmFlushEndpoints is not currently implemented on non-Linux platforms.
+var pmFlushEndpoints = func() error {
+	return ErrNotImplemented
+}
+
+// pmLimits is not currently implemented on non-Linux platforms.
+var pmLimits = func() (Limits, error) {
+	return Limits{}, ErrNotImplemented
+}
+
+// pmSetLimits is not currently implemented on non-Linux platforms.
+var pmSetLimits = func(l Limits) error {
+	return ErrNotImplemented
 }
@@ -4,20 +4,158 @@ package mptcp
 
 import "testing"
 
-// TestOthers_checkMPTCP verifies that checkMPTCP is not implemented on
-// platforms other than Linux.
-func TestOthers_checkMPTCP(t *testing.T) {
-	ok, err := checkMPTCP("localhost", 8080)
+// TestOthers_NewMatcher verifies that NewMatcher is not implemented on
+// other than Linux.
+func TestOthers_NewMatcher(t *testing.T) {
+	m, err := NewMatcher()
+	if m != nil || err != ErrNotImplemented {
+		t.Fatalf("NewMatcher is not implemented, but returned: (%v, %v)", m, err)
+	}
+}
+
+// TestOthers_openRawTable verifies that openRawTable is not implemented on
+// other than Linux.
+func TestOthers_openRawTable(t *testing.T) {
+	rc, err := openRawTable()
+	if rc != nil || err != ErrNotImplemented {
+		t.Fatalf("openRawTable is not implemented, but returned: (%v, %v)", rc, err)
+	}
+}
+
+// TestOthers_checkFallback verifies that checkFallback is not implemented on
+// other than Linux.
+func TestOthers_checkFallback(t *testing.T) {
+	r, err := checkFallback("localhost", 8080)
+	if r != (FallbackResult{}) || err != ErrNotImplemented {
+		t.Fatalf("checkFallback is not implemented, but returned: (%v, %v)", r, err)
+	}
+}
+
+// TestOthers_listConnectionDetails verifies that listConnectionDetails is not
+// implemented on platforms other than Linux.
+func TestOthers_listConnectionDetails(t *testing.T) {
+	details, err := listConnectionDetails()
+	if details != nil || err != ErrNotImplemented {
+		t.Fatalf("listConnectionDetails is not implemented, but returned: (%v, %v)", details, err)
+	}
+}
+
+// TestOthers_listDiagConnections verifies that listDiagConnections is not
+// implemented on platforms other than Linux.
+func TestOthers_listDiagConnections(t *testing.T) {
+	details, err := listDiagConnections()
+	if details != nil || err != ErrNotImplemented {
+		t.Fatalf("listDiagConnections is not implemented, but returned: (%v, %v)", details, err)
+	}
+}
+
+// TestOthers_isFDMPTCP verifies that isFDMPTCP is not implemented on
+// other than Linux.
+func TestOthers_isFDMPTCP(t *testing.T) {
+	ok, err := isFDMPTCP(0)
 	if ok || err != ErrNotImplemented {
-		t.Fatalf("checkMPTCP is not implemented, but returned: (%v, %v)", ok, err)
+		t.Fatalf("isFDMPTCP is not implemented, but returned: (%v, %v)", ok, err)
+	}
+}
+
+// TestOthers_ValidateTable verifies that ValidateTable is not implemented on
+// other than Linux.
+func TestOthers_ValidateTable(t *testing.T) {
+	info, err := ValidateTable(nil)
+	if info.Version != FormatUnknown || err != ErrNotImplemented {
+		t.Fatalf("ValidateTable is not implemented, but returned: (%v, %v)", info, err)
+	}
+}
+
+// TestOthers_TotalQueuedBytes verifies that TotalQueuedBytes is not
+// implemented on platforms other than Linux.
+func TestOthers_TotalQueuedBytes(t *testing.T) {
+	tx, rx, err := TotalQueuedBytes()
+	if tx != 0 || rx != 0 || err != ErrNotImplemented {
+		t.Fatalf("TotalQueuedBytes is not implemented, but returned: (%v, %v, %v)", tx, rx, err)
+	}
+}
+
+// TestOthers_HeaderForFormat verifies that HeaderForFormat returns no header
+// on platforms other than Linux.
+func TestOthers_HeaderForFormat(t *testing.T) {
+	for _, v := range []FormatVersion{FormatUnknown, FormatV1, FormatV2} {
+		if h := HeaderForFormat(v); h != nil {
+			t.Fatalf("HeaderForFormat should return nil for %v, but returned: %q", v, h)
+		}
+	}
+}
+
+// TestOthers_ListLazyConnections verifies that ListLazyConnections is not
+// implemented on platforms other than Linux.
+func TestOthers_ListLazyConnections(t *testing.T) {
+	lazy, err := ListLazyConnections()
+	if lazy != nil || err != ErrNotImplemented {
+		t.Fatalf("ListLazyConnections is not implemented, but returned: (%v, %v)", lazy, err)
+	}
+}
+
+// TestOthers_Subflows verifies that Subflows is not implemented on
+// other than Linux.
+func TestOthers_Subflows(t *testing.T) {
+	subflows, err := Subflows("127.0.0.1", 8080)
+	if subflows != nil || err != ErrNotImplemented {
+		t.Fatalf("Subflows is not implemented, but returned: (%v, %v)", subflows, err)
+	}
+}
+
+// TestOthers_Stats verifies that Stats is not implemented on platforms
+// other than Linux.
+func TestOthers_Stats(t *testing.T) {
+	stats, err := Stats()
+	if stats != nil || err != ErrNotImplemented {
+		t.Fatalf("Stats is not implemented, but returned: (%v, %v)", stats, err)
+	}
+}
+
+// TestOthers_fdInfo verifies that fdInfo is not implemented on platforms
+// other than Linux.
+func TestOthers_fdInfo(t *testing.T) {
+	info, err := fdInfo(0)
+	if info != nil || err != ErrNotImplemented {
+		t.Fatalf("fdInfo is not implemented, but returned: (%v, %v)", info, err)
+	}
+}
+
+// TestOthers_dialMonitor verifies that dialMonitor is not implemented on
+// platforms other than Linux.
+func TestOthers_dialMonitor(t *testing.T) {
+	r, err := dialMonitor()
+	if r != nil || err != ErrNotImplemented {
+		t.Fatalf("dialMonitor is not implemented, but returned: (%v, %v)", r, err)
+	}
+}
+
+// TestOthers_CheckerNetNS verifies that a Checker configured using WithNetNS
+// cannot list connections on platforms other than Linux.
+func TestOthers_CheckerNetNS(t *testing.T) {
+	conns, err := NewChecker(WithNetNS(3)).ListConnections()
+	if conns != nil || err != ErrNotImplemented {
+		t.Fatalf("WithNetNS is not implemented, but returned: (%v, %v)", conns, err)
 	}
 }
 
-// TestOthers_mptcpEnabled verifies that mptcpEnabled always returns
-// false unless a platform explicitly supports it.
-func TestOthers_mptcpEnabled(t *testing.T) {
-	ok, err := mptcpEnabled()
-	if ok || err != nil {
-		t.Fatalf("mptcpEnabled should return (false, nil), but returned: (%v, %v)", ok, err)
+// TestOthers_PathManager verifies that the path manager functions are not
+// implemented on platforms other than Linux.
+func TestOthers_PathManager(t *testing.T) {
+	if endpoints, err := Endpoints(); endpoints != nil || err != ErrNotImplemented {
+		t.Fatalf("Endpoints is not implemented, but returned: (%v, %v)", endpoints, err)
+	}
+	if err := DeleteEndpoint(1); err != ErrNotImplemented {
+		t.Fatalf("DeleteEndpoint is not implemented, but returned: %v", err)
+	}
+	if err := FlushEndpoints(); err != ErrNotImplemented {
+		t.Fatalf("FlushEndpoints is not implemented, but returned: %v", err)
+	}
+	if _, err := ReadLimits(); err != ErrNotImplemented {
+		t.Fatalf("ReadLimits is not implemented, but returned: %v", err)
+	}
+	if err := SetLimits(Limits{}); err != ErrNotImplemented {
+		t.Fatalf("SetLimits is not implemented, but returned: %v", err)
 	}
 }
@@ -0,0 +1,470 @@
+package mptcp
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultMaxReadBytes is the default maximum number of bytes a Checker
+	// will read from a connections table.  It is far larger than any
+	// legitimate table, and exists only to bound memory and time spent
+	// reading a malicious or broken data source.
+	DefaultMaxReadBytes int64 = 64 << 20
+
+	// ProcPathEnv is the environment variable which, if set, overrides the
+	// path of the connections table read by a Checker, unless the path is
+	// set explicitly using WithProcPath.
+	ProcPathEnv = "MPTCP_PROC_PATH"
+)
+
+// defaultChecker is the Checker used by package-level functions.
+var defaultChecker = NewChecker()
+
+// A Checker detects active multipath TCP connections, with behavior which
+// can be customized using Options.  A Checker is safe for concurrent use.
+type Checker struct {
+	maxReadBytes int64
+	procPath     string
+	netNS        int
+	hasNetNS     bool
+	sources      []Source
+	table        tableOptions
+	order        func(a, b Connection) int
+	resolver     Resolver
+	failMode     FailMode
+	eagerDecode  bool
+	states       []State
+	cacheTTL     time.Duration
+
+	// now is the clock used to timestamp snapshots, swappable for tests.
+	now func() time.Time
+
+	// snapshots is set once snapshots are used by CheckCached, Refresh,
+	// or RefreshEvery.  Until then, snapshots are only stored if a cache
+	// TTL is configured.
+	snapshots atomic.Bool
+
+	snapMu sync.RWMutex
+	snap   *snapshot
+
+	checks flightGroup
+}
+
+// tableOptions configure how a Checker parses a connections table.
+type tableOptions struct {
+	stripPrefix func(line string) string
+	split       bufio.SplitFunc
+	skipInvalid bool
+}
+
+// splitFunc returns the bufio.SplitFunc used to split a connections table
+// into lines.
+func (o tableOptions) splitFunc() bufio.SplitFunc {
+	if o.split != nil {
+		return o.split
+	}
+
+	return bufio.ScanLines
+}
+
+// line applies any configured transformations to a line read from
+// a connections table.
+func (o tableOptions) line(s string) string {
+	if o.stripPrefix != nil {
+		s = o.stripPrefix(s)
+	}
+
+	return s
+}
+
+// An Option configures a Checker.
+type Option func(c *Checker)
+
+// WithMaxReadBytes limits the number of bytes a Checker will read from a
+// connections table to n.  If a table is larger than n bytes, the Checker
+// will stop reading it and return ErrTableTooLarge.
+//
+// If this option is not set, DefaultMaxReadBytes is used.
+func WithMaxReadBytes(n int64) Option {
+	return func(c *Checker) {
+		c.maxReadBytes = n
+	}
+}
+
+// WithProcPath configures a Checker to read the connections table at the
+// input path, such as a table bind-mounted into a container from its host.
+//
+// The path of the connections table is chosen in order of precedence:
+//   - the path set using this option
+//   - the path set using the ProcPathEnv environment variable, which is read
+//     once when the Checker is created
+//   - the operating system's default path
+func WithProcPath(path string) Option {
+	return func(c *Checker) {
+		c.procPath = path
+	}
+}
+
+// WithProcRoot configures a Checker to read the connections table from the
+// proc filesystem mounted at the input root, such as a host's proc filesystem
+// mounted into a container, or the /proc/<pid> directory of a process in
+// another network namespace.  It is equivalent to calling WithProcPath with
+// the location of the connections table beneath root.
+func WithProcRoot(root string) Option {
+	return WithProcPath(filepath.Join(root, "net", "mptcp"))
+}
+
+// WithNetNS configures a Checker to retrieve connections from the network
+// namespace referred to by the input file descriptor, such as an open
+// /proc/<pid>/ns/net or /run/netns file, rather than from the network
+// namespace of the current process.  The file descriptor must remain open
+// for as long as the Checker is used, and joining another network namespace
+// typically requires CAP_SYS_ADMIN.
+//
+// WithNetNS has no effect on connections retrieved from Sources configured
+// using WithSources, or on a connections table read from a path configured
+// using WithProcPath or WithProcRoot, which already belongs to a namespace.
+//
+// If network namespaces are not implemented for the current operating
+// system, listing connections will return ErrNotImplemented.
+func WithNetNS(fd int) Option {
+	return func(c *Checker) {
+		c.netNS = fd
+		c.hasNetNS = true
+	}
+}
+
+// WithSources configures a Checker to retrieve connections from the input
+// sources, in priority order.  The first source which returns connections
+// without error is used, and the remaining sources are not consulted.  If
+// every source returns an error, all of the errors are returned together.
+//
+// If this option is not set, the operating system's connections table is used.
+func WithSources(sources ...Source) Option {
+	return func(c *Checker) {
+		c.sources = sources
+	}
+}
+
+// WithLinePrefixStripper configures a Checker to apply fn to each line of a
+// connections table before the line is parsed, including the header line.
+// This enables parsing of tables captured by tools such as journald or
+// syslog, which add a prefix such as a timestamp and hostname to each line.
+//
+// If this option is not set, lines are parsed exactly as they are read.
+func WithLinePrefixStripper(fn func(line string) string) Option {
+	return func(c *Checker) {
+		c.table.stripPrefix = fn
+	}
+}
+
+// WithLineSplitter configures a Checker to use split to divide a connections
+// table into lines, before any prefix is stripped by WithLinePrefixStripper.
+// This enables parsing of exotic captures in which rows are not separated by
+// newlines, such as captures which join rows using NUL bytes.  Each token
+// returned by split must contain exactly one row, or the header.
+//
+// If this option is not set, bufio.ScanLines is used, so a trailing carriage
+// return is stripped from each line.
+func WithLineSplitter(split bufio.SplitFunc) Option {
+	return func(c *Checker) {
+		c.table.split = split
+	}
+}
+
+// WithSkipInvalidRows configures a Checker to skip rows of a connections
+// table which cannot be parsed, instead of failing on the first such row.
+//
+// If every row of a table is skipped, an error which wraps both
+// ErrNoParseableRows and the error for the first row is returned, so that a
+// corrupted table is not mistaken for a table with no connections.  A table
+// which contains only a header is still reported as having no connections.
+//
+// If this option is not set, the first row which cannot be parsed causes an
+// error to be returned.
+func WithSkipInvalidRows() Option {
+	return func(c *Checker) {
+		c.table.skipInvalid = true
+	}
+}
+
+// WithStableOrder configures a Checker to sort the connections it returns,
+// so that repeated calls yield connections in an identical order.  The
+// kernel does not guarantee a stable order for its connections table.
+//
+// Connections are sorted by remote IP, remote port, local IP, and local port.
+// IP addresses are compared in their 16 byte form, so IPv4 connections sort
+// before IPv6 connections.  Any remaining ties are broken by local token,
+// remote token, and inode, in that order.
+//
+// If this option is not set, connections are returned in the order they are
+// reported by the operating system.
+func WithStableOrder() Option {
+	return WithOrder(compareConnections)
+}
+
+// WithStates configures a Checker to report only connections in one of the
+// input states, such as StateEstablished.  This affects each method which
+// lists or checks connections, so that, for example, Check does not report a
+// match for a half-open connection in StateSynRecv.
+//
+// If this option is not set, or no states are passed, connections in every
+// state are reported.
+func WithStates(states ...State) Option {
+	return func(c *Checker) {
+		c.states = states
+	}
+}
+
+// WithCacheTTL configures a Checker to answer Check and FindAll from its most
+// recent snapshot of connections, indexed by remote address, if the snapshot
+// was taken less than ttl ago.  Older snapshots are refreshed by reading the
+// connections table again.  This avoids reading and parsing the table for
+// every check on hosts which check many connections, at the cost of missing
+// connections established since the snapshot was taken.
+//
+// Snapshots may be refreshed on demand using Refresh, or in the background
+// using RefreshEvery.
+//
+// If this option is not set, or ttl is not positive, every check reads the
+// connections table.
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(c *Checker) {
+		c.cacheTTL = ttl
+	}
+}
+
+// WithOrder configures a Checker to sort the connections it returns using
+// cmp, which returns a negative number if a sorts before b, a positive number
+// if a sorts after b, and zero if their order should be preserved.  Prebuilt
+// comparison functions such as ByTriagePriority may be used.
+//
+// WithOrder and WithStableOrder replace one another, so only the last of
+// them passed to NewChecker takes effect.
+func WithOrder(cmp func(a, b Connection) int) Option {
+	return func(c *Checker) {
+		c.order = cmp
+	}
+}
+
+// NewChecker creates a new Checker, configured using the input options.
+func NewChecker(options ...Option) *Checker {
+	c := &Checker{
+		maxReadBytes: DefaultMaxReadBytes,
+		procPath:     os.Getenv(ProcPathEnv),
+		now:          time.Now,
+	}
+
+	for _, o := range options {
+		o(c)
+	}
+
+	return c
+}
+
+// Check detects if there is an active multipath TCP connection to this machine,
+// originating from the input host:port string.  Read errors are reported as
+// configured by WithFailMode.  Its behavior is otherwise identical to the
+// package-level Check function.
+func (c *Checker) Check(hostport string) (bool, error) {
+	// Split input hostport pair
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return false, err
+	}
+
+	// Convert port into a uint16
+	uPort, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return false, err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false, ErrInvalidIPAddress
+	}
+
+	// Share the results of any identical check which is already in progress
+	key := net.JoinHostPort(ip.String(), strconv.FormatUint(uPort, 10))
+	return c.failMode.apply(c.checks.do(key, func() (bool, error) {
+		conns, err := c.FindAll(host, uint16(uPort))
+		if err != nil {
+			return false, err
+		}
+
+		return len(conns) > 0, nil
+	}))
+}
+
+// FindAll returns every active multipath TCP connection to this machine which
+// originates from the input host and port.  Its behavior is otherwise
+// identical to the package-level FindAll function.
+func (c *Checker) FindAll(host string, port uint16) ([]Connection, error) {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, ErrInvalidIPAddress
+	}
+
+	// Answer from memory if a cache is configured and fresh
+	if snap := c.freshSnapshot(); snap != nil {
+		return snap.find(ip, port), nil
+	}
+
+	conns, err := c.ListConnections()
+	if err != nil {
+		return nil, err
+	}
+
+	return findConnections(conns, ip, port), nil
+}
+
+// ListConnections returns all active multipath TCP connections on this host.
+// Its behavior is otherwise identical to the package-level ListConnections
+// function.
+func (c *Checker) ListConnections() ([]Connection, error) {
+	conns, err := c.listSourceConnections()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(c.states) > 0 {
+		// Filtering always creates a new slice, so a Source's slice is
+		// never modified
+		conns = filterStates(conns, c.states)
+	}
+
+	if c.order != nil {
+		// Sort a copy, as a Source may return a slice it continues to use
+		conns = append([]Connection(nil), conns...)
+		sortConnections(conns, c.order)
+	}
+
+	c.storeSnapshot(conns)
+
+	return conns, nil
+}
+
+// listSourceConnections retrieves connections from the operating system, or
+// from the sources configured for the Checker.
+func (c *Checker) listSourceConnections() ([]Connection, error) {
+	if len(c.sources) == 0 {
+		var conns []Connection
+		err := c.inNetNS(func() error {
+			var err error
+			conns, err = c.listConnections()
+			return err
+		})
+
+		return conns, err
+	}
+
+	// Use the first source which succeeds
+	errs := make([]error, 0, len(c.sources))
+	for _, s := range c.sources {
+		conns, err := s.ListConnections()
+		if err == nil {
+			return conns, nil
+		}
+
+		errs = append(errs, err)
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+// inNetNS invokes fn in the network namespace configured for the Checker
+// using WithNetNS, or directly if no network namespace is configured.
+func (c *Checker) inNetNS(fn func() error) error {
+	if !c.hasNetNS {
+		return fn()
+	}
+
+	return runInNetNS(c.netNS, fn)
+}
+
+// openTable opens the raw connections table, applying any read limits
+// configured for the Checker.
+func (c *Checker) openTable() (io.ReadCloser, error) {
+	rc, err := c.openRawTable()
+	if err != nil {
+		return nil, err
+	}
+
+	return &limitedReadCloser{
+		r: newMaxBytesReader(rc, c.maxReadBytes),
+		c: rc,
+	}, nil
+}
+
+// openRawTable opens the connections table at the Checker's configured path,
+// or the operating system's default connections table.  If a network
+// namespace is configured, the default table of the calling thread's network
+// namespace is opened.
+func (c *Checker) openRawTable() (io.ReadCloser, error) {
+	if c.procPath == "" {
+		if c.hasNetNS {
+			return openNetNSTable()
+		}
+
+		return openRawTable()
+	}
+
+	f, err := os.Open(c.procPath)
+	if err != nil {
+		// Avoid returning a non-nil io.ReadCloser containing a nil *os.File
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// limitedReadCloser is an io.ReadCloser which reads from a limited io.Reader,
+// and closes the underlying io.Closer.
+type limitedReadCloser struct {
+	r io.Reader
+	c io.Closer
+}
+
+func (l *limitedReadCloser) Read(b []byte) (int, error) { return l.r.Read(b) }
+func (l *limitedReadCloser) Close() error               { return l.c.Close() }
+
+// maxBytesReader is an io.Reader which returns ErrTableTooLarge if more than
+// a maximum number of bytes are available from its underlying io.Reader.
+type maxBytesReader struct {
+	lr *io.LimitedReader
+}
+
+// newMaxBytesReader creates a new maxBytesReader which permits reading up to
+// n bytes from r.
+func newMaxBytesReader(r io.Reader, n int64) *maxBytesReader {
+	// Permit reading one byte beyond the limit, so that a stream of exactly
+	// n bytes can be distinguished from a larger one.  No stream can exceed
+	// the largest limit, so it is not incremented, which would overflow
+	if n < math.MaxInt64 {
+		n++
+	}
+
+	return &maxBytesReader{
+		lr: &io.LimitedReader{R: r, N: n},
+	}
+}
+
+func (m *maxBytesReader) Read(b []byte) (int, error) {
+	n, err := m.lr.Read(b)
+	if m.lr.N <= 0 {
+		// The extra byte was read, so the limit was exceeded
+		return 0, ErrTableTooLarge
+	}
+
+	return n, err
+}
@@ -0,0 +1,60 @@
+package mptcp
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"testing"
+)
+
+// TestMaxBytesReader verifies that maxBytesReader permits reading streams up
+// to its limit, and returns ErrTableTooLarge for larger streams.
+func TestMaxBytesReader(t *testing.T) {
+	var tests = []struct {
+		size  int
+		limit int64
+		err   error
+	}{
+		// Empty stream
+		{0, 0, nil},
+		{0, 1024, nil},
+		// Stream smaller than limit
+		{1023, 1024, nil},
+		// Stream exactly at limit
+		{1024, 1024, nil},
+		// Stream larger than limit
+		{1025, 1024, ErrTableTooLarge},
+		{1 << 20, 1024, ErrTableTooLarge},
+		{1, 0, ErrTableTooLarge},
+		// Largest limit, which must not overflow
+		{0, math.MaxInt64, nil},
+		{1 << 20, math.MaxInt64, nil},
+	}
+
+	for i, test := range tests {
+		in := bytes.Repeat([]byte{'a'}, test.size)
+
+		out, err := ioutil.ReadAll(newMaxBytesReader(bytes.NewReader(in), test.limit))
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected err: %v != %v [test: %v]", i, err, test.err, test)
+		}
+
+		if err == nil && !bytes.Equal(out, in) {
+			t.Fatalf("[%02d] unexpected output length: %v != %v [test: %v]", i, len(out), len(in), test)
+		}
+	}
+}
+
+// TestNewCheckerDefaults verifies that NewChecker applies default options
+// when none are set.
+func TestNewCheckerDefaults(t *testing.T) {
+	c := NewChecker()
+	if c.maxReadBytes != DefaultMaxReadBytes {
+		t.Fatalf("unexpected default max read bytes: %v != %v", c.maxReadBytes, DefaultMaxReadBytes)
+	}
+
+	c = NewChecker(WithMaxReadBytes(1024))
+	if c.maxReadBytes != 1024 {
+		t.Fatalf("unexpected max read bytes: %v != %v", c.maxReadBytes, 1024)
+	}
+}
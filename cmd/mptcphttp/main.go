@@ -37,10 +37,11 @@ func main() {
 	flag.Parse()
 
 	// Handle connections on root of HTTP server
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		// Check if HTTP request is being issued from a client which is
 		// connected using multipath TCP
-		ok, err := mptcp.Check(r.RemoteAddr)
+		ok, err := mptcp.FromContext(r.Context())
 		if err != nil {
 			log.Println("error:", err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -55,7 +56,15 @@ func main() {
 		}
 	})
 
+	// Check each client connection at most once, when its first request
+	// needs the result, rather than in the server's accept loop
+	srv := &http.Server{
+		Addr:        host,
+		Handler:     mptcp.Handler(mux, mptcp.WithResponseHeader("X-MPTCP")),
+		ConnContext: mptcp.ConnContext,
+	}
+
 	// Bind HTTP server to host
 	log.Println("binding to:", host)
-	http.ListenAndServe(host, nil)
+	log.Fatal(srv.ListenAndServe())
 }
@@ -0,0 +1,168 @@
+package mptcp
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// Connection contains information about an active multipath TCP connection,
+// as reported by the operating system.
+type Connection struct {
+	// LocalToken and RemoteToken are the MPTCP tokens which identify this
+	// connection on the local and remote hosts.
+	LocalToken  uint32
+	RemoteToken uint32
+
+	// IsIPv6 reports whether or not this connection is using IPv6.
+	IsIPv6 bool
+
+	// LocalAddr and RemoteAddr are the local and remote TCP addresses of
+	// this connection.
+	LocalAddr  *net.TCPAddr
+	RemoteAddr *net.TCPAddr
+
+	// State is the TCP state of this connection.
+	State State
+
+	// Subflows is the number of subflows which make up this connection.
+	// It is zero if the number of subflows is not known.
+	Subflows int
+
+	// Inode is the inode of the socket for this connection.
+	Inode uint64
+
+	// TxQueue and RxQueue are the number of bytes queued for transmission
+	// on this connection, and received but not yet read by the application.
+	// They are only reported by connections tables, and are always zero for
+	// connections read using the Linux netlink sock_diag interface.
+	TxQueue uint32
+	RxQueue uint32
+
+	// LocalAddrID and RemoteAddrID are the MPTCP address IDs assigned to
+	// the local and remote addresses of this connection's subflow, as used
+	// by path manager ADD_ADDR and RM_ADDR signaling.  The local address of
+	// the initial subflow always has ID zero.
+	//
+	// Address IDs are only reported by the Linux netlink sock_diag interface,
+	// and only to processes with CAP_NET_ADMIN.  They are only retrieved by
+	// ListConnectionDetails, which needs a second dump of every TCP socket
+	// on the host to do so, and are zero if that dump fails.  They are
+	// always zero for connections returned by Check, ListConnections, and
+	// other lookups.
+	LocalAddrID  uint8
+	RemoteAddrID uint8
+
+	// TimerActive reports whether a retransmit, probe, or keepalive timer
+	// is pending for this connection, and Retransmits is the number of
+	// unrecovered retransmit timeouts of this connection.
+	//
+	// Timer state is only reported by Linux connections tables in the
+	// extended format, which append the timer and retransmit columns of the
+	// kernel's TCP table to each row.  These fields are always zero for
+	// connections read from a table in any other format.
+	TimerActive bool
+	Retransmits uint32
+}
+
+// ID returns a string which identifies this connection, composed of its
+// tokens and its local and remote addresses.  The ID of a connection is
+// stable across reads of the connections table, so it may be used to
+// compare connections from different snapshots.
+func (c Connection) ID() string {
+	return fmt.Sprintf("%08X:%08X/%s/%s", c.LocalToken, c.RemoteToken, c.LocalAddr, c.RemoteAddr)
+}
+
+// IsSinglePath reports whether this connection is using only a single subflow,
+// and is therefore effectively a single-path connection.  An established
+// connection which has lost its additional subflows may indicate a
+// path failure.
+func (c Connection) IsSinglePath() bool {
+	return c.Subflows == 1
+}
+
+// LocalAddrPort returns the local TCP address of this connection as a
+// netip.AddrPort.  The address of an IPv4 connection is always in its 4-byte
+// form.  If the local address is not set, the zero netip.AddrPort is
+// returned.
+func (c Connection) LocalAddrPort() netip.AddrPort {
+	return tcpAddrToAddrPort(c.LocalAddr, c.IsIPv6)
+}
+
+// RemoteAddrPort returns the remote TCP address of this connection as a
+// netip.AddrPort.  The address of an IPv4 connection is always in its 4-byte
+// form.  If the remote address is not set, the zero netip.AddrPort is
+// returned.
+func (c Connection) RemoteAddrPort() netip.AddrPort {
+	return tcpAddrToAddrPort(c.RemoteAddr, c.IsIPv6)
+}
+
+// tcpAddrToAddrPort converts an input TCP address into a netip.AddrPort.
+// Unless isIPv6 is set, IPv4 addresses in their 16-byte form are unmapped.
+func tcpAddrToAddrPort(addr *net.TCPAddr, isIPv6 bool) netip.AddrPort {
+	if addr == nil {
+		return netip.AddrPort{}
+	}
+
+	ip, ok := netip.AddrFromSlice(addr.IP)
+	if !ok {
+		return netip.AddrPort{}
+	}
+	if !isIPv6 {
+		ip = ip.Unmap()
+	}
+
+	return netip.AddrPortFrom(ip, uint16(addr.Port))
+}
+
+// Debug returns a multi-line string which shows each field of this connection
+// in the hex form used by the kernel's connections table alongside its decoded
+// value, for diagnosing encoding and decoding problems.
+//
+// Hex addresses are shown using this host's table byte order.  The format of
+// the string is intended for humans, and may change at any time.
+func (c Connection) Debug() string {
+	var b strings.Builder
+	line := func(name string, raw string, decoded interface{}) {
+		fmt.Fprintf(&b, "%-13s %-38s -> %v\n", name+":", raw, decoded)
+	}
+
+	line("local_token", fmt.Sprintf("%08X", c.LocalToken), c.LocalToken)
+	line("remote_token", fmt.Sprintf("%08X", c.RemoteToken), c.RemoteToken)
+	line("v6", fmt.Sprintf("%d", boolToInt(c.IsIPv6)), c.IsIPv6)
+	line("local_addr", tcpAddrToHex(c.LocalAddr, c.IsIPv6), c.LocalAddr)
+	line("remote_addr", tcpAddrToHex(c.RemoteAddr, c.IsIPv6), c.RemoteAddr)
+	line("st", fmt.Sprintf("%02X", uint8(c.State)), c.State)
+	line("ns", fmt.Sprintf("%02X", c.Subflows), c.Subflows)
+	line("inode", fmt.Sprintf("%d", c.Inode), c.Inode)
+
+	return b.String()
+}
+
+// tcpAddrToHex converts an input TCP address into its equivalent hex
+// host:port form, as it appears in a connections table.
+func tcpAddrToHex(addr *net.TCPAddr, isIPv6 bool) string {
+	if addr == nil {
+		return "<nil>"
+	}
+
+	ip := addr.IP.To4()
+	if isIPv6 || ip == nil {
+		ip = addr.IP.To16()
+	}
+	if ip == nil {
+		return "<invalid>"
+	}
+
+	return strings.ToUpper(ipToHex(ip, tableByteOrder) + ":" + u16PortToHex(uint16(addr.Port)))
+}
+
+// boolToInt converts a boolean into 1 for true, or 0 for false.
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+
+	return 0
+}
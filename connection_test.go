@@ -0,0 +1,168 @@
+package mptcp
+
+import (
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+// TestConnectionID verifies that connections with identical fields have
+// identical IDs, and that connections which differ have different IDs.
+func TestConnectionID(t *testing.T) {
+	// Copy connection with newly allocated addresses
+	a := testSetConnA
+	a.LocalAddr = &net.TCPAddr{IP: net.ParseIP("192.168.1.10"), Port: 80}
+	a.RemoteAddr = &net.TCPAddr{IP: net.ParseIP(ipv4HostOne), Port: 2020}
+
+	if a.ID() != testSetConnA.ID() {
+		t.Fatalf("identical connections have different IDs: %q != %q", a.ID(), testSetConnA.ID())
+	}
+
+	if testSetConnA.ID() == testSetConnB.ID() {
+		t.Fatalf("different connections have identical IDs: %q", testSetConnA.ID())
+	}
+}
+
+// TestConnectionIsSinglePath verifies that IsSinglePath reports connections
+// with exactly one subflow as single-path.
+func TestConnectionIsSinglePath(t *testing.T) {
+	var tests = []struct {
+		subflows int
+		ok       bool
+	}{
+		// Unknown number of subflows
+		{0, false},
+		// Single subflow
+		{1, true},
+		// Multiple subflows
+		{2, false},
+		{8, false},
+	}
+
+	for i, test := range tests {
+		if ok := (Connection{Subflows: test.subflows}).IsSinglePath(); ok != test.ok {
+			t.Fatalf("[%02d] unexpected ok: %v != %v [test: %v]", i, ok, test.ok, test)
+		}
+	}
+}
+
+// TestConnectionAddrPort verifies that LocalAddrPort and RemoteAddrPort
+// convert a connection's addresses into their netip.AddrPort forms.
+func TestConnectionAddrPort(t *testing.T) {
+	var tests = []struct {
+		desc   string
+		c      Connection
+		local  string
+		remote string
+	}{
+		{
+			desc: "no addresses",
+		},
+		{
+			desc: "IPv4, 4-byte form",
+			c: Connection{
+				LocalAddr:  &net.TCPAddr{IP: net.IPv4(104, 131, 14, 231).To4(), Port: 22},
+				RemoteAddr: &net.TCPAddr{IP: net.IPv4(24, 176, 52, 17).To4(), Port: 48104},
+			},
+			local:  "104.131.14.231:22",
+			remote: "24.176.52.17:48104",
+		},
+		{
+			desc: "IPv4, 16-byte form",
+			c: Connection{
+				LocalAddr:  &net.TCPAddr{IP: net.ParseIP("104.131.14.231"), Port: 22},
+				RemoteAddr: &net.TCPAddr{IP: net.ParseIP("24.176.52.17"), Port: 48104},
+			},
+			local:  "104.131.14.231:22",
+			remote: "24.176.52.17:48104",
+		},
+		{
+			desc: "IPv6",
+			c: Connection{
+				IsIPv6:     true,
+				LocalAddr:  &net.TCPAddr{IP: net.ParseIP("2604:a880:800:10::74:c001"), Port: 8080},
+				RemoteAddr: &net.TCPAddr{IP: net.ParseIP("2604:a880:800:10::289:2001"), Port: 37797},
+			},
+			local:  "[2604:a880:800:10::74:c001]:8080",
+			remote: "[2604:a880:800:10::289:2001]:37797",
+		},
+		{
+			desc: "IPv6, IPv4-mapped",
+			c: Connection{
+				IsIPv6:     true,
+				LocalAddr:  &net.TCPAddr{IP: net.ParseIP("::ffff:104.131.14.231"), Port: 22},
+				RemoteAddr: &net.TCPAddr{IP: net.ParseIP("::ffff:24.176.52.17"), Port: 48104},
+			},
+			local:  "[::ffff:104.131.14.231]:22",
+			remote: "[::ffff:24.176.52.17]:48104",
+		},
+		{
+			desc: "invalid address",
+			c: Connection{
+				LocalAddr:  &net.TCPAddr{IP: net.IP{1, 2, 3}, Port: 22},
+				RemoteAddr: &net.TCPAddr{IP: nil, Port: 48104},
+			},
+		},
+	}
+
+	for i, test := range tests {
+		var local, remote netip.AddrPort
+		if test.local != "" {
+			local = netip.MustParseAddrPort(test.local)
+		}
+		if test.remote != "" {
+			remote = netip.MustParseAddrPort(test.remote)
+		}
+
+		if got := test.c.LocalAddrPort(); got != local {
+			t.Fatalf("[%02d] unexpected local address: %v != %v [test: %v]", i, got, local, test.desc)
+		}
+
+		if got := test.c.RemoteAddrPort(); got != remote {
+			t.Fatalf("[%02d] unexpected remote address: %v != %v [test: %v]", i, got, remote, test.desc)
+		}
+	}
+}
+
+// TestConnectionDebug verifies that Connection.Debug shows both the hex and
+// decoded forms of each field.
+func TestConnectionDebug(t *testing.T) {
+	orig := tableByteOrder
+	defer func() { tableByteOrder = orig }()
+	tableByteOrder = binary.LittleEndian
+
+	c := Connection{
+		LocalToken:  0x9C290BF6,
+		RemoteToken: 0x4CC0A727,
+		LocalAddr:   &net.TCPAddr{IP: net.IPv4(104, 131, 14, 231), Port: 22},
+		RemoteAddr:  &net.TCPAddr{IP: net.IPv4(24, 176, 52, 17), Port: 48104},
+		State:       StateEstablished,
+		Subflows:    1,
+		Inode:       15666,
+	}
+
+	debug := c.Debug()
+
+	// Each field must be shown in hex form, and decoded form
+	for i, want := range [][2]string{
+		{"9C290BF6", "2619935734"},
+		{"4CC0A727", "1287694119"},
+		{"E70E8368:0016", "104.131.14.231:22"},
+		{"1134B018:BBE8", "24.176.52.17:48104"},
+		{"01", "ESTABLISHED"},
+		{"15666", "15666"},
+	} {
+		if !strings.Contains(debug, want[0]) || !strings.Contains(debug, want[1]) {
+			t.Fatalf("[%02d] debug string missing %q or %q:\n%s", i, want[0], want[1], debug)
+		}
+	}
+
+	// IPv6 addresses are shown in their full hex form
+	c.IsIPv6 = true
+	c.RemoteAddr = &net.TCPAddr{IP: net.ParseIP("2604:a880:800:10::289:2001"), Port: 37797}
+	if want := "80A80426100000080000000001208902:93A5"; !strings.Contains(c.Debug(), want) {
+		t.Fatalf("debug string missing %q:\n%s", want, c.Debug())
+	}
+}
@@ -0,0 +1,35 @@
+// +build !linux,!darwin
+
+package mptcp
+
+import "context"
+
+// checkMPTCP is not currently implemented on platforms other than Linux and
+// Darwin.
+var checkMPTCP = func(ctx context.Context, host string, port uint16) (bool, error) {
+	return false, ErrNotImplemented
+}
+
+// checkLocalMPTCP is not currently implemented on platforms other than Linux
+// and Darwin.
+var checkLocalMPTCP = func(host string, port uint16) (bool, error) {
+	return false, ErrNotImplemented
+}
+
+// listConnections is not currently implemented on platforms other than Linux
+// and Darwin.
+var listConnections = func() ([]Connection, error) {
+	return nil, ErrNotImplemented
+}
+
+// listConnections is not currently implemented on platforms other than Linux
+// and Darwin.
+func (c *Checker) listConnections() ([]Connection, error) {
+	return nil, ErrNotImplemented
+}
+
+// forEachConnection is not currently implemented on platforms other than
+// Linux and Darwin.
+var forEachConnection = func(fn func(c Connection) bool) error {
+	return ErrNotImplemented
+}
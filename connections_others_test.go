@@ -0,0 +1,44 @@
+// +build !linux,!darwin
+
+package mptcp
+
+import (
+	"context"
+	"testing"
+)
+
+// TestOthers_checkMPTCP verifies that checkMPTCP is not implemented on
+// platforms other than Linux and Darwin.
+func TestOthers_checkMPTCP(t *testing.T) {
+	ok, err := checkMPTCP(context.Background(), "localhost", 8080)
+	if ok || err != ErrNotImplemented {
+		t.Fatalf("checkMPTCP is not implemented, but returned: (%v, %v)", ok, err)
+	}
+}
+
+// TestOthers_listConnections verifies that listConnections is not implemented
+// on platforms other than Linux and Darwin.
+func TestOthers_listConnections(t *testing.T) {
+	conns, err := listConnections()
+	if conns != nil || err != ErrNotImplemented {
+		t.Fatalf("listConnections is not implemented, but returned: (%v, %v)", conns, err)
+	}
+}
+
+// TestOthers_Connections verifies that Connections is not implemented on
+// platforms other than Linux and Darwin.
+func TestOthers_Connections(t *testing.T) {
+	conns, err := Connections()
+	if conns != nil || err != ErrNotImplemented {
+		t.Fatalf("Connections is not implemented, but returned: (%v, %v)", conns, err)
+	}
+}
+
+// TestOthers_CheckLocal verifies that CheckLocal is not implemented on
+// platforms other than Linux and Darwin.
+func TestOthers_CheckLocal(t *testing.T) {
+	ok, err := CheckLocal("127.0.0.1", 8080)
+	if ok || err != ErrNotImplemented {
+		t.Fatalf("CheckLocal is not implemented, but returned: (%v, %v)", ok, err)
+	}
+}
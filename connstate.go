@@ -0,0 +1,87 @@
+package mptcp
+
+import (
+	"net"
+	"net/http"
+	"sync"
+)
+
+// ConnStats contains statistics about multipath TCP usage by the connections
+// accepted by an HTTP server, as recorded by NewConnStateTracker.
+type ConnStats struct {
+	// Total is the number of connections which were checked.
+	Total int
+
+	// MPTCP is the number of checked connections which were using
+	// multipath TCP.
+	MPTCP int
+
+	// Errors is the number of connections which could not be checked.
+	// They are not counted in Total.
+	Errors int
+}
+
+// NewConnStateTracker returns a callback for use as the ConnState field of an
+// http.Server, and a function which returns the statistics it has accumulated,
+// giving a server built-in metrics for multipath TCP adoption.
+//
+// Each connection is checked using CheckConn once, when it first transitions
+// to http.StateActive.  For HTTPS servers, this transition occurs after the
+// TLS handshake is complete, and the remote address of the underlying TCP
+// connection is checked.  Connections which are reused for later requests are
+// not checked again.
+//
+// The callback and the statistics function are safe for concurrent use.  A
+// separate tracker should be created for each server.
+func NewConnStateTracker() (func(net.Conn, http.ConnState), func() ConnStats) {
+	var (
+		mu      sync.Mutex
+		stats   ConnStats
+		checked = make(map[net.Conn]struct{})
+	)
+
+	callback := func(nc net.Conn, state http.ConnState) {
+		switch state {
+		case http.StateActive:
+			mu.Lock()
+			_, ok := checked[nc]
+			checked[nc] = struct{}{}
+			mu.Unlock()
+
+			if ok {
+				return
+			}
+
+			// Check outside the lock, so slow checks do not block other
+			// connections
+			isMPTCP, err := CheckConn(nc)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			switch {
+			case err != nil:
+				stats.Errors++
+			case isMPTCP:
+				stats.Total++
+				stats.MPTCP++
+			default:
+				stats.Total++
+			}
+		case http.StateClosed, http.StateHijacked:
+			// The connection will not become active again
+			mu.Lock()
+			delete(checked, nc)
+			mu.Unlock()
+		}
+	}
+
+	statsFn := func() ConnStats {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return stats
+	}
+
+	return callback, statsFn
+}
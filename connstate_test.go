@@ -0,0 +1,115 @@
+package mptcp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// mockConn is a net.Conn with a fixed remote address.
+type mockConn struct {
+	net.Conn
+	remote net.Addr
+}
+
+// RemoteAddr returns the remote address of the mockConn.
+func (c *mockConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// TestNewConnStateTracker verifies that a ConnState callback returned by
+// NewConnStateTracker checks each connection once when it becomes active, and
+// accumulates statistics about the results.
+func TestNewConnStateTracker(t *testing.T) {
+	// Remote hosts which are using multipath TCP, or which cannot be checked
+	var (
+		mptcpHost = "192.0.2.1"
+		errorHost = "192.0.2.2"
+		tcpHost   = "192.0.2.3"
+	)
+
+	var mu sync.Mutex
+	checks := make(map[string]int)
+
+	origCheck := checkMPTCP
+	defer func() { checkMPTCP = origCheck }()
+	checkMPTCP = func(ctx context.Context, host string, port uint16) (bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		checks[host]++
+
+		switch host {
+		case mptcpHost:
+			return true, nil
+		case errorHost:
+			return false, errors.New("lookup failed")
+		default:
+			return false, nil
+		}
+	}
+
+	conn := func(host string, port int) net.Conn {
+		return &mockConn{remote: &net.TCPAddr{IP: net.ParseIP(host), Port: port}}
+	}
+
+	var (
+		mptcp1 = conn(mptcpHost, 1000)
+		mptcp2 = conn(mptcpHost, 1001)
+		tcp    = conn(tcpHost, 1000)
+		failed = conn(errorHost, 1000)
+	)
+
+	callback, stats := NewConnStateTracker()
+
+	// Drive each connection through typical state transitions, including
+	// keep-alive connections which become active more than once
+	var transitions = []struct {
+		c     net.Conn
+		state http.ConnState
+	}{
+		{mptcp1, http.StateNew},
+		{mptcp1, http.StateActive},
+		{mptcp1, http.StateIdle},
+		{mptcp1, http.StateActive},
+		{tcp, http.StateNew},
+		{tcp, http.StateActive},
+		{failed, http.StateNew},
+		{failed, http.StateActive},
+		{failed, http.StateClosed},
+		{mptcp2, http.StateNew},
+		{mptcp2, http.StateActive},
+		{mptcp2, http.StateHijacked},
+		{mptcp1, http.StateClosed},
+		{tcp, http.StateIdle},
+		{tcp, http.StateActive},
+	}
+
+	var wg sync.WaitGroup
+	for _, tr := range transitions {
+		callback(tr.c, tr.state)
+
+		// Statistics may be read while connections are being checked
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = stats()
+		}()
+	}
+	wg.Wait()
+
+	want := ConnStats{Total: 3, MPTCP: 2, Errors: 1}
+	if got := stats(); got != want {
+		t.Fatalf("unexpected stats: %+v != %+v", got, want)
+	}
+
+	// Each connection is only checked when it first becomes active
+	wantChecks := map[string]int{mptcpHost: 2, tcpHost: 1, errorHost: 1}
+	for host, n := range wantChecks {
+		if checks[host] != n {
+			t.Fatalf("unexpected number of checks for %s: %d != %d", host, checks[host], n)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package mptcp
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+)
+
+// csvHeader is the header row written by WriteCSV.  Its column order is
+// stable, and is documented by WriteCSV.
+var csvHeader = []string{
+	"local_token",
+	"remote_token",
+	"ipv6",
+	"local_ip",
+	"local_port",
+	"remote_ip",
+	"remote_port",
+}
+
+// WriteCSV writes the input connections to w in CSV format, for analysis
+// with spreadsheets and similar tools.
+//
+// A header row is written first, followed by one row per connection.  The
+// columns are always written in the following order:
+//   - local_token: the local MPTCP token, in hex
+//   - remote_token: the remote MPTCP token, in hex
+//   - ipv6: "true" if the connection uses IPv6, or "false" otherwise
+//   - local_ip: the local IP address
+//   - local_port: the local port
+//   - remote_ip: the remote IP address
+//   - remote_port: the remote port
+//
+// The IP address and port fields of an address which is not set are empty.
+//
+// Fields are quoted as described in RFC 4180, so IPv6 addresses are written
+// verbatim, without enclosing brackets.
+func WriteCSV(w io.Writer, conns []Connection) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, c := range conns {
+		if err := cw.Write(csvRecord(c)); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvRecord converts a Connection into a CSV record, using the column
+// order of csvHeader.
+func csvRecord(c Connection) []string {
+	localIP, localPort := csvAddr(c.LocalAddr)
+	remoteIP, remotePort := csvAddr(c.RemoteAddr)
+
+	return []string{
+		fmt.Sprintf("%08X", c.LocalToken),
+		fmt.Sprintf("%08X", c.RemoteToken),
+		strconv.FormatBool(c.IsIPv6),
+		localIP,
+		localPort,
+		remoteIP,
+		remotePort,
+	}
+}
+
+// csvAddr returns the IP address and port fields of an input TCP address.
+// Both are empty if the address is not set, and the IP address field is
+// empty if the address has no IP address.
+func csvAddr(addr *net.TCPAddr) (string, string) {
+	if addr == nil {
+		return "", ""
+	}
+
+	var ip string
+	if addr.IP != nil {
+		ip = addr.IP.String()
+	}
+
+	return ip, strconv.Itoa(addr.Port)
+}
@@ -0,0 +1,41 @@
+package mptcp
+
+// ConnectionDetail contains detailed information about an active multipath
+// TCP connection, which is only available from some operating system
+// interfaces.
+//
+// On Linux, detailed information is retrieved using the netlink sock_diag
+// interface of the mainline kernel's MPTCP implementation.  The /proc/net/mptcp
+// connections table of the out-of-tree kernel does not expose these fields.
+type ConnectionDetail struct {
+	// Connection contains the basic information about this connection.
+	// The netlink sock_diag interface only reports the local token, so
+	// RemoteToken is always zero.
+	Connection
+
+	// WriteSeq is the data sequence number (DSN) which will be assigned to
+	// the next byte of data written to this connection.
+	WriteSeq uint64
+
+	// SndUna is the data sequence number of the oldest data sent on this
+	// connection which has not been acknowledged at the data level.
+	SndUna uint64
+
+	// RcvNxt is the data sequence number of the next byte of data expected
+	// to be received on this connection.
+	RcvNxt uint64
+
+	// BytesAcked is the number of bytes of data sent on this connection
+	// which have been acknowledged at the data level.  It is zero on kernels
+	// which do not report it.
+	BytesAcked uint64
+}
+
+// ListConnectionDetails returns detailed information about all active
+// multipath TCP connections on this host.
+//
+// If detailed connection information is not available on the current operating
+// system, this function will return ErrNotImplemented.
+func ListConnectionDetails() ([]ConnectionDetail, error) {
+	return listConnectionDetails()
+}
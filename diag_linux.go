@@ -0,0 +1,406 @@
+// +build linux
+
+package mptcp
+
+import (
+	"encoding/binary"
+	"net"
+	"syscall"
+)
+
+const (
+	// netlinkSockDiag is the netlink family used for socket monitoring.
+	netlinkSockDiag = 4
+
+	// sockDiagByFamily is the netlink message type for sock_diag requests
+	// and replies.
+	sockDiagByFamily = 20
+
+	// inetDiagInfo is the sock_diag reply attribute which contains
+	// protocol-specific information, and inetDiagReqProtocol is the request
+	// attribute used to select protocols which do not fit in a byte.
+	inetDiagInfo        = 2
+	inetDiagReqProtocol = 3
+
+	// inetDiagULPInfo is the sock_diag reply attribute which contains
+	// information about the upper layer protocol of a TCP socket, such as
+	// a MPTCP subflow.  It is only reported alongside inetDiagInfo.
+	inetDiagULPInfo = 19
+
+	// inetULPInfo* are the attributes nested within inetDiagULPInfo.
+	inetULPInfoName  = 1
+	inetULPInfoMPTCP = 3
+
+	// mptcpSubflowAttr* are the attributes nested within inetULPInfoMPTCP.
+	mptcpSubflowAttrTokenLoc = 2
+	mptcpSubflowAttrFlags    = 8
+	mptcpSubflowAttrIDRem    = 9
+	mptcpSubflowAttrIDLoc    = 10
+
+	// mptcpSubflowFlagBackup* are the flags of mptcpSubflowAttrFlags which
+	// indicate that the remote or local host marked a subflow as a backup.
+	mptcpSubflowFlagBackupRem = 1 << 4
+	mptcpSubflowFlagBackupLoc = 1 << 5
+
+	// tcpListen is the TCP state of a listening socket.
+	tcpListen = 10
+
+	// ipprotoMPTCP is the IP protocol number used by Linux for MPTCP sockets.
+	ipprotoMPTCP = 262
+
+	// inetDiagReqV2Len and inetDiagMsgLen are the lengths of the sock_diag
+	// request and reply structures.
+	inetDiagReqV2Len = 56
+	inetDiagMsgLen   = 72
+
+	// mptcpInfo*Offset are the offsets of fields within the kernel's
+	// struct mptcp_info, reported in the inetDiagInfo attribute.
+	mptcpInfoSubflowsOffset      = 0
+	mptcpInfoTokenOffset         = 12
+	mptcpInfoWriteSeqOffset      = 16
+	mptcpInfoSndUnaOffset        = 24
+	mptcpInfoRcvNxtOffset        = 32
+	mptcpInfoBytesAckedOffset    = 72
+	mptcpInfoSubflowsTotalOffset = 80
+)
+
+// listConnectionDetails uses the Linux netlink sock_diag interface to retrieve
+// detailed information about all active MPTCP connections, including their
+// address IDs.
+//
+// Address IDs are only reported for each subflow, so each connection is
+// matched to the subflow which shares its token and addresses.  This requires
+// a second dump of every TCP socket on the host, so connections retrieved
+// for checks use listDiagConnections instead.  If the subflows cannot be
+// retrieved, the address IDs are left zero.
+var listConnectionDetails = func() ([]ConnectionDetail, error) {
+	details, err := listDiagConnections()
+	if err != nil {
+		return nil, err
+	}
+
+	subflows, err := listDiagSubflows()
+	if err != nil {
+		return details, nil
+	}
+
+	for i := range details {
+		for _, sf := range subflows {
+			if sf.matches(details[i].Connection) {
+				details[i].LocalAddrID = sf.LocalAddrID
+				details[i].RemoteAddrID = sf.RemoteAddrID
+				break
+			}
+		}
+	}
+
+	return details, nil
+}
+
+// listDiagConnections uses the Linux netlink sock_diag interface to retrieve
+// detailed information about all active MPTCP connections, without their
+// address IDs.
+var listDiagConnections = func() ([]ConnectionDetail, error) {
+	c, err := dialNetlink(netlinkSockDiag)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	// Dump MPTCP sockets of each address family
+	var details []ConnectionDetail
+	for _, family := range []uint8{syscall.AF_INET, syscall.AF_INET6} {
+		msgs, err := c.execute(sockDiagByFamily, syscall.NLM_F_DUMP, mptcpDiagRequest(family))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range msgs {
+			d, err := parseMPTCPDiagMessage(m.Data)
+			if err != nil {
+				return nil, err
+			}
+
+			details = append(details, d)
+		}
+	}
+
+	return details, nil
+}
+
+// listAllSubflows uses the Linux netlink sock_diag interface to retrieve the
+// TCP subflows of all active MPTCP connections.
+var listAllSubflows = func() ([]Subflow, error) {
+	subflows, err := listDiagSubflows()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Subflow, 0, len(subflows))
+	for _, sf := range subflows {
+		out = append(out, sf.subflow())
+	}
+
+	return out, nil
+}
+
+// listDiagSubflows uses the Linux netlink sock_diag interface to retrieve the
+// TCP subflows of all active MPTCP connections, in their sock_diag form.
+var listDiagSubflows = func() ([]mptcpSubflow, error) {
+	c, err := dialNetlink(netlinkSockDiag)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	return listSubflows(c)
+}
+
+// A mptcpSubflow is a TCP subflow of a MPTCP connection, as reported by the
+// sock_diag interface.
+type mptcpSubflow struct {
+	LocalToken   uint32
+	LocalAddrID  uint8
+	RemoteAddrID uint8
+	LocalAddr    *net.TCPAddr
+	RemoteAddr   *net.TCPAddr
+	State        State
+	Flags        uint32
+}
+
+// subflow converts the subflow into its exported form.
+func (sf mptcpSubflow) subflow() Subflow {
+	return Subflow{
+		LocalToken:   sf.LocalToken,
+		LocalAddr:    sf.LocalAddr,
+		RemoteAddr:   sf.RemoteAddr,
+		State:        sf.State,
+		LocalAddrID:  sf.LocalAddrID,
+		RemoteAddrID: sf.RemoteAddrID,
+		Backup:       sf.Flags&(mptcpSubflowFlagBackupRem|mptcpSubflowFlagBackupLoc) != 0,
+	}
+}
+
+// matches reports whether the subflow belongs to the input connection, and
+// uses the same local and remote addresses.
+func (sf mptcpSubflow) matches(c Connection) bool {
+	return sf.LocalToken == c.LocalToken &&
+		sf.LocalAddr.IP.Equal(c.LocalAddr.IP) && sf.LocalAddr.Port == c.LocalAddr.Port &&
+		sf.RemoteAddr.IP.Equal(c.RemoteAddr.IP) && sf.RemoteAddr.Port == c.RemoteAddr.Port
+}
+
+// listSubflows uses the input sock_diag netlink socket to retrieve the TCP
+// subflows of all MPTCP connections.
+func listSubflows(c *netlinkConn) ([]mptcpSubflow, error) {
+	var subflows []mptcpSubflow
+	for _, family := range []uint8{syscall.AF_INET, syscall.AF_INET6} {
+		msgs, err := c.execute(sockDiagByFamily, syscall.NLM_F_DUMP, subflowDiagRequest(family))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range msgs {
+			sf, ok, err := parseSubflowDiagMessage(m.Data)
+			if err != nil {
+				return nil, err
+			}
+
+			if ok {
+				subflows = append(subflows, sf)
+			}
+		}
+	}
+
+	return subflows, nil
+}
+
+// subflowDiagRequest creates a sock_diag request which dumps all TCP sockets
+// of the input address family, including upper layer protocol information.
+func subflowDiagRequest(family uint8) []byte {
+	b := make([]byte, inetDiagReqV2Len)
+	b[0] = family
+	b[1] = syscall.IPPROTO_TCP
+
+	// Upper layer protocol information is only reported along with
+	// protocol-specific information
+	b[2] = 1 << (inetDiagInfo - 1)
+
+	// Request sockets in all states except listening, which are not
+	// subflows
+	binary.NativeEndian.PutUint32(b[4:8], 0xffffffff&^(1<<tcpListen))
+
+	return b
+}
+
+// parseSubflowDiagMessage parses a mptcpSubflow from the data of a sock_diag
+// reply message for a TCP socket.  If the socket is not a MPTCP subflow, or
+// its upper layer protocol information is not reported, ok is false.
+func parseSubflowDiagMessage(b []byte) (sf mptcpSubflow, ok bool, err error) {
+	d, err := parseMPTCPDiagMessage(b)
+	if err != nil {
+		return mptcpSubflow{}, false, err
+	}
+
+	attrs, err := parseNetlinkAttributes(b[inetDiagMsgLen:])
+	if err != nil {
+		return mptcpSubflow{}, false, err
+	}
+
+	for _, a := range attrs {
+		if a.Type != inetDiagULPInfo {
+			continue
+		}
+
+		sf, ok, err = parseMPTCPULPInfo(a.Data)
+		if err != nil || !ok {
+			return mptcpSubflow{}, false, err
+		}
+
+		sf.LocalAddr = d.LocalAddr
+		sf.RemoteAddr = d.RemoteAddr
+		sf.State = d.State
+		return sf, true, nil
+	}
+
+	return mptcpSubflow{}, false, nil
+}
+
+// parseMPTCPULPInfo parses MPTCP subflow information from the data of a
+// inetDiagULPInfo attribute.  If the upper layer protocol is not MPTCP, ok
+// is false.
+func parseMPTCPULPInfo(b []byte) (sf mptcpSubflow, ok bool, err error) {
+	attrs, err := parseNetlinkAttributes(b)
+	if err != nil {
+		return mptcpSubflow{}, false, err
+	}
+
+	for _, a := range attrs {
+		if a.Type != inetULPInfoMPTCP {
+			continue
+		}
+
+		sfAttrs, err := parseNetlinkAttributes(a.Data)
+		if err != nil {
+			return mptcpSubflow{}, false, err
+		}
+
+		for _, sa := range sfAttrs {
+			switch {
+			case sa.Type == mptcpSubflowAttrTokenLoc && len(sa.Data) == 4:
+				sf.LocalToken = binary.NativeEndian.Uint32(sa.Data)
+			case sa.Type == mptcpSubflowAttrFlags && len(sa.Data) == 4:
+				sf.Flags = binary.NativeEndian.Uint32(sa.Data)
+			case sa.Type == mptcpSubflowAttrIDLoc && len(sa.Data) == 1:
+				sf.LocalAddrID = sa.Data[0]
+			case sa.Type == mptcpSubflowAttrIDRem && len(sa.Data) == 1:
+				sf.RemoteAddrID = sa.Data[0]
+			}
+		}
+
+		return sf, true, nil
+	}
+
+	return mptcpSubflow{}, false, nil
+}
+
+// mptcpDiagRequest creates a sock_diag request which dumps all MPTCP sockets
+// of the input address family, including MPTCP-specific information.
+func mptcpDiagRequest(family uint8) []byte {
+	b := make([]byte, inetDiagReqV2Len)
+	b[0] = family
+	b[1] = syscall.IPPROTO_TCP
+	b[2] = 1 << (inetDiagInfo - 1)
+
+	// Request sockets in all states except listening, which are not
+	// connections
+	binary.NativeEndian.PutUint32(b[4:8], 0xffffffff&^(1<<tcpListen))
+
+	// MPTCP does not fit in the one byte protocol field, so it must be
+	// selected using an attribute
+	protocol := make([]byte, 4)
+	binary.NativeEndian.PutUint32(protocol, ipprotoMPTCP)
+
+	return append(b, marshalNetlinkAttributes([]netlinkAttribute{{
+		Type: inetDiagReqProtocol,
+		Data: protocol,
+	}})...)
+}
+
+// parseMPTCPDiagMessage parses a ConnectionDetail from the data of a sock_diag
+// reply message for a MPTCP socket.
+func parseMPTCPDiagMessage(b []byte) (ConnectionDetail, error) {
+	if len(b) < inetDiagMsgLen {
+		return ConnectionDetail{}, errInvalidNetlinkMessage
+	}
+
+	d := ConnectionDetail{
+		Connection: Connection{
+			IsIPv6: b[0] == syscall.AF_INET6,
+			State:  State(b[1]),
+		},
+	}
+
+	// Ports and addresses are always in network byte order
+	ipLen := net.IPv4len
+	if d.IsIPv6 {
+		ipLen = net.IPv6len
+	}
+
+	d.LocalAddr = &net.TCPAddr{
+		IP:   net.IP(append([]byte(nil), b[8:8+ipLen]...)),
+		Port: int(binary.BigEndian.Uint16(b[4:6])),
+	}
+	d.RemoteAddr = &net.TCPAddr{
+		IP:   net.IP(append([]byte(nil), b[24:24+ipLen]...)),
+		Port: int(binary.BigEndian.Uint16(b[6:8])),
+	}
+
+	attrs, err := parseNetlinkAttributes(b[inetDiagMsgLen:])
+	if err != nil {
+		return ConnectionDetail{}, err
+	}
+
+	for _, a := range attrs {
+		if a.Type == inetDiagInfo {
+			parseMPTCPInfo(a.Data, &d)
+		}
+	}
+
+	return d, nil
+}
+
+// parseMPTCPInfo parses fields from the kernel's struct mptcp_info into a
+// ConnectionDetail.  Older kernels report a shorter structure, so fields
+// which are not present are left unset.
+func parseMPTCPInfo(b []byte, d *ConnectionDetail) {
+	u32 := func(off int) uint32 {
+		if len(b) < off+4 {
+			return 0
+		}
+
+		return binary.NativeEndian.Uint32(b[off : off+4])
+	}
+
+	u64 := func(off int) uint64 {
+		if len(b) < off+8 {
+			return 0
+		}
+
+		return binary.NativeEndian.Uint64(b[off : off+8])
+	}
+
+	// Newer kernels report the total number of subflows, while older
+	// kernels only report the number of subflows beyond the first
+	switch {
+	case len(b) > mptcpInfoSubflowsTotalOffset:
+		d.Subflows = int(b[mptcpInfoSubflowsTotalOffset])
+	case len(b) > mptcpInfoSubflowsOffset:
+		d.Subflows = int(b[mptcpInfoSubflowsOffset]) + 1
+	}
+
+	d.LocalToken = u32(mptcpInfoTokenOffset)
+	d.WriteSeq = u64(mptcpInfoWriteSeqOffset)
+	d.SndUna = u64(mptcpInfoSndUnaOffset)
+	d.RcvNxt = u64(mptcpInfoRcvNxtOffset)
+	d.BytesAcked = u64(mptcpInfoBytesAckedOffset)
+}
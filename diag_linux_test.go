@@ -0,0 +1,373 @@
+// +build linux
+
+package mptcp
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"net"
+	"reflect"
+	"syscall"
+	"testing"
+)
+
+// TestLinux_parseMPTCPDiagMessage verifies that parseMPTCPDiagMessage decodes
+// addresses and data-level sequence information from sock_diag replies.
+func TestLinux_parseMPTCPDiagMessage(t *testing.T) {
+	ipv4Local := &net.TCPAddr{IP: net.IPv4(104, 131, 14, 231).To4(), Port: 22}
+	ipv4Remote := &net.TCPAddr{IP: net.IPv4(24, 176, 52, 17).To4(), Port: 48104}
+	ipv6Local := &net.TCPAddr{IP: net.ParseIP("2604:a880:800:10::74:c001"), Port: 8080}
+	ipv6Remote := &net.TCPAddr{IP: net.ParseIP("2604:a880:800:10::289:2001"), Port: 37797}
+
+	var tests = []struct {
+		b      []byte
+		detail ConnectionDetail
+		err    error
+	}{
+		// Truncated message
+		{make([]byte, inetDiagMsgLen-1), ConnectionDetail{}, errInvalidNetlinkMessage},
+		// IPv4 socket with full MPTCP information
+		{
+			testMPTCPDiagMessage(syscall.AF_INET, ipv4Local, ipv4Remote, testMPTCPInfo(0x9C290BF6, 1000, 900, 5000, 800, 88)),
+			ConnectionDetail{
+				Connection: Connection{LocalToken: 0x9C290BF6, LocalAddr: ipv4Local, RemoteAddr: ipv4Remote, State: StateEstablished, Subflows: 2},
+				WriteSeq:   1000, SndUna: 900, RcvNxt: 5000, BytesAcked: 800,
+			},
+			nil,
+		},
+		// IPv6 socket with full MPTCP information
+		{
+			testMPTCPDiagMessage(syscall.AF_INET6, ipv6Local, ipv6Remote, testMPTCPInfo(0xF6635734, 1<<40, 1<<40-10, 1<<33, 1<<39, 88)),
+			ConnectionDetail{
+				Connection: Connection{LocalToken: 0xF6635734, IsIPv6: true, LocalAddr: ipv6Local, RemoteAddr: ipv6Remote, State: StateEstablished, Subflows: 2},
+				WriteSeq:   1 << 40, SndUna: 1<<40 - 10, RcvNxt: 1 << 33, BytesAcked: 1 << 39,
+			},
+			nil,
+		},
+		// IPv4 socket with short MPTCP information from an older kernel
+		{
+			testMPTCPDiagMessage(syscall.AF_INET, ipv4Local, ipv4Remote, testMPTCPInfo(0x9C290BF6, 1000, 900, 5000, 800, 40)),
+			ConnectionDetail{
+				Connection: Connection{LocalToken: 0x9C290BF6, LocalAddr: ipv4Local, RemoteAddr: ipv4Remote, State: StateEstablished, Subflows: 2},
+				WriteSeq:   1000, SndUna: 900, RcvNxt: 5000,
+			},
+			nil,
+		},
+		// IPv4 socket without MPTCP information
+		{
+			testMPTCPDiagMessage(syscall.AF_INET, ipv4Local, ipv4Remote, nil),
+			ConnectionDetail{
+				Connection: Connection{LocalAddr: ipv4Local, RemoteAddr: ipv4Remote, State: StateEstablished},
+			},
+			nil,
+		},
+	}
+
+	for i, test := range tests {
+		detail, err := parseMPTCPDiagMessage(test.b)
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected err: %v != %v", i, err, test.err)
+		}
+
+		if !reflect.DeepEqual(detail, test.detail) {
+			t.Fatalf("[%02d] unexpected detail: %+v != %+v", i, detail, test.detail)
+		}
+	}
+}
+
+// TestLinux_parseSubflowDiagMessage verifies that parseSubflowDiagMessage
+// decodes the token and address IDs of a MPTCP subflow from a recorded
+// sock_diag reply.
+func TestLinux_parseSubflowDiagMessage(t *testing.T) {
+	local := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1).To4(), Port: 43507}
+	remote := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1).To4(), Port: 45500}
+
+	// Upper layer protocol information recorded from a loopback subflow on
+	// Linux 6.18, with its address IDs changed from zero to distinguish them
+	ulpInfo, err := hex.DecodeString("0a0001006d7074637000000058000300080001000000000008000200de67a3f108000800c1000000050009000200000005000a000100000008000300010000000c0004003acf152eeebb68c208000500010000000800060090a724be0600070001000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Upper layer protocol information for a socket which is not a subflow
+	tlsInfo := marshalNetlinkAttributes([]netlinkAttribute{{
+		Type: inetULPInfoName,
+		Data: []byte("tls\x00"),
+	}})
+
+	withAttr := func(typ uint16, data []byte) []byte {
+		return append(testMPTCPDiagMessage(syscall.AF_INET, local, remote, nil), marshalNetlinkAttributes([]netlinkAttribute{{
+			Type: typ,
+			Data: data,
+		}})...)
+	}
+
+	var tests = []struct {
+		desc string
+		b    []byte
+		sf   mptcpSubflow
+		ok   bool
+		err  error
+	}{
+		{"truncated message", make([]byte, inetDiagMsgLen-1), mptcpSubflow{}, false, errInvalidNetlinkMessage},
+		{"no upper layer protocol", testMPTCPDiagMessage(syscall.AF_INET, local, remote, nil), mptcpSubflow{}, false, nil},
+		{"other upper layer protocol", withAttr(inetDiagULPInfo, tlsInfo), mptcpSubflow{}, false, nil},
+		{"invalid upper layer protocol", withAttr(inetDiagULPInfo, []byte{0xff, 0x00, 0x01, 0x00}), mptcpSubflow{}, false, errInvalidNetlinkAttribute},
+		{
+			"recorded subflow",
+			withAttr(inetDiagULPInfo, ulpInfo),
+			mptcpSubflow{
+				LocalToken:   binary.NativeEndian.Uint32([]byte{0xde, 0x67, 0xa3, 0xf1}),
+				LocalAddrID:  1,
+				RemoteAddrID: 2,
+				LocalAddr:    local,
+				RemoteAddr:   remote,
+				State:        StateEstablished,
+				Flags:        0xc1,
+			},
+			true,
+			nil,
+		},
+	}
+
+	for i, test := range tests {
+		sf, ok, err := parseSubflowDiagMessage(test.b)
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected err: %v != %v [test: %v]", i, err, test.err, test.desc)
+		}
+
+		if ok != test.ok {
+			t.Fatalf("[%02d] unexpected ok: %v != %v [test: %v]", i, ok, test.ok, test.desc)
+		}
+
+		if !reflect.DeepEqual(sf, test.sf) {
+			t.Fatalf("[%02d] unexpected subflow: %+v != %+v [test: %v]", i, sf, test.sf, test.desc)
+		}
+	}
+
+	// The subflow's address IDs apply to the connection with its token and
+	// addresses
+	conn := Connection{LocalToken: tests[4].sf.LocalToken, LocalAddr: local, RemoteAddr: remote}
+	if !tests[4].sf.matches(conn) {
+		t.Fatal("subflow does not match its connection")
+	}
+
+	conn.RemoteAddr = &net.TCPAddr{IP: remote.IP, Port: remote.Port + 1}
+	if tests[4].sf.matches(conn) {
+		t.Fatal("subflow matches connection with different remote address")
+	}
+}
+
+// TestLinux_listConnectionDetailsAddrIDs verifies that listConnectionDetails
+// fills in address IDs from matching subflows, and leaves them zero if the
+// subflows cannot be retrieved.
+func TestLinux_listConnectionDetailsAddrIDs(t *testing.T) {
+	origConns, origSubflows := listDiagConnections, listDiagSubflows
+	defer func() {
+		listDiagConnections, listDiagSubflows = origConns, origSubflows
+	}()
+
+	local := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 5000}
+	remote := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 2), Port: 443}
+	conn := Connection{LocalToken: 1, LocalAddr: local, RemoteAddr: remote}
+
+	subflow := mptcpSubflow{
+		LocalToken:   1,
+		LocalAddrID:  1,
+		RemoteAddrID: 2,
+		LocalAddr:    local,
+		RemoteAddr:   remote,
+	}
+
+	errFoo := errors.New("foo")
+
+	var tests = []struct {
+		desc       string
+		connsErr   error
+		subflows   []mptcpSubflow
+		subflowErr error
+		details    []ConnectionDetail
+		err        error
+	}{
+		{
+			desc:     "connections error",
+			connsErr: errFoo,
+			err:      errFoo,
+		},
+		{
+			desc:       "subflows error",
+			subflowErr: errFoo,
+			details:    []ConnectionDetail{{Connection: conn}},
+		},
+		{
+			desc:    "no matching subflow",
+			details: []ConnectionDetail{{Connection: conn}},
+		},
+		{
+			desc:     "matching subflow",
+			subflows: []mptcpSubflow{subflow},
+			details: []ConnectionDetail{{Connection: Connection{
+				LocalToken:   1,
+				LocalAddr:    local,
+				RemoteAddr:   remote,
+				LocalAddrID:  1,
+				RemoteAddrID: 2,
+			}}},
+		},
+	}
+
+	for i, test := range tests {
+		listDiagConnections = func() ([]ConnectionDetail, error) {
+			if test.connsErr != nil {
+				return nil, test.connsErr
+			}
+
+			return []ConnectionDetail{{Connection: conn}}, nil
+		}
+		listDiagSubflows = func() ([]mptcpSubflow, error) {
+			return test.subflows, test.subflowErr
+		}
+
+		details, err := listConnectionDetails()
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected err: %v != %v [test: %v]", i, err, test.err, test.desc)
+		}
+
+		if !reflect.DeepEqual(details, test.details) {
+			t.Fatalf("[%02d] unexpected details: %+v != %+v [test: %v]", i, details, test.details, test.desc)
+		}
+	}
+}
+
+// TestLinux_NetlinkSourceSkipsSubflows verifies that NetlinkSource does not
+// dump subflows to retrieve address IDs.
+func TestLinux_NetlinkSourceSkipsSubflows(t *testing.T) {
+	origConns, origSubflows := listDiagConnections, listDiagSubflows
+	defer func() {
+		listDiagConnections, listDiagSubflows = origConns, origSubflows
+	}()
+
+	listDiagConnections = func() ([]ConnectionDetail, error) {
+		return []ConnectionDetail{{Connection: Connection{LocalToken: 1}}}, nil
+	}
+	listDiagSubflows = func() ([]mptcpSubflow, error) {
+		t.Fatal("NetlinkSource dumped subflows")
+		return nil, nil
+	}
+
+	conns, err := NetlinkSource().ListConnections()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []Connection{{LocalToken: 1}}; !reflect.DeepEqual(conns, want) {
+		t.Fatalf("unexpected connections: %+v != %+v", conns, want)
+	}
+}
+
+// TestLinux_mptcpDiagRequest verifies that mptcpDiagRequest selects MPTCP
+// sockets using the sock_diag protocol attribute.
+func TestLinux_mptcpDiagRequest(t *testing.T) {
+	b := mptcpDiagRequest(syscall.AF_INET6)
+	if b[0] != syscall.AF_INET6 {
+		t.Fatalf("unexpected family: %v != %v", b[0], syscall.AF_INET6)
+	}
+
+	attrs, err := parseNetlinkAttributes(b[inetDiagReqV2Len:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []netlinkAttribute{{Type: inetDiagReqProtocol, Data: nlenc32(ipprotoMPTCP)}}
+	if !reflect.DeepEqual(attrs, want) {
+		t.Fatalf("unexpected request attributes: %v != %v", attrs, want)
+	}
+}
+
+// testMPTCPDiagMessage generates the data of a sock_diag reply message for a
+// MPTCP socket, with optional MPTCP information.
+func testMPTCPDiagMessage(family uint8, local, remote *net.TCPAddr, info []byte) []byte {
+	b := make([]byte, inetDiagMsgLen)
+	b[0] = family
+	b[1] = 1
+
+	binary.BigEndian.PutUint16(b[4:6], uint16(local.Port))
+	binary.BigEndian.PutUint16(b[6:8], uint16(remote.Port))
+
+	ipFor := func(ip net.IP) net.IP {
+		if family == syscall.AF_INET {
+			return ip.To4()
+		}
+
+		return ip.To16()
+	}
+	copy(b[8:24], ipFor(local.IP))
+	copy(b[24:40], ipFor(remote.IP))
+
+	if info == nil {
+		return b
+	}
+
+	return append(b, marshalNetlinkAttributes([]netlinkAttribute{{
+		Type: inetDiagInfo,
+		Data: info,
+	}})...)
+}
+
+// testMPTCPInfo generates a kernel struct mptcp_info of the input length,
+// containing the input values.
+func testMPTCPInfo(token uint32, writeSeq, sndUna, rcvNxt, bytesAcked uint64, length int) []byte {
+	// Use two subflows, reported in both the old and new forms
+	b := make([]byte, 88)
+	b[mptcpInfoSubflowsOffset] = 1
+	b[mptcpInfoSubflowsTotalOffset] = 2
+	binary.NativeEndian.PutUint32(b[mptcpInfoTokenOffset:], token)
+	binary.NativeEndian.PutUint64(b[mptcpInfoWriteSeqOffset:], writeSeq)
+	binary.NativeEndian.PutUint64(b[mptcpInfoSndUnaOffset:], sndUna)
+	binary.NativeEndian.PutUint64(b[mptcpInfoRcvNxtOffset:], rcvNxt)
+	binary.NativeEndian.PutUint64(b[mptcpInfoBytesAckedOffset:], bytesAcked)
+
+	return b[:length]
+}
+
+// nlenc32 encodes a uint32 in native byte order, as used by netlink.
+func nlenc32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.NativeEndian.PutUint32(b, v)
+	return b
+}
+
+// TestLinux_SubflowsLoopback verifies that Subflows reports the subflow of a
+// real loopback MPTCP connection, if this host supports MPTCP.
+func TestLinux_SubflowsLoopback(t *testing.T) {
+	client, server := testDialPair(t, &Dialer{})
+
+	ok, err := client.(*net.TCPConn).MultipathTCP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Skip("MPTCP is not available on this host")
+	}
+
+	local := client.LocalAddr().(*net.TCPAddr)
+	subflows, err := Subflows(local.IP.String(), uint16(local.Port))
+	if err != nil {
+		t.Skipf("subflows are not available: %v", err)
+	}
+
+	if len(subflows) != 1 {
+		t.Fatalf("unexpected subflow count: %v != %v", len(subflows), 1)
+	}
+
+	sf := subflows[0]
+	serverLocal := server.LocalAddr().(*net.TCPAddr)
+	if !sf.LocalAddr.IP.Equal(serverLocal.IP) || sf.LocalAddr.Port != serverLocal.Port {
+		t.Fatalf("unexpected local address: %v != %v", sf.LocalAddr, serverLocal)
+	}
+
+	if sf.State != StateEstablished {
+		t.Fatalf("unexpected state: %v != %v", sf.State, StateEstablished)
+	}
+}
@@ -0,0 +1,113 @@
+package mptcp
+
+import (
+	"context"
+	"net"
+	"syscall"
+)
+
+// A Dialer dials TCP connections which use multipath TCP when the operating
+// system supports it, and transparently fall back to regular TCP when it does
+// not, or when the peer does not support multipath TCP.
+//
+// The embedded net.Dialer may be used to configure options such as timeouts
+// and the local address.  The zero value of Dialer is ready to use.
+type Dialer struct {
+	net.Dialer
+}
+
+// Dial connects to the address on the named network.  See net.Dial for a
+// description of the network and address parameters.
+func (d *Dialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+// DialContext connects to the address on the named network using the input
+// context.  See net.Dialer.DialContext for a description of the parameters.
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	// Configure a copy, so the Dialer's own options are not modified
+	nd := d.Dialer
+	nd.SetMultipathTCP(true)
+
+	return nd.DialContext(ctx, network, address)
+}
+
+// Dial connects to the address on the named network using a Dialer with
+// default options.
+func Dial(network, address string) (net.Conn, error) {
+	var d Dialer
+	return d.Dial(network, address)
+}
+
+// Listen announces on the local network address, accepting connections which
+// use multipath TCP when the operating system supports it, and regular TCP
+// otherwise.  See net.Listen for a description of the network and address
+// parameters.
+func Listen(network, address string) (net.Listener, error) {
+	return ListenContext(context.Background(), network, address)
+}
+
+// ListenContext is like Listen, but uses the input context while announcing
+// on the local network address.
+func ListenContext(ctx context.Context, network, address string) (net.Listener, error) {
+	var lc net.ListenConfig
+	lc.SetMultipathTCP(true)
+
+	return lc.Listen(ctx, network, address)
+}
+
+// IsMPTCP reports whether the input connection, which must be owned by the
+// current process, is using multipath TCP.  Connections which wrap another
+// net.Conn and expose it using a NetConn method, such as a *tls.Conn, are
+// unwrapped before they are checked.
+//
+// Connections which fell back to regular TCP, because the peer does not
+// support multipath TCP, are not reported as using multipath TCP.
+//
+// If the connection does not expose its file descriptor, ErrNoFileDescriptor
+// is returned.
+//
+// If multipath TCP detection is not implemented for the current operating system,
+// this function will return ErrNotImplemented.
+func IsMPTCP(c net.Conn) (bool, error) {
+	var (
+		isMPTCP bool
+		fdErr   error
+	)
+	if err := controlConn(c, func(fd int) {
+		isMPTCP, fdErr = isFDMPTCP(fd)
+	}); err != nil {
+		return false, err
+	}
+
+	return isMPTCP, fdErr
+}
+
+// controlConn invokes fn with the file descriptor of the input connection,
+// unwrapping connections layered over another connection.  If the connection
+// does not expose its file descriptor, ErrNoFileDescriptor is returned.
+func controlConn(c net.Conn, fn func(fd int)) error {
+	// Unwrap connections layered over another connection
+	for {
+		w, ok := c.(interface{ NetConn() net.Conn })
+		if !ok {
+			break
+		}
+
+		c = w.NetConn()
+	}
+
+	sc, ok := c.(syscall.Conn)
+	if !ok {
+		return ErrNoFileDescriptor
+	}
+
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	return rc.Control(func(fd uintptr) {
+		fn(int(fd))
+	})
+}
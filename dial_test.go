@@ -0,0 +1,129 @@
+package mptcp
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+)
+
+// testDialPair dials a connection to a listener created by Listen, using
+// the input Dialer, and returns both ends of the connection.
+func testDialPair(t *testing.T, d *Dialer) (client net.Conn, server net.Conn) {
+	t.Helper()
+
+	l, err := Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	acceptC := make(chan net.Conn, 1)
+	errC := make(chan error, 1)
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			errC <- err
+			return
+		}
+
+		acceptC <- c
+	}()
+
+	client, err = d.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	select {
+	case server = <-acceptC:
+	case err := <-errC:
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = server.Close() })
+
+	return client, server
+}
+
+// TestDialerListen verifies that connections dialed by a Dialer to a
+// listener created by Listen carry data, and that dialing does not modify
+// the Dialer's own options.
+func TestDialerListen(t *testing.T) {
+	var d Dialer
+	client, server := testDialPair(t, &d)
+
+	if d.Dialer.MultipathTCP() {
+		t.Fatal("Dial modified the Dialer's options")
+	}
+
+	want := []byte("hello")
+	if _, err := client.Write(want); err != nil {
+		t.Fatal(err)
+	}
+
+	got := make([]byte, len(want))
+	if _, err := io.ReadFull(server, got); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != string(want) {
+		t.Fatalf("unexpected data: %q != %q", got, want)
+	}
+}
+
+// wrappedConn is a net.Conn which exposes the connection it wraps using a
+// NetConn method, like a *tls.Conn.
+type wrappedConn struct {
+	net.Conn
+}
+
+// NetConn returns the wrapped connection.
+func (c wrappedConn) NetConn() net.Conn {
+	return c.Conn
+}
+
+// TestIsMPTCP verifies that IsMPTCP checks the file descriptor of a
+// connection, unwrapping connections layered over another connection.
+func TestIsMPTCP(t *testing.T) {
+	client, _ := testDialPair(t, &Dialer{})
+
+	errFD := errors.New("fd check failed")
+
+	origFD := isFDMPTCP
+	defer func() { isFDMPTCP = origFD }()
+
+	pipe, _ := net.Pipe()
+	defer pipe.Close()
+
+	var tests = []struct {
+		desc  string
+		c     net.Conn
+		fdOK  bool
+		fdErr error
+		ok    bool
+		err   error
+	}{
+		{"MPTCP connection", client, true, nil, true, nil},
+		{"TCP connection", client, false, nil, false, nil},
+		{"wrapped connection", wrappedConn{wrappedConn{client}}, true, nil, true, nil},
+		{"fd check error", client, false, errFD, false, errFD},
+		{"no file descriptor", pipe, true, nil, false, ErrNoFileDescriptor},
+		{"wrapped, no file descriptor", wrappedConn{pipe}, true, nil, false, ErrNoFileDescriptor},
+	}
+
+	for i, test := range tests {
+		isFDMPTCP = func(fd int) (bool, error) {
+			return test.fdOK, test.fdErr
+		}
+
+		ok, err := IsMPTCP(test.c)
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected err: %v != %v [test: %v]", i, err, test.err, test.desc)
+		}
+
+		if ok != test.ok {
+			t.Fatalf("[%02d] unexpected ok: %v != %v [test: %v]", i, ok, test.ok, test.desc)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package mptcp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// WriteDOT writes the topology of all active multipath TCP connections on
+// this host to w as a Graphviz DOT graph, which may be rendered using a tool
+// such as "dot -Tpng".
+//
+// Local addresses and remote peers are rendered as nodes, and each subflow is
+// rendered as an edge from its local address to its remote peer, labeled
+// with its ports.  The subflows of each connection are grouped together and
+// share a color.
+//
+// If multipath TCP detection is not implemented for the current operating system,
+// this function will return ErrNotImplemented.
+func WriteDOT(w io.Writer) error {
+	conns, err := listConnections()
+	if err != nil {
+		return err
+	}
+
+	return writeDOT(w, conns)
+}
+
+// dotColors are the colors used for the edges of each connection, in turn.
+var dotColors = []string{
+	"blue",
+	"red",
+	"darkgreen",
+	"orange",
+	"purple",
+	"brown",
+}
+
+// writeDOT writes the topology of the input connections to w as a DOT graph.
+func writeDOT(w io.Writer, conns []Connection) error {
+	// Sort a copy so the graph is identical for identical connections
+	conns = append([]Connection(nil), conns...)
+	sortConnections(conns, compareConnections)
+
+	// Collect each distinct local and remote IP address
+	locals := make(map[string]struct{})
+	remotes := make(map[string]struct{})
+	for _, c := range conns {
+		if c.LocalAddr != nil {
+			locals[c.LocalAddr.IP.String()] = struct{}{}
+		}
+		if c.RemoteAddr != nil {
+			remotes[c.RemoteAddr.IP.String()] = struct{}{}
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "digraph mptcp {")
+	fmt.Fprintln(bw, "\trankdir=LR;")
+
+	for _, ip := range sortedKeys(locals) {
+		fmt.Fprintf(bw, "\t%q [shape=box];\n", ip)
+	}
+	for _, ip := range sortedKeys(remotes) {
+		fmt.Fprintf(bw, "\t%q [shape=ellipse];\n", ip)
+	}
+
+	for i, lc := range groupConnections(conns) {
+		fmt.Fprintf(bw, "\tsubgraph \"connection_%08X\" {\n", lc.LocalToken)
+		fmt.Fprintf(bw, "\t\tedge [color=%s];\n", dotColors[i%len(dotColors)])
+
+		for _, sf := range lc.Subflows {
+			if sf.LocalAddr == nil || sf.RemoteAddr == nil {
+				continue
+			}
+
+			fmt.Fprintf(bw, "\t\t%q -> %q [label=\"%d -> %d\"];\n",
+				sf.LocalAddr.IP.String(), sf.RemoteAddr.IP.String(),
+				sf.LocalAddr.Port, sf.RemoteAddr.Port,
+			)
+		}
+
+		fmt.Fprintln(bw, "\t}")
+	}
+
+	fmt.Fprintln(bw, "}")
+
+	return bw.Flush()
+}
+
+// sortedKeys returns the keys of the input map in sorted order.
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,60 @@
+package mptcp
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+// TestWriteDOT verifies that WriteDOT renders a golden DOT graph, grouping
+// the subflows of each connection, using a mock connection source.
+func TestWriteDOT(t *testing.T) {
+	origList := listConnections
+	defer func() { listConnections = origList }()
+
+	subflow := func(token uint32, local string, lport int, remote string, rport int) Connection {
+		return Connection{
+			LocalToken: token,
+			LocalAddr:  &net.TCPAddr{IP: net.ParseIP(local), Port: lport},
+			RemoteAddr: &net.TCPAddr{IP: net.ParseIP(remote), Port: rport},
+		}
+	}
+
+	// Two subflows of one connection, and a single subflow connection
+	conns := []Connection{
+		subflow(0x9C290BF6, "10.0.0.10", 22, ipv4HostOne, 48105),
+		subflow(0xF6635734, "2001:db8::10", 80, ipv6HostOne, 2020),
+		subflow(0x9C290BF6, "192.168.1.10", 22, ipv4HostOne, 48104),
+	}
+	listConnections = func() ([]Connection, error) {
+		return conns, nil
+	}
+
+	const golden = `digraph mptcp {
+	rankdir=LR;
+	"10.0.0.10" [shape=box];
+	"192.168.1.10" [shape=box];
+	"2001:db8::10" [shape=box];
+	"2001:4860:4860::8888" [shape=ellipse];
+	"8.8.8.8" [shape=ellipse];
+	subgraph "connection_9C290BF6" {
+		edge [color=blue];
+		"192.168.1.10" -> "8.8.8.8" [label="22 -> 48104"];
+		"10.0.0.10" -> "8.8.8.8" [label="22 -> 48105"];
+	}
+	subgraph "connection_F6635734" {
+		edge [color=red];
+		"2001:db8::10" -> "2001:4860:4860::8888" [label="80 -> 2020"];
+	}
+}
+`
+
+	var buf bytes.Buffer
+	if err := WriteDOT(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if s := buf.String(); s != golden {
+		t.Fatalf("unexpected DOT output:\n- want:\n%s\n-  got:\n%s", golden, s)
+	}
+}
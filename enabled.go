@@ -0,0 +1,61 @@
+package mptcp
+
+import (
+	"context"
+	"time"
+)
+
+// WatchEnabled polls Enabled at each interval, so that applications can react
+// when multipath TCP support is added to or removed from this host at
+// runtime, such as when a kernel module is loaded or unloaded.
+//
+// The returned channel first emits the current result of Enabled, and then
+// emits each time the result flips.  Polls which return an error are skipped,
+// and do not change the reported state.  The channel is closed once ctx is
+// canceled.
+//
+// If interval is not positive, ErrInvalidInterval is returned.  If the
+// initial call to Enabled returns an error, it is returned.
+func WatchEnabled(ctx context.Context, interval time.Duration) (<-chan bool, error) {
+	if interval <= 0 {
+		return nil, ErrInvalidInterval
+	}
+
+	enabled, err := mptcpEnabled(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Buffer the initial state so it is available immediately
+	enabledC := make(chan bool, 1)
+	enabledC <- enabled
+
+	go func() {
+		defer close(enabledC)
+
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+
+			ok, err := mptcpEnabled(ctx)
+			if err != nil || ok == enabled {
+				continue
+			}
+			enabled = ok
+
+			select {
+			case enabledC <- ok:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return enabledC, nil
+}
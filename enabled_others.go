@@ -0,0 +1,10 @@
+// +build !linux,!darwin
+
+package mptcp
+
+import "context"
+
+// mptcpEnabled always returns false unless explicitly supported by a platform.
+var mptcpEnabled = func(ctx context.Context) (bool, error) {
+	return false, nil
+}
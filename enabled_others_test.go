@@ -0,0 +1,17 @@
+// +build !linux,!darwin
+
+package mptcp
+
+import (
+	"context"
+	"testing"
+)
+
+// TestOthers_mptcpEnabled verifies that mptcpEnabled always returns
+// false unless a platform explicitly supports it.
+func TestOthers_mptcpEnabled(t *testing.T) {
+	ok, err := mptcpEnabled(context.Background())
+	if ok || err != nil {
+		t.Fatalf("mptcpEnabled should return (false, nil), but returned: (%v, %v)", ok, err)
+	}
+}
@@ -0,0 +1,107 @@
+package mptcp
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestWatchEnabled verifies that WatchEnabled emits the initial enabled state,
+// and then emits each time the state flips.
+func TestWatchEnabled(t *testing.T) {
+	type result struct {
+		ok  bool
+		err error
+	}
+
+	errPoll := errors.New("poll error")
+
+	// Toggle the enabled state, with repeated states and errors which must
+	// not be emitted, and then remain disabled
+	var mu sync.Mutex
+	results := []result{
+		{true, nil},
+		{true, nil},
+		{false, nil},
+		{false, errPoll},
+		{false, nil},
+		{true, nil},
+		{false, errPoll},
+		{false, nil},
+	}
+
+	origEnabled := mptcpEnabled
+	defer func() { mptcpEnabled = origEnabled }()
+	mptcpEnabled = func(context.Context) (bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		r := results[0]
+		if len(results) > 1 {
+			results = results[1:]
+		}
+
+		return r.ok, r.err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	enabledC, err := WatchEnabled(ctx, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []bool
+	for len(got) < 4 {
+		select {
+		case ok := <-enabledC:
+			got = append(got, ok)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for enabled states, got: %v", got)
+		}
+	}
+
+	if want := []bool{true, false, true, false}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected enabled states: %v != %v", got, want)
+	}
+
+	// The channel must be closed once the context is canceled
+	cancel()
+	for range enabledC {
+	}
+}
+
+// TestWatchEnabledErrors verifies that WatchEnabled returns an error for an
+// invalid interval, or an error from the initial enabled check.
+func TestWatchEnabledErrors(t *testing.T) {
+	errFoo := errors.New("foo")
+
+	var tests = []struct {
+		interval time.Duration
+		err      error
+		wantErr  error
+	}{
+		{0, nil, ErrInvalidInterval},
+		{-time.Second, nil, ErrInvalidInterval},
+		{time.Second, errFoo, errFoo},
+	}
+
+	origEnabled := mptcpEnabled
+	defer func() { mptcpEnabled = origEnabled }()
+
+	for i, test := range tests {
+		err := test.err
+		mptcpEnabled = func(context.Context) (bool, error) {
+			return false, err
+		}
+
+		enabledC, err := WatchEnabled(context.Background(), test.interval)
+		if enabledC != nil || err != test.wantErr {
+			t.Fatalf("[%02d] unexpected result: (%v, %v) != (nil, %v)", i, enabledC, err, test.wantErr)
+		}
+	}
+}
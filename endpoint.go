@@ -0,0 +1,138 @@
+package mptcp
+
+import (
+	"net/netip"
+	"strings"
+)
+
+// EndpointFlags are flags which configure how the multipath TCP path manager
+// uses an Endpoint, using the flag values of the Linux kernel.
+type EndpointFlags uint32
+
+// Possible EndpointFlags values.
+const (
+	// EndpointSignal announces the endpoint's address to peers of each
+	// connection.
+	EndpointSignal EndpointFlags = 1 << iota
+
+	// EndpointSubflow creates an additional subflow from the endpoint's
+	// address for each connection initiated by this host.
+	EndpointSubflow
+
+	// EndpointBackup marks subflows using the endpoint's address as backup
+	// subflows.
+	EndpointBackup
+
+	// EndpointFullmesh creates a subflow from the endpoint's address to each
+	// address announced by a peer.
+	EndpointFullmesh
+
+	// EndpointImplicit is set by the kernel on endpoints it created
+	// implicitly, for addresses used by subflows created by peers.
+	EndpointImplicit
+)
+
+// endpointFlagNames are the names of each known EndpointFlags value, as used
+// by the ip-mptcp(8) command.
+var endpointFlagNames = []struct {
+	f    EndpointFlags
+	name string
+}{
+	{EndpointSignal, "signal"},
+	{EndpointSubflow, "subflow"},
+	{EndpointBackup, "backup"},
+	{EndpointFullmesh, "fullmesh"},
+	{EndpointImplicit, "implicit"},
+}
+
+// String returns the names of the flags which are set, separated by commas.
+func (f EndpointFlags) String() string {
+	var names []string
+	for _, n := range endpointFlagNames {
+		if f&n.f != 0 {
+			names = append(names, n.name)
+		}
+	}
+
+	return strings.Join(names, ",")
+}
+
+// An Endpoint is a local address which the multipath TCP path manager may
+// announce to peers or use to create additional subflows, as configured by
+// the ip-mptcp(8) endpoint command.
+type Endpoint struct {
+	// ID is the MPTCP address ID of this endpoint.  When adding an endpoint,
+	// an ID of zero lets the kernel choose one.
+	ID uint8
+
+	// Addr is the local address of this endpoint, and Port is an optional
+	// port on which this endpoint accepts additional subflows.
+	Addr netip.Addr
+	Port uint16
+
+	// Interface is the optional index of the network interface from which
+	// subflows using this endpoint are created.
+	Interface int
+
+	// Flags configures how the path manager uses this endpoint.
+	Flags EndpointFlags
+}
+
+// Limits are the limits which the multipath TCP path manager applies to each
+// connection.
+type Limits struct {
+	// Subflows is the maximum number of additional subflows of each
+	// connection.
+	Subflows uint32
+
+	// AddAddrAccepted is the maximum number of addresses announced by the
+	// peer of each connection which are used to create additional subflows.
+	AddAddrAccepted uint32
+}
+
+// Endpoints returns the endpoints configured for the multipath TCP path
+// manager of this host.
+//
+// On Linux, the path manager is configured using generic netlink, and
+// requires a kernel with mainline MPTCP support.  Changing its
+// configuration requires CAP_NET_ADMIN.
+//
+// If path manager configuration is not implemented for the current operating
+// system, this function and the other path manager functions will return
+// ErrNotImplemented.
+func Endpoints() ([]Endpoint, error) {
+	return pmEndpoints()
+}
+
+// AddEndpoint adds an endpoint to the multipath TCP path manager of this
+// host.  The address of the endpoint must be valid.
+func AddEndpoint(e Endpoint) error {
+	if !e.Addr.IsValid() {
+		return ErrInvalidIPAddress
+	}
+
+	return pmAddEndpoint(e)
+}
+
+// DeleteEndpoint removes the endpoint with the input MPTCP address ID from
+// the multipath TCP path manager of this host.
+func DeleteEndpoint(id uint8) error {
+	return pmDeleteEndpoint(id)
+}
+
+// FlushEndpoints removes every endpoint from the multipath TCP path manager
+// of this host.
+func FlushEndpoints() error {
+	return pmFlushEndpoints()
+}
+
+// ReadLimits returns the limits of the multipath TCP path manager of this
+// host.
+func ReadLimits() (Limits, error) {
+	return pmLimits()
+}
+
+// SetLimits sets the limits of the multipath TCP path manager of this host.
+func SetLimits(l Limits) error {
+	return pmSetLimits(l)
+}
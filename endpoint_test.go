@@ -0,0 +1,40 @@
+package mptcp
+
+import (
+	"testing"
+)
+
+// TestEndpointFlagsString verifies that EndpointFlags are named as they are
+// by the ip-mptcp(8) command.
+func TestEndpointFlagsString(t *testing.T) {
+	var tests = []struct {
+		f    EndpointFlags
+		want string
+	}{
+		{0, ""},
+		{EndpointSignal, "signal"},
+		{EndpointSubflow | EndpointBackup, "subflow,backup"},
+		{EndpointFullmesh | EndpointImplicit | 1<<10, "fullmesh,implicit"},
+	}
+
+	for i, test := range tests {
+		if got := test.f.String(); got != test.want {
+			t.Fatalf("[%02d] unexpected string: %q != %q", i, got, test.want)
+		}
+	}
+}
+
+// TestAddEndpointInvalidAddress verifies that AddEndpoint rejects endpoints
+// without a valid address.
+func TestAddEndpointInvalidAddress(t *testing.T) {
+	orig := pmAddEndpoint
+	defer func() { pmAddEndpoint = orig }()
+	pmAddEndpoint = func(e Endpoint) error {
+		t.Fatal("path manager consulted for invalid endpoint")
+		return nil
+	}
+
+	if err := AddEndpoint(Endpoint{ID: 1, Flags: EndpointSignal}); err != ErrInvalidIPAddress {
+		t.Fatalf("unexpected err: %v != %v", err, ErrInvalidIPAddress)
+	}
+}
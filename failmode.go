@@ -0,0 +1,53 @@
+package mptcp
+
+// A FailMode determines how a Checker's Check method reports errors which
+// occur while reading connections.
+type FailMode int
+
+const (
+	// FailError reports read errors to the caller.  It is the default.
+	FailError FailMode = iota
+
+	// FailOpen treats a read error as an active multipath TCP connection,
+	// reporting true and no error.
+	FailOpen
+
+	// FailClosed treats a read error as the absence of a multipath TCP
+	// connection, reporting false and no error.
+	FailClosed
+)
+
+// WithFailMode configures how a Checker's Check method reports errors which
+// occur while reading connections, such as a connections table which cannot
+// be opened or contains invalid entries.  Errors caused by invalid input,
+// such as a malformed host:port string, are always returned.
+//
+// Suppressing errors hides failures from the caller, so the mode must be
+// chosen based on what a wrong answer costs.  A caller which uses Check to
+// grant access based on multipath TCP usage should use FailClosed, so that
+// an attacker who can break reads of the table cannot gain access.  FailOpen
+// should only be used by best-effort callers, such as monitors, for which a
+// false positive is harmless.  Never use FailOpen for security decisions.
+//
+// If this option is not set, FailError is used.
+func WithFailMode(mode FailMode) Option {
+	return func(c *Checker) {
+		c.failMode = mode
+	}
+}
+
+// apply applies the FailMode to the result of reading connections.
+func (m FailMode) apply(ok bool, err error) (bool, error) {
+	if err == nil {
+		return ok, nil
+	}
+
+	switch m {
+	case FailOpen:
+		return true, nil
+	case FailClosed:
+		return false, nil
+	default:
+		return false, err
+	}
+}
@@ -0,0 +1,59 @@
+package mptcp
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+// TestCheckerFailMode verifies that a Checker reports read errors from Check
+// as configured by WithFailMode.
+func TestCheckerFailMode(t *testing.T) {
+	errRead := errors.New("read error")
+	failing := SourceFunc(func() ([]Connection, error) {
+		return nil, errRead
+	})
+
+	working := SourceFunc(func() ([]Connection, error) {
+		return []Connection{{
+			LocalAddr:  &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 22},
+			RemoteAddr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 40000},
+		}}, nil
+	})
+
+	var tests = []struct {
+		source   Source
+		mode     []Option
+		hostport string
+		ok       bool
+		err      error
+	}{
+		// Read errors are returned by default
+		{failing, nil, "10.0.0.2:40000", false, errRead},
+		{failing, []Option{WithFailMode(FailError)}, "10.0.0.2:40000", false, errRead},
+		// Read errors are suppressed by each fail mode
+		{failing, []Option{WithFailMode(FailOpen)}, "10.0.0.2:40000", true, nil},
+		{failing, []Option{WithFailMode(FailClosed)}, "10.0.0.2:40000", false, nil},
+		// Input errors are always returned
+		{failing, []Option{WithFailMode(FailOpen)}, "foo:40000", false, ErrInvalidIPAddress},
+		{failing, []Option{WithFailMode(FailClosed)}, "foo:40000", false, ErrInvalidIPAddress},
+		// Successful reads are unaffected by fail modes
+		{working, []Option{WithFailMode(FailOpen)}, "10.0.0.2:40000", true, nil},
+		{working, []Option{WithFailMode(FailOpen)}, "10.0.0.3:40000", false, nil},
+		{working, []Option{WithFailMode(FailClosed)}, "10.0.0.2:40000", true, nil},
+	}
+
+	for i, test := range tests {
+		c := NewChecker(append([]Option{WithSources(test.source)}, test.mode...)...)
+
+		// Source errors may be wrapped with the failing source
+		ok, err := c.Check(test.hostport)
+		if !errors.Is(err, test.err) {
+			t.Fatalf("[%02d] unexpected err: %v != %v", i, err, test.err)
+		}
+
+		if ok != test.ok {
+			t.Fatalf("[%02d] unexpected ok: %v != %v", i, ok, test.ok)
+		}
+	}
+}
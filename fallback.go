@@ -0,0 +1,66 @@
+package mptcp
+
+import (
+	"net"
+	"strconv"
+)
+
+// TCPTables is a set of flags which indicate the operating system TCP
+// connections tables consulted during fallback detection.
+type TCPTables uint8
+
+const (
+	// TCPTableIPv4 indicates that the IPv4 TCP connections table was consulted.
+	TCPTableIPv4 TCPTables = 1 << iota
+
+	// TCPTableIPv6 indicates that the IPv6 TCP connections table was consulted.
+	TCPTableIPv6
+)
+
+// FallbackResult contains the result of CheckFallback.
+type FallbackResult struct {
+	// TCP reports whether a TCP connection originating from the host:port
+	// was found in any of the consulted TCP connections tables.
+	TCP bool
+
+	// MPTCP reports whether a multipath TCP connection originating from the
+	// host:port is active.
+	MPTCP bool
+
+	// Tables indicates which TCP connections tables were consulted.  Tables
+	// which are not present on this host are not consulted, and IPv4
+	// clients may be found in the IPv6 table when connected to an IPv6 socket.
+	Tables TCPTables
+}
+
+// Fallback reports whether a connection originating from the host:port is
+// active, but is using regular TCP instead of multipath TCP.
+func (r FallbackResult) Fallback() bool {
+	return r.TCP && !r.MPTCP
+}
+
+// CheckFallback detects if there is an active TCP connection to this machine,
+// originating from the input host:port string, which has fallen back to
+// regular TCP instead of using multipath TCP.
+//
+// Whichever TCP connections tables are present on this host are consulted,
+// and the tables consulted are reported in the result.  An error is only
+// returned for missing tables if no table could be consulted.
+//
+// If multipath TCP detection is not implemented for the current operating system,
+// this function will return ErrNotImplemented.
+func CheckFallback(hostport string) (FallbackResult, error) {
+	// Split input hostport pair
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return FallbackResult{}, err
+	}
+
+	// Convert port into a uint16
+	uPort, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return FallbackResult{}, err
+	}
+
+	return checkFallback(host, uint16(uPort))
+}
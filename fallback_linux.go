@@ -0,0 +1,176 @@
+// +build linux
+
+package mptcp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+)
+
+const (
+	// procTCP and procTCP6 are the locations of the Linux-specific files
+	// which contain the active IPv4 and IPv6 TCP connections tables.
+	procTCP  = "/proc/net/tcp"
+	procTCP6 = "/proc/net/tcp6"
+
+	// tcpTableMinColumns is the minimum number of columns in a valid Linux
+	// TCP connections table entry.
+	tcpTableMinColumns = 4
+)
+
+var (
+	// errInvalidTCPEntry is returned when an input TCP connection entry
+	// is not in the expected format.
+	errInvalidTCPEntry = errors.New("invalid TCP connection entry")
+)
+
+// tcpTable is a Linux TCP connections table which may be consulted for
+// fallback detection.
+type tcpTable struct {
+	path string
+	flag TCPTables
+}
+
+// tcpTables are the TCP connections tables consulted for fallback detection.
+var tcpTables = []tcpTable{
+	{path: procTCP, flag: TCPTableIPv4},
+	{path: procTCP6, flag: TCPTableIPv6},
+}
+
+// checkFallback uses the Linux /proc filesystem to determine if an input
+// host string and uint16 port are present in this Linux machine's TCP
+// active connections, and in its MPTCP active connections.
+var checkFallback = func(host string, port uint16) (FallbackResult, error) {
+	r, err := checkTCPTablesLinux(tcpTables, host, port)
+	if err != nil {
+		return FallbackResult{}, err
+	}
+
+	// A missing MPTCP table means no connection can be using MPTCP
+	ok, err := checkMPTCP(context.Background(), host, port)
+	if err != nil && !os.IsNotExist(err) {
+		return FallbackResult{}, err
+	}
+	r.MPTCP = ok
+
+	return r, nil
+}
+
+// checkTCPTablesLinux checks each input TCP connections table which is
+// present on this machine for an active connection matching the input
+// host string and uint16 port.
+func checkTCPTablesLinux(tables []tcpTable, host string, port uint16) (FallbackResult, error) {
+	// Determine the hex form of the host:port in each table
+	hexHostPorts, err := tcpTableHostPorts(host, port)
+	if err != nil {
+		return FallbackResult{}, err
+	}
+
+	var r FallbackResult
+	var missingErr error
+	for _, t := range tables {
+		// Skip tables which cannot contain this host
+		hexHostPort, ok := hexHostPorts[t.flag]
+		if !ok {
+			continue
+		}
+
+		f, err := os.Open(t.path)
+		if err != nil {
+			// Skip missing tables, but report if no tables are present
+			if os.IsNotExist(err) {
+				if missingErr == nil {
+					missingErr = err
+				}
+
+				continue
+			}
+
+			return FallbackResult{}, err
+		}
+
+		found, err := tcpTableReaderLinux(f, hexHostPort)
+		_ = f.Close()
+		if err != nil {
+			return FallbackResult{}, err
+		}
+
+		r.Tables |= t.flag
+		r.TCP = r.TCP || found
+	}
+
+	if r.Tables == 0 && missingErr != nil {
+		return FallbackResult{}, missingErr
+	}
+
+	return r, nil
+}
+
+// tcpTableHostPorts returns the hex host:port forms of an input host string
+// and uint16 port, keyed by the TCP connections table in which they
+// may appear.
+func tcpTableHostPorts(host string, port uint16) (map[TCPTables]string, error) {
+	hexHostPort, err := hostPortToHex(host, port)
+	if err != nil {
+		return nil, err
+	}
+
+	// IPv6 hosts can only appear in the IPv6 table
+	if net.ParseIP(host).To4() == nil {
+		return map[TCPTables]string{
+			TCPTableIPv6: hexHostPort,
+		}, nil
+	}
+
+	// IPv4 hosts may also appear in the IPv6 table, using an IPv4-mapped
+	// IPv6 address, when connected to an IPv6 socket
+	return map[TCPTables]string{
+		TCPTableIPv4: hexHostPort,
+		TCPTableIPv6: joinHexHostPort(ipToHex(net.ParseIP(host).To16(), tableByteOrder), port),
+	}, nil
+}
+
+// tcpTableReaderLinux reads a TCP connections table from an input stream,
+// and reports whether an entry with a remote address matching the input hex
+// host:port pair is present.
+func tcpTableReaderLinux(r io.Reader, hexHostPort string) (bool, error) {
+	// Open text scanner to split lines, skip header line
+	scanner := bufio.NewScanner(r)
+	scanner.Split(bufio.ScanLines)
+	if !scanner.Scan() {
+		// Report any read error before assuming the file was empty
+		if err := scanner.Err(); err != nil {
+			return false, err
+		}
+
+		// If file was empty, return unexpected EOF
+		return false, io.ErrUnexpectedEOF
+	}
+
+	// Iterate until EOF or entry found
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < tcpTableMinColumns {
+			// A read error leaves a truncated final line, so report the
+			// read error instead of the resulting invalid entry
+			if err := scanner.Err(); err != nil {
+				return false, err
+			}
+
+			return false, errInvalidTCPEntry
+		}
+
+		// Check for remote address which matches input
+		if fields[2] == hexHostPort {
+			return true, nil
+		}
+	}
+
+	// No result found, unless a read error stopped the scan early
+	return false, scanner.Err()
+}
@@ -0,0 +1,124 @@
+// +build linux
+
+package mptcp
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var (
+	// Headers and entries in the format of real TCP connections tables,
+	// used for testing
+	testTCPTableHeader  = "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode"
+	testTCP6TableHeader = "  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode"
+	testTCPEntry        = "   1: E70E8368:0016 1134B018:BBE8 01 00000000:00000000 02:000A7214 00000000     0        0 15666 4 0000000000000000 20 4 30 10 -1"
+	testTCP6MappedEntry = "   0: 0000000000000000FFFF0000E70E8368:0016 0000000000000000FFFF000008080808:07E4 01 00000000:00000000 00:00000000 00000000     0        0 15667 1 0000000000000000 20 4 30 10 -1"
+)
+
+// TestLinux_checkTCPTablesLinux verifies that checkTCPTablesLinux reads
+// whichever TCP connections tables are present, and reports which tables
+// were consulted.
+func TestLinux_checkTCPTablesLinux(t *testing.T) {
+	// The IPv6 table entry contains an IPv4-mapped IPv6 address for
+	// 8.8.8.8:2020
+	tcp := testTCPTableHeader + "\n" + testTCPEntry + "\n"
+	tcp6 := testTCP6TableHeader + "\n" + testTCP6MappedEntry + "\n"
+
+	var tests = []struct {
+		tcp    string
+		tcp6   string
+		host   string
+		port   uint16
+		result FallbackResult
+		err    bool
+	}{
+		// Neither table present
+		{"", "", "24.176.52.17", 48104, FallbackResult{}, true},
+		// Both tables present, found in IPv4 table
+		{tcp, tcp6, "24.176.52.17", 48104, FallbackResult{TCP: true, Tables: TCPTableIPv4 | TCPTableIPv6}, false},
+		// Both tables present, found in IPv6 table
+		{tcp, tcp6, "8.8.8.8", 2020, FallbackResult{TCP: true, Tables: TCPTableIPv4 | TCPTableIPv6}, false},
+		// Both tables present, not found
+		{tcp, tcp6, "8.8.4.4", 4040, FallbackResult{Tables: TCPTableIPv4 | TCPTableIPv6}, false},
+		// Only IPv4 table present, found
+		{tcp, "", "24.176.52.17", 48104, FallbackResult{TCP: true, Tables: TCPTableIPv4}, false},
+		// Only IPv4 table present, not found
+		{tcp, "", "8.8.8.8", 2020, FallbackResult{Tables: TCPTableIPv4}, false},
+		// Only IPv6 table present, found
+		{"", tcp6, "8.8.8.8", 2020, FallbackResult{TCP: true, Tables: TCPTableIPv6}, false},
+		// Only IPv6 table present, not found
+		{"", tcp6, "24.176.52.17", 48104, FallbackResult{Tables: TCPTableIPv6}, false},
+		// Invalid IP address
+		{tcp, tcp6, badIPHostOne, 0, FallbackResult{}, true},
+	}
+
+	for i, test := range tests {
+		dir, err := ioutil.TempDir("", "mptcp")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		// Only create tables which are present for this test
+		tables := []tcpTable{
+			{path: filepath.Join(dir, "tcp"), flag: TCPTableIPv4},
+			{path: filepath.Join(dir, "tcp6"), flag: TCPTableIPv6},
+		}
+		for j, content := range []string{test.tcp, test.tcp6} {
+			if content == "" {
+				continue
+			}
+
+			if err := ioutil.WriteFile(tables[j].path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		result, err := checkTCPTablesLinux(tables, test.host, test.port)
+		if (err != nil) != test.err {
+			t.Fatalf("[%02d] unexpected err: %v [test: %v]", i, err, test)
+		}
+
+		if result != test.result {
+			t.Fatalf("[%02d] unexpected result: %v != %v [test: %v]", i, result, test.result, test)
+		}
+	}
+}
+
+// TestLinux_tcpTableReaderLinux verifies that tcpTableReaderLinux can properly
+// parse a Linux TCP connections table for entries.
+func TestLinux_tcpTableReaderLinux(t *testing.T) {
+	var tests = []struct {
+		table string
+		entry string
+		ok    bool
+		err   error
+	}{
+		// Empty file
+		{"", "", false, io.ErrUnexpectedEOF},
+		// Header only, no entries
+		{testTCPTableHeader + "\n", "1134B018:BBE8", false, nil},
+		// Header, bad entry
+		{testTCPTableHeader + "\nfoobar\n", "1134B018:BBE8", false, errInvalidTCPEntry},
+		// Header, local address does not match
+		{testTCPTableHeader + "\n" + testTCPEntry + "\n", "E70E8368:0016", false, nil},
+		// Header, good entry
+		{testTCPTableHeader + "\n" + testTCPEntry + "\n", "1134B018:BBE8", true, nil},
+	}
+
+	for i, test := range tests {
+		ok, err := tcpTableReaderLinux(strings.NewReader(test.table), test.entry)
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected err: %v != %v [test: %v]", i, err, test.err, test)
+		}
+
+		if ok != test.ok {
+			t.Fatalf("[%02d] unexpected ok: %v != %v [test: %v]", i, ok, test.ok, test)
+		}
+	}
+}
@@ -0,0 +1,381 @@
+// +build linux
+
+package mptcp
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	// solMPTCP is the socket option level for MPTCP socket options,
+	// mptcpInfoOpt is the socket option which retrieves struct mptcp_info,
+	// and mptcpTCPInfoOpt is the socket option which retrieves the struct
+	// tcp_info of each subflow.
+	solMPTCP        = 284
+	mptcpInfoOpt    = 1
+	mptcpTCPInfoOpt = 2
+
+	// mptcpInfoFlagFallback is the flag of struct mptcp_info which indicates
+	// that a connection fell back to regular TCP.
+	mptcpInfoFlagFallback = 1 << 0
+
+	// mptcpInfo*Offset are the offsets of additional fields within the
+	// kernel's struct mptcp_info, and mptcpInfoLen is the size of the buffer
+	// used to retrieve it.  The kernel truncates the structure to fit.
+	mptcpInfoFlagsOffset         = 8
+	mptcpInfoBytesSentOffset     = 56
+	mptcpInfoBytesReceivedOffset = 64
+	mptcpInfoLen                 = 128
+
+	// mptcpSubflowDataLen is the length of the kernel's struct
+	// mptcp_subflow_data, which precedes the subflow information reported
+	// by mptcpTCPInfoOpt.
+	mptcpSubflowDataLen = 16
+
+	// tcpInfo*Offset are the offsets of fields within the kernel's struct
+	// tcp_info, and tcpInfoLen is the number of bytes requested for each
+	// subflow.  The kernel truncates the structure to fit.
+	tcpInfoBytesAckedOffset    = 120
+	tcpInfoBytesReceivedOffset = 128
+	tcpInfoBytesSentOffset     = 200
+	tcpInfoLen                 = 232
+
+	// infoMaxSubflows is the number of subflows for which space is
+	// initially allocated when retrieving subflow information.
+	infoMaxSubflows = 8
+)
+
+var (
+	// errNotSocket is returned when a file descriptor does not refer to
+	// a socket.
+	errNotSocket = errors.New("file descriptor is not a socket")
+)
+
+// isFDMPTCP determines if the socket with the input file descriptor is using
+// MPTCP.  Sockets created using the mainline kernel's MPTCP protocol are
+// queried directly, and other sockets are looked up in the out-of-tree
+// kernel's MPTCP connections table using their inode.
+var isFDMPTCP = func(fd int) (bool, error) {
+	proto, err := fdProtocol(fd)
+	if err != nil {
+		return false, err
+	}
+
+	if proto == ipprotoMPTCP {
+		// Kernels without MPTCP socket options cannot report whether a
+		// socket has fallen back to regular TCP
+		if !hasSOLMPTCP() {
+			return true, nil
+		}
+
+		return !isFallbackErr(fdMPTCPInfo(fd)), nil
+	}
+
+	inode, err := fdInode(fd)
+	if err != nil {
+		return false, err
+	}
+
+	// Open Linux MPTCP table, which is only present on the out-of-tree
+	// kernel, and is the only way regular TCP sockets can use MPTCP
+	mptcpFile, err := defaultChecker.openTable()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+
+		return false, err
+	}
+	defer mptcpFile.Close()
+
+	return mptcpInodeReaderLinux(mptcpFile, inode)
+}
+
+var (
+	// solMPTCPOnce and solMPTCPSupported cache the result of hasSOLMPTCP.
+	solMPTCPOnce      sync.Once
+	solMPTCPSupported bool
+)
+
+// hasSOLMPTCP reports whether the running kernel supports MPTCP socket
+// options, which were added in Linux 5.16.  Earlier kernels report
+// EOPNOTSUPP for every MPTCP socket option, including on sockets which are
+// using MPTCP.
+//
+// This implementation is swappable for testing with a mock data source.
+var hasSOLMPTCP = func() bool {
+	solMPTCPOnce.Do(func() {
+		var uts syscall.Utsname
+		if err := syscall.Uname(&uts); err != nil {
+			return
+		}
+
+		release := make([]byte, 0, len(uts.Release))
+		for _, c := range uts.Release {
+			if c == 0 {
+				break
+			}
+			release = append(release, byte(c))
+		}
+
+		major, minor, ok := parseKernelRelease(string(release))
+		solMPTCPSupported = ok && (major > 5 || (major == 5 && minor >= 16))
+	})
+
+	return solMPTCPSupported
+}
+
+// parseKernelRelease parses the major and minor version of the kernel from
+// its release string, such as "6.1.0-13-amd64".
+func parseKernelRelease(release string) (major, minor int, ok bool) {
+	parts := strings.SplitN(release, ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+
+	// The minor version may be followed by a suffix, such as "-rc1"
+	end := 0
+	for end < len(parts[1]) && parts[1][end] >= '0' && parts[1][end] <= '9' {
+		end++
+	}
+
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	minor, err = strconv.Atoi(parts[1][:end])
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return major, minor, true
+}
+
+// isFallbackErr reports whether the input error, returned by an MPTCP socket
+// option on a kernel which supports them, indicates that the socket has
+// fallen back to regular TCP.  The kernel reports EOPNOTSUPP for IPv4 sockets
+// and ENOPROTOOPT for IPv6 sockets.
+func isFallbackErr(err error) bool {
+	return err == syscall.EOPNOTSUPP || err == syscall.ENOPROTOOPT
+}
+
+// fdProtocol returns the protocol of the socket with the input file
+// descriptor.
+//
+// This implementation is swappable for testing with a mock data source.
+var fdProtocol = func(fd int) (int, error) {
+	proto, err := syscall.GetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_PROTOCOL)
+	if err != nil {
+		return 0, os.NewSyscallError("getsockopt", err)
+	}
+
+	return proto, nil
+}
+
+// fdMPTCPInfo attempts to retrieve MPTCP information from the socket with the
+// input file descriptor, returning the raw error from the kernel on failure.
+//
+// This implementation is swappable for testing with a mock data source.
+var fdMPTCPInfo = func(fd int) error {
+	// Only the success or failure of the call is needed, so a truncated
+	// result is acceptable
+	_, err := syscall.GetsockoptInt(fd, solMPTCP, mptcpInfoOpt)
+	return err
+}
+
+// fdInfo retrieves detailed information about the MPTCP socket with the input
+// file descriptor, using the MPTCP_INFO and MPTCP_TCPINFO socket options.
+var fdInfo = func(fd int) (*ConnInfo, error) {
+	proto, err := fdProtocol(fd)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only sockets created using the mainline kernel's MPTCP protocol
+	// support MPTCP socket options, and only on kernels which implement them
+	if proto != ipprotoMPTCP || !hasSOLMPTCP() {
+		return nil, ErrNoInfo
+	}
+
+	b := make([]byte, mptcpInfoLen)
+	n, err := fdGetsockopt(fd, solMPTCP, mptcpInfoOpt, b)
+	if err != nil {
+		// An MPTCP socket which has fallen back to regular TCP no longer
+		// reports MPTCP information
+		if isFallbackErr(err) {
+			return &ConnInfo{Fallback: true}, nil
+		}
+
+		return nil, os.NewSyscallError("getsockopt", err)
+	}
+
+	info := parseMPTCPInfoSockopt(b[:n])
+	if info.Fallback {
+		return info, nil
+	}
+
+	subflows, err := fdSubflowInfo(fd)
+	if err != nil {
+		return nil, err
+	}
+	info.Subflows = subflows
+
+	return info, nil
+}
+
+// fdSubflowInfo retrieves information about each subflow of the MPTCP socket
+// with the input file descriptor, using the MPTCP_TCPINFO socket option.
+func fdSubflowInfo(fd int) ([]SubflowInfo, error) {
+	count := infoMaxSubflows
+	for {
+		b := make([]byte, mptcpSubflowDataLen+count*tcpInfoLen)
+
+		// The kernel reads the lengths of struct mptcp_subflow_data and
+		// of each struct tcp_info from the buffer
+		binary.NativeEndian.PutUint32(b[0:4], mptcpSubflowDataLen)
+		binary.NativeEndian.PutUint32(b[12:16], tcpInfoLen)
+
+		if _, err := fdGetsockopt(fd, solMPTCP, mptcpTCPInfoOpt, b); err != nil {
+			return nil, os.NewSyscallError("getsockopt", err)
+		}
+
+		subflows, total, ok := parseSubflowInfoSockopt(b, count)
+		if ok {
+			return subflows, nil
+		}
+
+		// Subflows were added since the previous attempt, so try again
+		// with enough space for all of them
+		count = total
+	}
+}
+
+// parseMPTCPInfoSockopt parses the kernel's struct mptcp_info, as reported by
+// the MPTCP_INFO socket option, into a ConnInfo.  Older kernels report a
+// shorter structure, so fields which are not present are left unset.
+func parseMPTCPInfoSockopt(b []byte) *ConnInfo {
+	u64 := func(off int) uint64 {
+		if len(b) < off+8 {
+			return 0
+		}
+
+		return binary.NativeEndian.Uint64(b[off : off+8])
+	}
+
+	var flags uint32
+	if len(b) >= mptcpInfoFlagsOffset+4 {
+		flags = binary.NativeEndian.Uint32(b[mptcpInfoFlagsOffset : mptcpInfoFlagsOffset+4])
+	}
+	if flags&mptcpInfoFlagFallback != 0 {
+		return &ConnInfo{Fallback: true}
+	}
+
+	// Reuse the parsing of struct mptcp_info reported by sock_diag
+	var d ConnectionDetail
+	parseMPTCPInfo(b, &d)
+
+	return &ConnInfo{
+		LocalToken:    d.LocalToken,
+		BytesSent:     u64(mptcpInfoBytesSentOffset),
+		BytesReceived: u64(mptcpInfoBytesReceivedOffset),
+		BytesAcked:    d.BytesAcked,
+	}
+}
+
+// parseSubflowInfoSockopt parses the kernel's struct mptcp_subflow_data and
+// the struct tcp_info of each subflow which follows it, as reported by the
+// MPTCP_TCPINFO socket option in a buffer with space for count subflows.  If
+// the connection has more subflows than would fit, ok is false and total is
+// the number of subflows of the connection.
+func parseSubflowInfoSockopt(b []byte, count int) (subflows []SubflowInfo, total int, ok bool) {
+	if len(b) < mptcpSubflowDataLen {
+		return nil, 0, true
+	}
+
+	total = int(binary.NativeEndian.Uint32(b[4:8]))
+	if total > count {
+		return nil, total, false
+	}
+
+	// The kernel reports the number of bytes it copied for each subflow,
+	// which may be fewer than were requested
+	size := int(binary.NativeEndian.Uint32(b[12:16]))
+	if size <= 0 {
+		return nil, total, true
+	}
+
+	u64 := func(sb []byte, off int) uint64 {
+		if len(sb) < off+8 {
+			return 0
+		}
+
+		return binary.NativeEndian.Uint64(sb[off : off+8])
+	}
+
+	subflows = make([]SubflowInfo, 0, total)
+	for i := 0; i < total; i++ {
+		start := mptcpSubflowDataLen + i*size
+		if start+size > len(b) {
+			break
+		}
+
+		sb := b[start : start+size]
+		subflows = append(subflows, SubflowInfo{
+			BytesSent:     u64(sb, tcpInfoBytesSentOffset),
+			BytesReceived: u64(sb, tcpInfoBytesReceivedOffset),
+			BytesAcked:    u64(sb, tcpInfoBytesAckedOffset),
+		})
+	}
+
+	return subflows, total, true
+}
+
+// fdGetsockopt retrieves the value of a socket option from the socket with
+// the input file descriptor into b, returning the number of bytes written by
+// the kernel and the raw error from the kernel on failure.
+//
+// This implementation is swappable for testing with a mock data source.
+var fdGetsockopt = func(fd, level, opt int, b []byte) (int, error) {
+	l := uint32(len(b))
+	if errno := getsockopt(fd, level, opt, unsafe.Pointer(&b[0]), &l); errno != 0 {
+		return 0, errno
+	}
+
+	return int(l), nil
+}
+
+// fdInode returns the inode of the socket with the input file descriptor,
+// using the Linux /proc filesystem.
+//
+// This implementation is swappable for testing with a mock data source.
+var fdInode = func(fd int) (uint64, error) {
+	link, err := os.Readlink("/proc/self/fd/" + strconv.Itoa(fd))
+	if err != nil {
+		return 0, err
+	}
+
+	return parseSocketInode(link)
+}
+
+// parseSocketInode parses the inode of a socket from the target of its
+// /proc/self/fd symlink, which is in the form "socket:[inode]".
+func parseSocketInode(link string) (uint64, error) {
+	const prefix, suffix = "socket:[", "]"
+	if !strings.HasPrefix(link, prefix) || !strings.HasSuffix(link, suffix) {
+		return 0, errNotSocket
+	}
+
+	inode, err := strconv.ParseUint(link[len(prefix):len(link)-len(suffix)], 10, 64)
+	if err != nil {
+		return 0, errNotSocket
+	}
+
+	return inode, nil
+}
@@ -0,0 +1,449 @@
+// +build linux
+
+package mptcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"reflect"
+	"syscall"
+	"testing"
+)
+
+// TestLinux_isFDMPTCP verifies that isFDMPTCP detects MPTCP sockets using
+// socket options for mainline kernel MPTCP sockets, and using mocked
+// fd-to-inode resolution for other sockets.
+func TestLinux_isFDMPTCP(t *testing.T) {
+	origProtocol, origInfo, origInode, origOpen, origSOL := fdProtocol, fdMPTCPInfo, fdInode, openRawTable, hasSOLMPTCP
+	defer func() {
+		fdProtocol, fdMPTCPInfo, fdInode, openRawTable, hasSOLMPTCP = origProtocol, origInfo, origInode, origOpen, origSOL
+	}()
+
+	// The IPv4 test entry has inode 15666
+	table := testLargeMPTCPTable(1)
+	errInode := errors.New("inode resolution failed")
+
+	var tests = []struct {
+		desc      string
+		proto     int
+		oldKernel bool
+		infoErr   error
+		inode     uint64
+		inoErr    error
+		noTable   bool
+		ok        bool
+		err       error
+	}{
+		{desc: "MPTCP socket", proto: ipprotoMPTCP, ok: true},
+		{desc: "IPv4 MPTCP socket fallen back to TCP", proto: ipprotoMPTCP, infoErr: syscall.EOPNOTSUPP},
+		{desc: "IPv6 MPTCP socket fallen back to TCP", proto: ipprotoMPTCP, infoErr: syscall.ENOPROTOOPT},
+		{desc: "MPTCP socket, unexpected error", proto: ipprotoMPTCP, infoErr: syscall.EINVAL, ok: true},
+		{desc: "MPTCP socket on kernel without socket options", proto: ipprotoMPTCP, oldKernel: true, infoErr: syscall.EOPNOTSUPP, ok: true},
+		{desc: "TCP socket in table", proto: syscall.IPPROTO_TCP, inode: 15666, ok: true},
+		{desc: "TCP socket not in table", proto: syscall.IPPROTO_TCP, inode: 15667},
+		{desc: "TCP socket without table", proto: syscall.IPPROTO_TCP, inode: 15666, noTable: true},
+		{desc: "inode resolution error", proto: syscall.IPPROTO_TCP, inoErr: errInode, err: errInode},
+	}
+
+	for i, test := range tests {
+		fdProtocol = func(fd int) (int, error) {
+			return test.proto, nil
+		}
+		fdMPTCPInfo = func(fd int) error {
+			return test.infoErr
+		}
+		hasSOLMPTCP = func() bool {
+			return !test.oldKernel
+		}
+		fdInode = func(fd int) (uint64, error) {
+			return test.inode, test.inoErr
+		}
+		openRawTable = func() (io.ReadCloser, error) {
+			if test.noTable {
+				return nil, &os.PathError{Op: "open", Path: procMPTCP, Err: os.ErrNotExist}
+			}
+
+			return ioutil.NopCloser(bytes.NewReader(table)), nil
+		}
+
+		ok, err := isFDMPTCP(3)
+		if err != test.err {
+			t.Fatalf("[%02d] %s: unexpected err: %v != %v", i, test.desc, err, test.err)
+		}
+
+		if ok != test.ok {
+			t.Fatalf("[%02d] %s: unexpected ok: %v != %v", i, test.desc, ok, test.ok)
+		}
+	}
+}
+
+// TestLinux_IsMPTCPFallback verifies that IsMPTCP agrees with the standard
+// library for IPv4 and IPv6 MPTCP connections which fell back to regular TCP,
+// because the peer does not support multipath TCP.
+func TestLinux_IsMPTCPFallback(t *testing.T) {
+	for i, addr := range []string{"127.0.0.1:0", "[::1]:0"} {
+		network := "tcp"
+		l, err := net.Listen(network, addr)
+		if err != nil {
+			t.Logf("[%02d] skipping %s: %v", i, addr, err)
+			continue
+		}
+		defer l.Close()
+
+		go func() {
+			c, err := l.Accept()
+			if err == nil {
+				defer c.Close()
+				_, _ = io.Copy(ioutil.Discard, c)
+			}
+		}()
+
+		c, err := Dial(network, l.Addr().String())
+		if err != nil {
+			t.Fatalf("[%02d] failed to dial %s: %v", i, addr, err)
+		}
+		defer c.Close()
+
+		want, err := c.(*net.TCPConn).MultipathTCP()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ok, err := IsMPTCP(c)
+		if err != nil {
+			t.Fatalf("[%02d] unexpected err for %s: %v", i, addr, err)
+		}
+
+		if ok != want {
+			t.Fatalf("[%02d] unexpected ok for %s: %v != %v", i, addr, ok, want)
+		}
+	}
+}
+
+// TestLinux_parseKernelRelease verifies that parseKernelRelease parses the
+// major and minor version from kernel release strings.
+func TestLinux_parseKernelRelease(t *testing.T) {
+	var tests = []struct {
+		release      string
+		major, minor int
+		ok           bool
+	}{
+		{"5.15.0-91-generic", 5, 15, true},
+		{"5.16.0", 5, 16, true},
+		{"6.18.44-fc-v130", 6, 18, true},
+		{"6.8-rc1", 6, 8, true},
+		{"6", 0, 0, false},
+		{"foo.bar", 0, 0, false},
+		{"", 0, 0, false},
+	}
+
+	for i, test := range tests {
+		major, minor, ok := parseKernelRelease(test.release)
+		if major != test.major || minor != test.minor || ok != test.ok {
+			t.Fatalf("[%02d] unexpected version for %q: (%d, %d, %v) != (%d, %d, %v)",
+				i, test.release, major, minor, ok, test.major, test.minor, test.ok)
+		}
+	}
+}
+
+// TestLinux_parseSocketInode verifies that parseSocketInode parses socket
+// inodes from /proc/self/fd symlink targets.
+func TestLinux_parseSocketInode(t *testing.T) {
+	var tests = []struct {
+		link  string
+		inode uint64
+		err   error
+	}{
+		{"socket:[15666]", 15666, nil},
+		{"socket:[0]", 0, nil},
+		{"/dev/null", 0, errNotSocket},
+		{"pipe:[15666]", 0, errNotSocket},
+		{"socket:[foo]", 0, errNotSocket},
+		{"socket:[15666", 0, errNotSocket},
+	}
+
+	for i, test := range tests {
+		inode, err := parseSocketInode(test.link)
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected err: %v != %v [test: %v]", i, err, test.err, test)
+		}
+
+		if inode != test.inode {
+			t.Fatalf("[%02d] unexpected inode: %v != %v [test: %v]", i, inode, test.inode, test)
+		}
+	}
+}
+
+// TestLinux_IsMPTCP verifies that IsMPTCP agrees with the standard library
+// for connections created by Dial and Listen, and reports connections
+// created without multipath TCP as regular TCP.
+func TestLinux_IsMPTCP(t *testing.T) {
+	client, server := testDialPair(t, &Dialer{})
+
+	for i, c := range []net.Conn{client, server} {
+		want, err := c.(*net.TCPConn).MultipathTCP()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ok, err := IsMPTCP(c)
+		if err != nil {
+			t.Fatalf("[%02d] unexpected err: %v", i, err)
+		}
+
+		if ok != want {
+			t.Fatalf("[%02d] unexpected ok: %v != %v", i, ok, want)
+		}
+	}
+
+	// Regular TCP connections are never MPTCP on mainline kernels
+	if _, err := os.Stat(procMPTCP); err == nil {
+		t.Skipf("%s exists, so regular TCP connections may use MPTCP", procMPTCP)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	c, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	ok, err := IsMPTCP(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("regular TCP connection reported as MPTCP")
+	}
+}
+
+// TestLinux_fdInfo verifies that fdInfo only queries MPTCP sockets, and
+// reports fallen back sockets without querying their subflows.
+func TestLinux_fdInfo(t *testing.T) {
+	origProtocol, origGetsockopt, origSOL := fdProtocol, fdGetsockopt, hasSOLMPTCP
+	defer func() { fdProtocol, fdGetsockopt, hasSOLMPTCP = origProtocol, origGetsockopt, origSOL }()
+
+	var tests = []struct {
+		desc      string
+		proto     int
+		oldKernel bool
+		flags     uint32
+		err       error
+		info      *ConnInfo
+		wErr      error
+	}{
+		{desc: "TCP socket", proto: syscall.IPPROTO_TCP, wErr: ErrNoInfo},
+		{desc: "kernel without socket options", proto: ipprotoMPTCP, oldKernel: true, err: syscall.EOPNOTSUPP, wErr: ErrNoInfo},
+		{desc: "IPv4 fallen back socket", proto: ipprotoMPTCP, err: syscall.EOPNOTSUPP, info: &ConnInfo{Fallback: true}},
+		{desc: "IPv6 fallen back socket", proto: ipprotoMPTCP, err: syscall.ENOPROTOOPT, info: &ConnInfo{Fallback: true}},
+		{desc: "fallback flag", proto: ipprotoMPTCP, flags: mptcpInfoFlagFallback, info: &ConnInfo{Fallback: true}},
+		{
+			desc:  "MPTCP socket",
+			proto: ipprotoMPTCP,
+			info: &ConnInfo{
+				LocalToken: 0x01020304,
+				BytesSent:  100,
+				Subflows:   []SubflowInfo{{BytesSent: 200}},
+			},
+		},
+	}
+
+	for i, test := range tests {
+		fdProtocol = func(fd int) (int, error) {
+			return test.proto, nil
+		}
+		hasSOLMPTCP = func() bool {
+			return !test.oldKernel
+		}
+		fdGetsockopt = func(fd, level, opt int, b []byte) (int, error) {
+			if test.err != nil {
+				return 0, test.err
+			}
+
+			switch opt {
+			case mptcpInfoOpt:
+				binary.NativeEndian.PutUint32(b[mptcpInfoFlagsOffset:], test.flags)
+				binary.NativeEndian.PutUint32(b[mptcpInfoTokenOffset:], 0x01020304)
+				binary.NativeEndian.PutUint64(b[mptcpInfoBytesSentOffset:], 100)
+				return mptcpInfoLen, nil
+			case mptcpTCPInfoOpt:
+				binary.NativeEndian.PutUint32(b[4:8], 1)
+				binary.NativeEndian.PutUint64(b[mptcpSubflowDataLen+tcpInfoBytesSentOffset:], 200)
+				return len(b), nil
+			}
+
+			return 0, syscall.ENOPROTOOPT
+		}
+
+		info, err := fdInfo(3)
+		if err != test.wErr {
+			t.Fatalf("[%02d] %s: unexpected err: %v != %v", i, test.desc, err, test.wErr)
+		}
+
+		if !reflect.DeepEqual(info, test.info) {
+			t.Fatalf("[%02d] %s: unexpected info: %#v != %#v", i, test.desc, info, test.info)
+		}
+	}
+}
+
+// TestLinux_parseSubflowInfoSockopt verifies that parseSubflowInfoSockopt
+// parses the information of each subflow, using the size of struct tcp_info
+// reported by the kernel, and detects buffers which are too small.
+func TestLinux_parseSubflowInfoSockopt(t *testing.T) {
+	// buf builds a buffer with the input number of subflows and per-subflow
+	// size, in which each subflow sent its index plus one bytes
+	buf := func(total, count, size int) []byte {
+		b := make([]byte, mptcpSubflowDataLen+count*size)
+		binary.NativeEndian.PutUint32(b[0:4], mptcpSubflowDataLen)
+		binary.NativeEndian.PutUint32(b[4:8], uint32(total))
+		binary.NativeEndian.PutUint32(b[12:16], uint32(size))
+
+		for i := 0; i < count && i < total; i++ {
+			off := mptcpSubflowDataLen + i*size + tcpInfoBytesSentOffset
+			if off+8 <= len(b) {
+				binary.NativeEndian.PutUint64(b[off:], uint64(i+1))
+			}
+		}
+
+		return b
+	}
+
+	var tests = []struct {
+		desc     string
+		b        []byte
+		count    int
+		subflows []SubflowInfo
+		total    int
+		ok       bool
+	}{
+		{
+			desc:  "short buffer",
+			b:     make([]byte, 4),
+			count: 1,
+			ok:    true,
+		},
+		{
+			desc:     "two subflows",
+			b:        buf(2, 2, tcpInfoLen),
+			count:    2,
+			subflows: []SubflowInfo{{BytesSent: 1}, {BytesSent: 2}},
+			total:    2,
+			ok:       true,
+		},
+		{
+			desc:     "short struct tcp_info",
+			b:        buf(1, 1, tcpInfoBytesSentOffset),
+			count:    1,
+			subflows: []SubflowInfo{{}},
+			total:    1,
+			ok:       true,
+		},
+		{
+			desc:  "too many subflows",
+			b:     buf(3, 2, tcpInfoLen),
+			count: 2,
+			total: 3,
+		},
+	}
+
+	for i, test := range tests {
+		subflows, total, ok := parseSubflowInfoSockopt(test.b, test.count)
+		if ok != test.ok {
+			t.Fatalf("[%02d] %s: unexpected ok: %v != %v", i, test.desc, ok, test.ok)
+		}
+
+		if total != test.total {
+			t.Fatalf("[%02d] %s: unexpected total: %v != %v", i, test.desc, total, test.total)
+		}
+
+		if !reflect.DeepEqual(subflows, test.subflows) {
+			t.Fatalf("[%02d] %s: unexpected subflows: %#v != %#v", i, test.desc, subflows, test.subflows)
+		}
+	}
+}
+
+// TestLinux_Info verifies that Info reports the token and byte counts of a
+// real loopback MPTCP connection.
+func TestLinux_Info(t *testing.T) {
+	client, server := testDialPair(t, &Dialer{})
+
+	if ok, err := client.(*net.TCPConn).MultipathTCP(); err != nil || !ok {
+		t.Skip("skipping, connection does not use MPTCP")
+	}
+
+	const n = 1000
+	if _, err := client.Write(make([]byte, n)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadFull(server, make([]byte, n)); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := Info(client)
+	if err == ErrNoInfo || (err != nil && errors.Is(err, syscall.EOPNOTSUPP)) {
+		t.Skipf("skipping, kernel does not report MPTCP information: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("failed to retrieve info: %v", err)
+	}
+
+	if info.Fallback {
+		t.Fatal("loopback connection unexpectedly fell back to TCP")
+	}
+	if info.LocalToken == 0 {
+		t.Fatal("connection has no local token")
+	}
+	if len(info.Subflows) == 0 {
+		t.Fatal("connection has no subflows")
+	}
+
+	var sent uint64
+	for _, sf := range info.Subflows {
+		sent += sf.BytesSent
+	}
+	if sent < n {
+		t.Fatalf("subflows sent too few bytes: %d < %d", sent, n)
+	}
+}
+
+// TestLinux_CheckConnOldKernel verifies that CheckConn checks the connections
+// table for MPTCP connections on kernels without MPTCP socket options, rather
+// than reporting them as fallen back.
+func TestLinux_CheckConnOldKernel(t *testing.T) {
+	client, _ := testDialPair(t, &Dialer{})
+
+	origSOL, origInfo, origCheck := hasSOLMPTCP, fdGetsockopt, checkMPTCP
+	defer func() { hasSOLMPTCP, fdGetsockopt, checkMPTCP = origSOL, origInfo, origCheck }()
+
+	// Kernels before 5.16 report EOPNOTSUPP for every MPTCP socket option
+	hasSOLMPTCP = func() bool { return false }
+	fdGetsockopt = func(fd, level, opt int, b []byte) (int, error) {
+		return 0, syscall.EOPNOTSUPP
+	}
+
+	var checked bool
+	checkMPTCP = func(ctx context.Context, host string, port uint16) (bool, error) {
+		checked = true
+		return true, nil
+	}
+
+	ok, err := CheckConn(client)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	if !ok || !checked {
+		t.Fatalf("connection not checked using table: (ok: %v, checked: %v)", ok, checked)
+	}
+}
@@ -0,0 +1,52 @@
+package mptcp
+
+import (
+	"sync"
+)
+
+// A flightGroup coalesces concurrent identical queries, so that callers
+// which perform the same query at the same time share a single result.
+//
+// The zero value of a flightGroup is ready to use.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// A flightCall is a query which is in progress, or has completed.
+type flightCall struct {
+	wg  sync.WaitGroup
+	ok  bool
+	err error
+}
+
+// do invokes fn and returns its results, unless a query with the same key is
+// already in progress, in which case do waits for that query to complete and
+// returns its results instead.
+func (g *flightGroup) do(key string, fn func() (bool, error)) (bool, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.ok, c.err
+	}
+
+	c := new(flightCall)
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	c.ok, c.err = fn()
+	c.wg.Done()
+
+	// Later queries with the same key must perform a new query
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+
+	return c.ok, c.err
+}
@@ -0,0 +1,76 @@
+package mptcp
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestFlightGroupCoalesces verifies that concurrent identical queries to a
+// flightGroup share a single call and its result, and that later queries
+// perform a new call.
+func TestFlightGroupCoalesces(t *testing.T) {
+	const n = 64
+
+	var (
+		g       flightGroup
+		calls   int32
+		release = make(chan struct{})
+		errTest = errors.New("shared error")
+	)
+
+	fn := func() (bool, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return true, errTest
+	}
+
+	var started, done sync.WaitGroup
+	started.Add(n)
+	done.Add(n)
+
+	results := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer done.Done()
+			started.Done()
+
+			ok, err := g.do("hot", fn)
+			if !ok {
+				err = errors.New("unexpected false result")
+			}
+			results <- err
+		}()
+	}
+
+	// Allow every goroutine to join the in-progress call before releasing it
+	started.Wait()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	done.Wait()
+	close(results)
+
+	for err := range results {
+		if err != errTest {
+			t.Fatalf("unexpected err: %v != %v", err, errTest)
+		}
+	}
+
+	if c := atomic.LoadInt32(&calls); c != 1 {
+		t.Fatalf("unexpected call count: %v != %v", c, 1)
+	}
+
+	// Once complete, an identical query performs a new call, and a query
+	// with a different key is never coalesced
+	for _, key := range []string{"hot", "cold"} {
+		if _, err := g.do(key, fn); err != errTest {
+			t.Fatalf("unexpected err: %v != %v", err, errTest)
+		}
+	}
+
+	if c := atomic.LoadInt32(&calls); c != 3 {
+		t.Fatalf("unexpected call count: %v != %v", c, 3)
+	}
+}
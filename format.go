@@ -0,0 +1,83 @@
+package mptcp
+
+import "io"
+
+// A FormatVersion identifies a known layout of a multipath TCP connections
+// table.
+type FormatVersion int
+
+const (
+	// FormatUnknown indicates that a connections table is not in any
+	// known layout.
+	FormatUnknown FormatVersion = iota
+
+	// FormatV1 is the ten column layout of the /proc/net/mptcp
+	// connections table written by the out-of-tree Linux MPTCP kernel.
+	FormatV1
+
+	// FormatV2 is the extended layout of the /proc/net/mptcp connections
+	// table, which appends the tr:tm->when timer and retrnsmt columns of
+	// the kernel's TCP table to each row of FormatV1.
+	FormatV2
+)
+
+// String returns the name of a FormatVersion.
+func (v FormatVersion) String() string {
+	switch v {
+	case FormatV1:
+		return "v1"
+	case FormatV2:
+		return "v2"
+	default:
+		return "unknown"
+	}
+}
+
+// validateSampleRows is the maximum number of rows inspected by
+// ValidateTable after the header.
+const validateSampleRows = 100
+
+// FormatInfo describes the format of a captured connections table, as
+// detected by ValidateTable.
+type FormatInfo struct {
+	// Version is the detected layout of the table.
+	Version FormatVersion
+
+	// Columns is the number of columns expected in each row of the table.
+	Columns int
+
+	// Rows is the number of rows which were inspected after the header.
+	Rows int
+
+	// Anomalies describes each problem found in the inspected rows, such
+	// as a row with the wrong number of columns or a field which cannot be
+	// decoded.  A table with no anomalies may still contain problems in
+	// rows beyond those which were inspected.
+	Anomalies []string
+}
+
+// ValidateTable reads the header and a sample of rows from a captured
+// connections table, such as one streamed from OpenRawTable, and reports
+// whether they match a layout understood by the current operating system's
+// parser.  Rows are checked without being collected into connections, so
+// ValidateTable may be used on large captures.
+//
+// If the header does not match a known layout, the returned FormatInfo has
+// Version FormatUnknown and an error is returned.  Problems with individual
+// rows are reported as anomalies rather than errors.
+//
+// If multipath TCP detection is not implemented for the current operating system,
+// this function will return ErrNotImplemented.
+func ValidateTable(r io.Reader) (FormatInfo, error) {
+	return validateTable(r)
+}
+
+// HeaderForFormat returns the exact header line, without a trailing newline,
+// which begins a connections table in the input format.  The returned bytes
+// may be modified by the caller.
+//
+// If the format is not known, or the current operating system has no
+// connections table, nil is returned.
+func HeaderForFormat(v FormatVersion) []byte {
+	return headerForFormat(v)
+}
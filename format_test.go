@@ -0,0 +1,23 @@
+package mptcp
+
+import "testing"
+
+// TestFormatVersionString verifies that FormatVersion.String returns the name
+// of each known format version.
+func TestFormatVersionString(t *testing.T) {
+	var tests = []struct {
+		v FormatVersion
+		s string
+	}{
+		{FormatUnknown, "unknown"},
+		{FormatV1, "v1"},
+		{FormatV2, "v2"},
+		{FormatVersion(99), "unknown"},
+	}
+
+	for i, test := range tests {
+		if s := test.v.String(); s != test.s {
+			t.Fatalf("[%02d] unexpected string: %q != %q", i, s, test.s)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+// +build linux
+
+package mptcp
+
+import (
+	"encoding/binary"
+	"strings"
+	"syscall"
+)
+
+const (
+	// netlinkGeneric is the netlink family used for generic netlink.
+	netlinkGeneric = 16
+
+	// genlHeaderLen is the length of a generic netlink message header.
+	genlHeaderLen = 4
+
+	// genlIDCtrl is the generic netlink family ID of the controller, which
+	// resolves the names of other generic netlink families.
+	genlIDCtrl = 0x10
+
+	// ctrlCmdGetFamily is the controller command which retrieves
+	// information about a generic netlink family.
+	ctrlCmdGetFamily = 3
+
+	// ctrlAttr* are controller attributes which describe a generic netlink
+	// family.
+	ctrlAttrFamilyID    = 1
+	ctrlAttrFamilyName  = 2
+	ctrlAttrMcastGroups = 7
+
+	// ctrlAttrMcastGrp* are the attributes which describe each multicast
+	// group of a generic netlink family.
+	ctrlAttrMcastGrpName = 1
+	ctrlAttrMcastGrpID   = 2
+
+	// mptcpPMFamilyName is the name of the generic netlink family used by
+	// the mainline Linux MPTCP path manager.
+	mptcpPMFamilyName = "mptcp_pm"
+)
+
+// genericMessage creates the data of a generic netlink message with the
+// input command and attributes.
+func genericMessage(cmd uint8, attrs []netlinkAttribute) []byte {
+	b := make([]byte, genlHeaderLen)
+	b[0] = cmd
+	b[1] = 1
+
+	return append(b, marshalNetlinkAttributes(attrs)...)
+}
+
+// genericFamilyID resolves the ID of the generic netlink family with the
+// input name.  If the family does not exist, syscall.ENOENT is returned.
+func (c *netlinkConn) genericFamilyID(name string) (uint16, error) {
+	msgs, err := c.execute(genlIDCtrl, 0, genericMessage(ctrlCmdGetFamily, []netlinkAttribute{{
+		Type: ctrlAttrFamilyName,
+		Data: append([]byte(name), 0x00),
+	}}))
+	if err != nil {
+		return 0, err
+	}
+
+	if len(msgs) == 0 {
+		return 0, errInvalidNetlinkMessage
+	}
+
+	return parseGenericFamilyID(msgs[0].Data)
+}
+
+// parseGenericFamilyID parses a generic netlink family ID from the data of a
+// controller reply message.
+func parseGenericFamilyID(b []byte) (uint16, error) {
+	if len(b) < genlHeaderLen {
+		return 0, errInvalidNetlinkMessage
+	}
+
+	attrs, err := parseNetlinkAttributes(b[genlHeaderLen:])
+	if err != nil {
+		return 0, err
+	}
+
+	for _, a := range attrs {
+		if a.Type == ctrlAttrFamilyID && len(a.Data) == 2 {
+			return binary.NativeEndian.Uint16(a.Data), nil
+		}
+	}
+
+	return 0, errInvalidNetlinkMessage
+}
+
+// genericMulticastGroupID resolves the ID of the multicast group with the
+// input name, belonging to the generic netlink family with the input name.
+// If the family or group does not exist, syscall.ENOENT is returned.
+func (c *netlinkConn) genericMulticastGroupID(family, group string) (uint32, error) {
+	msgs, err := c.execute(genlIDCtrl, 0, genericMessage(ctrlCmdGetFamily, []netlinkAttribute{{
+		Type: ctrlAttrFamilyName,
+		Data: append([]byte(family), 0x00),
+	}}))
+	if err != nil {
+		return 0, err
+	}
+
+	if len(msgs) == 0 {
+		return 0, errInvalidNetlinkMessage
+	}
+
+	return parseGenericMulticastGroupID(msgs[0].Data, group)
+}
+
+// parseGenericMulticastGroupID parses the ID of the multicast group with the
+// input name from the data of a controller reply message.  If the group does
+// not exist, syscall.ENOENT is returned.
+func parseGenericMulticastGroupID(b []byte, group string) (uint32, error) {
+	if len(b) < genlHeaderLen {
+		return 0, errInvalidNetlinkMessage
+	}
+
+	attrs, err := parseNetlinkAttributes(b[genlHeaderLen:])
+	if err != nil {
+		return 0, err
+	}
+
+	for _, a := range attrs {
+		if a.Type != ctrlAttrMcastGroups {
+			continue
+		}
+
+		// Each group is a nested attribute containing its name and ID
+		groups, err := parseNetlinkAttributes(a.Data)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, g := range groups {
+			gattrs, err := parseNetlinkAttributes(g.Data)
+			if err != nil {
+				return 0, err
+			}
+
+			var (
+				name string
+				id   uint32
+			)
+			for _, ga := range gattrs {
+				switch {
+				case ga.Type == ctrlAttrMcastGrpName:
+					name = strings.TrimRight(string(ga.Data), "\x00")
+				case ga.Type == ctrlAttrMcastGrpID && len(ga.Data) == 4:
+					id = binary.NativeEndian.Uint32(ga.Data)
+				}
+			}
+
+			if name == group && id != 0 {
+				return id, nil
+			}
+		}
+	}
+
+	return 0, syscall.ENOENT
+}
+
+// genericFamilyExists determines if the generic netlink family with the input
+// name is registered with the kernel.
+func genericFamilyExists(name string) (bool, error) {
+	c, err := dialNetlink(netlinkGeneric)
+	if err != nil {
+		return false, err
+	}
+	defer c.Close()
+
+	_, err = c.genericFamilyID(name)
+	switch err {
+	case nil:
+		return true, nil
+	case syscall.ENOENT:
+		return false, nil
+	default:
+		return false, err
+	}
+}
@@ -0,0 +1,97 @@
+// +build linux
+
+package mptcp
+
+import (
+	"encoding/binary"
+	"syscall"
+	"testing"
+)
+
+// TestLinux_parseGenericFamilyID verifies that parseGenericFamilyID parses
+// the family ID from a controller reply, and rejects invalid replies.
+func TestLinux_parseGenericFamilyID(t *testing.T) {
+	id := make([]byte, 2)
+	binary.NativeEndian.PutUint16(id, 0x1c)
+
+	var tests = []struct {
+		desc string
+		b    []byte
+		id   uint16
+		err  error
+	}{
+		{"short header", []byte{0x01}, 0, errInvalidNetlinkMessage},
+		{"no family ID", genericMessage(1, []netlinkAttribute{{
+			Type: ctrlAttrFamilyName,
+			Data: []byte("mptcp_pm\x00"),
+		}}), 0, errInvalidNetlinkMessage},
+		{"family ID", genericMessage(1, []netlinkAttribute{
+			{Type: ctrlAttrFamilyName, Data: []byte("mptcp_pm\x00")},
+			{Type: ctrlAttrFamilyID, Data: id},
+		}), 0x1c, nil},
+	}
+
+	for i, test := range tests {
+		id, err := parseGenericFamilyID(test.b)
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected err: %v != %v [test: %v]", i, err, test.err, test.desc)
+		}
+
+		if id != test.id {
+			t.Fatalf("[%02d] unexpected ID: %v != %v [test: %v]", i, id, test.id, test.desc)
+		}
+	}
+}
+
+// TestLinux_parseGenericMulticastGroupID verifies that
+// parseGenericMulticastGroupID finds the ID of a named multicast group in a
+// controller reply.
+func TestLinux_parseGenericMulticastGroupID(t *testing.T) {
+	group := func(name string, id uint32) netlinkAttribute {
+		b := make([]byte, 4)
+		binary.NativeEndian.PutUint32(b, id)
+
+		return netlinkAttribute{
+			Type: 1,
+			Data: marshalNetlinkAttributes([]netlinkAttribute{
+				{Type: ctrlAttrMcastGrpID, Data: b},
+				{Type: ctrlAttrMcastGrpName, Data: append([]byte(name), 0x00)},
+			}),
+		}
+	}
+
+	var tests = []struct {
+		desc string
+		b    []byte
+		id   uint32
+		err  error
+	}{
+		{"short header", []byte{0x01}, 0, errInvalidNetlinkMessage},
+		{"no groups", genericMessage(1, []netlinkAttribute{{
+			Type: ctrlAttrFamilyName,
+			Data: []byte("mptcp_pm\x00"),
+		}}), 0, syscall.ENOENT},
+		{"other group", genericMessage(1, []netlinkAttribute{{
+			Type: ctrlAttrMcastGroups,
+			Data: marshalNetlinkAttributes([]netlinkAttribute{group("foo", 2)}),
+		}}), 0, syscall.ENOENT},
+		{"group", genericMessage(1, []netlinkAttribute{{
+			Type: ctrlAttrMcastGroups,
+			Data: marshalNetlinkAttributes([]netlinkAttribute{
+				group("foo", 2),
+				group("mptcp_pm_events", 3),
+			}),
+		}}), 3, nil},
+	}
+
+	for i, test := range tests {
+		id, err := parseGenericMulticastGroupID(test.b, "mptcp_pm_events")
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected err: %v != %v [test: %v]", i, err, test.err, test.desc)
+		}
+
+		if id != test.id {
+			t.Fatalf("[%02d] unexpected ID: %v != %v [test: %v]", i, id, test.id, test.desc)
+		}
+	}
+}
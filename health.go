@@ -0,0 +1,120 @@
+package mptcp
+
+const (
+	// DefaultMinMultipathFraction is the default minimum fraction of
+	// connections which must be using multiple subflows for a Summary to
+	// be considered healthy.
+	DefaultMinMultipathFraction = 0.5
+
+	// DefaultMinEstablishedFraction is the default minimum fraction of
+	// connections which must be established for a Summary to be considered
+	// healthy.
+	DefaultMinEstablishedFraction = 0.9
+)
+
+// A Summary contains high-level signals about multipath TCP usage on a host.
+type Summary struct {
+	// Total is the total number of active connections.
+	Total int
+
+	// Multipath is the fraction of connections which are using more than
+	// one subflow.
+	Multipath float64
+
+	// Established is the fraction of connections which are in the
+	// established state.
+	Established float64
+
+	// Healthy reports whether each fraction meets the thresholds configured
+	// using HealthOptions.  A host with no active connections is healthy.
+	Healthy bool
+}
+
+// healthThresholds are the thresholds used to determine if a Summary is
+// healthy.
+type healthThresholds struct {
+	minMultipath   float64
+	minEstablished float64
+}
+
+// A HealthOption configures the thresholds used by HealthSummary.
+type HealthOption func(t *healthThresholds)
+
+// WithMinMultipathFraction sets the minimum fraction of connections which
+// must be using multiple subflows for a Summary to be healthy.
+//
+// If this option is not set, DefaultMinMultipathFraction is used.
+func WithMinMultipathFraction(f float64) HealthOption {
+	return func(t *healthThresholds) {
+		t.minMultipath = f
+	}
+}
+
+// WithMinEstablishedFraction sets the minimum fraction of connections which
+// must be established for a Summary to be healthy.
+//
+// If this option is not set, DefaultMinEstablishedFraction is used.
+func WithMinEstablishedFraction(f float64) HealthOption {
+	return func(t *healthThresholds) {
+		t.minEstablished = f
+	}
+}
+
+// HealthSummary summarizes multipath TCP usage on this host, using the
+// thresholds configured by the input options.  Its behavior is otherwise
+// identical to Checker.HealthSummary.
+func HealthSummary(options ...HealthOption) (Summary, error) {
+	return defaultChecker.HealthSummary(options...)
+}
+
+// HealthSummary summarizes multipath TCP usage, as a convenience for
+// dashboards which need a single gauge.  The summary is healthy if the
+// fractions of multipath and established connections meet the thresholds
+// configured by the input options.
+func (c *Checker) HealthSummary(options ...HealthOption) (Summary, error) {
+	t := healthThresholds{
+		minMultipath:   DefaultMinMultipathFraction,
+		minEstablished: DefaultMinEstablishedFraction,
+	}
+
+	for _, o := range options {
+		o(&t)
+	}
+
+	conns, err := c.ListConnections()
+	if err != nil {
+		return Summary{}, err
+	}
+
+	return summarize(conns, t), nil
+}
+
+// summarize computes a Summary for the input connections, using the input
+// thresholds.
+func summarize(conns []Connection, t healthThresholds) Summary {
+	s := Summary{
+		Total: len(conns),
+	}
+
+	// With no connections, there is nothing which could be unhealthy
+	if s.Total == 0 {
+		s.Healthy = true
+		return s
+	}
+
+	var multipath, established int
+	for _, c := range conns {
+		if c.Subflows > 1 {
+			multipath++
+		}
+		if c.State == StateEstablished {
+			established++
+		}
+	}
+
+	s.Multipath = float64(multipath) / float64(s.Total)
+	s.Established = float64(established) / float64(s.Total)
+	s.Healthy = s.Multipath >= t.minMultipath && s.Established >= t.minEstablished
+
+	return s
+}
@@ -0,0 +1,66 @@
+package mptcp
+
+import (
+	"testing"
+)
+
+// TestCheckerHealthSummary verifies that HealthSummary computes each summary
+// field over a fixture, and applies configured thresholds.
+func TestCheckerHealthSummary(t *testing.T) {
+	fixture := []Connection{
+		{LocalToken: 0x1, State: StateEstablished, Subflows: 2},
+		{LocalToken: 0x2, State: StateEstablished, Subflows: 3},
+		{LocalToken: 0x3, State: StateEstablished, Subflows: 1},
+		{LocalToken: 0x4, State: StateSynSent, Subflows: 1},
+	}
+
+	var tests = []struct {
+		desc    string
+		conns   []Connection
+		options []HealthOption
+		summary Summary
+	}{
+		{
+			desc:    "no connections",
+			summary: Summary{Healthy: true},
+		},
+		{
+			desc:    "default thresholds",
+			conns:   fixture,
+			summary: Summary{Total: 4, Multipath: 0.5, Established: 0.75, Healthy: false},
+		},
+		{
+			desc:    "relaxed established threshold",
+			conns:   fixture,
+			options: []HealthOption{WithMinEstablishedFraction(0.75)},
+			summary: Summary{Total: 4, Multipath: 0.5, Established: 0.75, Healthy: true},
+		},
+		{
+			desc:    "strict multipath threshold",
+			conns:   fixture,
+			options: []HealthOption{WithMinEstablishedFraction(0.5), WithMinMultipathFraction(0.6)},
+			summary: Summary{Total: 4, Multipath: 0.5, Established: 0.75, Healthy: false},
+		},
+		{
+			desc:    "all healthy",
+			conns:   fixture[:2],
+			summary: Summary{Total: 2, Multipath: 1, Established: 1, Healthy: true},
+		},
+	}
+
+	for i, test := range tests {
+		conns := test.conns
+		c := NewChecker(WithSources(SourceFunc(func() ([]Connection, error) {
+			return conns, nil
+		})))
+
+		s, err := c.HealthSummary(test.options...)
+		if err != nil {
+			t.Fatalf("[%02d] unexpected err: %v [test: %v]", i, err, test.desc)
+		}
+
+		if s != test.summary {
+			t.Fatalf("[%02d] unexpected summary: %+v != %+v [test: %v]", i, s, test.summary, test.desc)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package mptcp
+
+import (
+	"container/list"
+	"sync"
+)
+
+// hexCache is a bounded, least recently used cache of hex host encodings,
+// keyed by IP address string.  It is safe for concurrent use.
+type hexCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	hosts map[string]*list.Element
+
+	// encode is the function whose results are cached, swappable
+	// for testing.
+	encode func(host string) (string, error)
+}
+
+// hexCacheEntry is a single result stored in a hexCache.
+type hexCacheEntry struct {
+	host    string
+	hexHost string
+	err     error
+}
+
+// newHexCache creates a hexCache which holds up to size results of hostToHex.
+func newHexCache(size int) *hexCache {
+	return &hexCache{
+		size:   size,
+		order:  list.New(),
+		hosts:  make(map[string]*list.Element, size),
+		encode: hostToHex,
+	}
+}
+
+// hostToHex returns the cached hex encoding of the input host, encoding it
+// and evicting the least recently used result if it is not already cached.
+func (c *hexCache) hostToHex(host string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.hosts[host]; ok {
+		c.order.MoveToFront(e)
+
+		entry := e.Value.(*hexCacheEntry)
+		return entry.hexHost, entry.err
+	}
+
+	// Invalid hosts are cached too, since they are just as likely to recur
+	hexHost, err := c.encode(host)
+	c.hosts[host] = c.order.PushFront(&hexCacheEntry{
+		host:    host,
+		hexHost: hexHost,
+		err:     err,
+	})
+
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.hosts, oldest.Value.(*hexCacheEntry).host)
+	}
+
+	return hexHost, err
+}
@@ -0,0 +1,87 @@
+package mptcp
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestHexCache verifies that hexCache returns cached results, caches errors,
+// and evicts the least recently used hosts once full.
+func TestHexCache(t *testing.T) {
+	errBadHost := errors.New("bad host")
+
+	// Count encodings performed for each host
+	calls := make(map[string]int)
+	c := newHexCache(2)
+	c.encode = func(host string) (string, error) {
+		calls[host]++
+		if host == "bad" {
+			return "", errBadHost
+		}
+
+		return "hex-" + host, nil
+	}
+
+	var tests = []struct {
+		host    string
+		hexHost string
+		err     error
+		calls   int
+	}{
+		// Initial encodings
+		{"a", "hex-a", nil, 1},
+		{"b", "hex-b", nil, 1},
+		// Cached, and now most recently used
+		{"a", "hex-a", nil, 1},
+		// Evicts "b", the least recently used
+		{"bad", "", errBadHost, 1},
+		// Cached error
+		{"bad", "", errBadHost, 1},
+		// Still cached
+		{"a", "hex-a", nil, 1},
+		// Evicted, so encoded again
+		{"b", "hex-b", nil, 2},
+	}
+
+	for i, test := range tests {
+		hexHost, err := c.hostToHex(test.host)
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected err: %v != %v [test: %v]", i, err, test.err, test)
+		}
+
+		if hexHost != test.hexHost {
+			t.Fatalf("[%02d] unexpected hexHost: %v != %v [test: %v]", i, hexHost, test.hexHost, test)
+		}
+
+		if n := calls[test.host]; n != test.calls {
+			t.Fatalf("[%02d] unexpected encode calls: %v != %v [test: %v]", i, n, test.calls, test)
+		}
+
+		if l := c.order.Len(); l > 2 {
+			t.Fatalf("[%02d] cache exceeded its size: %v > 2", i, l)
+		}
+	}
+}
+
+// TestWithHostCache verifies that WithHostCache only enables a cache for
+// positive sizes.
+func TestWithHostCache(t *testing.T) {
+	var tests = []struct {
+		size  int
+		cache bool
+	}{
+		{-1, false},
+		{0, false},
+		{1, true},
+		{1024, true},
+	}
+
+	for i, test := range tests {
+		m := &Matcher{}
+		WithHostCache(test.size)(m)
+
+		if cache := m.cache != nil; cache != test.cache {
+			t.Fatalf("[%02d] unexpected cache: %v != %v [test: %v]", i, cache, test.cache, test)
+		}
+	}
+}
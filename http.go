@@ -0,0 +1,144 @@
+package mptcp
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// contextKey is the context key under which the result of a multipath TCP
+// check is stored.
+type contextKey struct{}
+
+// checkResult is the result of a multipath TCP check, as stored in a context.
+// The check is performed once, when its result is first needed.
+type checkResult struct {
+	once    sync.Once
+	check   func() (bool, error)
+	isMPTCP bool
+	err     error
+}
+
+// newCheckResult creates a checkResult which performs check when its result
+// is first needed.
+func newCheckResult(check func() (bool, error)) *checkResult {
+	return &checkResult{check: check}
+}
+
+// result performs the check if it has not already been performed, and
+// returns its result.
+func (r *checkResult) result() (bool, error) {
+	r.once.Do(func() {
+		r.isMPTCP, r.err = r.check()
+		r.check = nil
+	})
+
+	return r.isMPTCP, r.err
+}
+
+// FromContext returns the result of the multipath TCP check which was stored
+// in ctx by ConnContext or Handler, including any error which occurred while
+// checking.  The check is performed by the first call to FromContext for a
+// connection or request, and later calls return the same result.  If ctx does
+// not carry a result, ErrNotChecked is returned.
+func FromContext(ctx context.Context) (bool, error) {
+	r, ok := ctx.Value(contextKey{}).(*checkResult)
+	if !ok {
+		return false, ErrNotChecked
+	}
+
+	return r.result()
+}
+
+// ConnContext returns a copy of ctx carrying a multipath TCP check of the
+// input connection using CheckConn, for use with FromContext.  It has the
+// signature of the ConnContext field of an http.Server, and is intended to be
+// assigned to it, so that each connection is checked at most once rather than
+// on every request.  When used alongside Handler, Handler uses the stored
+// check instead of checking each request.
+//
+// An http.Server calls ConnContext in its accept loop, so ConnContext does not
+// check the connection itself.  The connection is checked when its result is
+// first needed, by FromContext or by a Handler which sets a response header,
+// on the goroutine serving the connection.  Checking a connection which is not
+// owned by a multipath TCP socket reads the connections table, which may be
+// costly on a busy server.
+//
+// To record statistics about the connections accepted by an http.Server,
+// see NewConnStateTracker.
+func ConnContext(ctx context.Context, c net.Conn) context.Context {
+	return context.WithValue(ctx, contextKey{}, newCheckResult(func() (bool, error) {
+		return CheckConn(c)
+	}))
+}
+
+// A HandlerOption configures a Handler.
+type HandlerOption func(h *handler)
+
+// WithResponseHeader configures a Handler to set the response header with
+// the input name to "true" or "false", reporting to the client whether its
+// connection is using multipath TCP.  The header is not set if the check
+// fails.
+//
+// If this option is not set, or name is empty, no header is set.
+func WithResponseHeader(name string) HandlerOption {
+	return func(h *handler) {
+		h.header = http.CanonicalHeaderKey(name)
+	}
+}
+
+// Handler returns an http.Handler which checks whether each request's client
+// is connected using multipath TCP, stores the result in the request's
+// context for use with FromContext, and then calls next.
+//
+// If the request's context already carries a check, such as one stored by
+// ConnContext, it is used without checking again.  Otherwise, the request's
+// RemoteAddr is checked in the same way as Check, so that concurrent checks of
+// the same client share a single lookup.  The check is performed when its result is
+// first needed, by FromContext or to set a response header.  A failed check
+// does not fail the request, and its error is reported by FromContext.
+func Handler(next http.Handler, options ...HandlerOption) http.Handler {
+	h := &handler{next: next}
+	for _, o := range options {
+		o(h)
+	}
+
+	return h
+}
+
+// handler is the http.Handler returned by Handler.
+type handler struct {
+	next   http.Handler
+	header string
+}
+
+// ServeHTTP implements http.Handler.
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Check the request only if its connection was not already checked
+	if _, ok := ctx.Value(contextKey{}).(*checkResult); !ok {
+		remoteAddr := r.RemoteAddr
+		ctx = context.WithValue(ctx, contextKey{}, newCheckResult(func() (bool, error) {
+			host, port, err := parseHostPort(remoteAddr)
+			if err != nil {
+				return false, err
+			}
+
+			// Check without the request's context, which would prevent
+			// sharing a lookup with concurrent checks of the same client
+			return CheckContext(context.Background(), host, port)
+		}))
+		r = r.WithContext(ctx)
+	}
+
+	if h.header != "" {
+		if isMPTCP, err := FromContext(ctx); err == nil {
+			w.Header().Set(h.header, strconv.FormatBool(isMPTCP))
+		}
+	}
+
+	h.next.ServeHTTP(w, r)
+}
@@ -0,0 +1,194 @@
+package mptcp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestHandler verifies that Handler checks each request's remote address,
+// stores the result in the request's context, and sets the configured
+// response header.
+func TestHandler(t *testing.T) {
+	errLookup := errors.New("lookup failed")
+
+	origCheck := checkMPTCP
+	defer func() { checkMPTCP = origCheck }()
+	checkMPTCP = func(ctx context.Context, host string, port uint16) (bool, error) {
+		switch host {
+		case "192.0.2.1":
+			return true, nil
+		case "192.0.2.2":
+			return false, errLookup
+		default:
+			return false, nil
+		}
+	}
+
+	var tests = []struct {
+		desc       string
+		remoteAddr string
+		header     string
+		isMPTCP    bool
+		err        error
+	}{
+		{"MPTCP client", "192.0.2.1:1000", "true", true, nil},
+		{"TCP client", "192.0.2.3:1000", "false", false, nil},
+		{"IPv6 client with zone", "[fe80::1%eth0]:1000", "false", false, nil},
+		{"failed check", "192.0.2.2:1000", "", false, errLookup},
+		{"invalid remote address", "foo", "", false, ErrInvalidIPAddress},
+	}
+
+	for i, test := range tests {
+		var (
+			called  bool
+			isMPTCP bool
+			err     error
+		)
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+			isMPTCP, err = FromContext(r.Context())
+		})
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = test.remoteAddr
+		w := httptest.NewRecorder()
+
+		Handler(next, WithResponseHeader("x-mptcp")).ServeHTTP(w, r)
+
+		if !called {
+			t.Fatalf("[%02d] next handler not called [test: %v]", i, test.desc)
+		}
+
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected err: %v != %v [test: %v]", i, err, test.err, test.desc)
+		}
+
+		if isMPTCP != test.isMPTCP {
+			t.Fatalf("[%02d] unexpected result: %v != %v [test: %v]", i, isMPTCP, test.isMPTCP, test.desc)
+		}
+
+		if h := w.Header().Get("X-Mptcp"); h != test.header {
+			t.Fatalf("[%02d] unexpected header: %q != %q [test: %v]", i, h, test.header, test.desc)
+		}
+	}
+
+	// No header is set unless configured
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "192.0.2.1:1000"
+	w := httptest.NewRecorder()
+
+	Handler(http.NotFoundHandler()).ServeHTTP(w, r)
+	if len(w.Header().Values("X-Mptcp")) != 0 {
+		t.Fatal("header set without WithResponseHeader")
+	}
+}
+
+// TestConnContext verifies that a check stored by ConnContext is performed
+// only when its result is first needed, and is used by Handler without
+// checking each request again.
+func TestConnContext(t *testing.T) {
+	var checks int
+
+	origCheck := checkMPTCP
+	defer func() { checkMPTCP = origCheck }()
+	checkMPTCP = func(ctx context.Context, host string, port uint16) (bool, error) {
+		checks++
+		return true, nil
+	}
+
+	c := &mockConn{remote: &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1000}}
+	ctx := ConnContext(context.Background(), c)
+
+	// The connection is not checked in the server's accept loop
+	if checks != 0 {
+		t.Fatalf("unexpected check count before requests: %v != %v", checks, 0)
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		isMPTCP, err := FromContext(r.Context())
+		if err != nil || !isMPTCP {
+			t.Fatalf("unexpected result: %v, %v", isMPTCP, err)
+		}
+	})
+
+	// Requests on the connection reuse its result, even if their remote
+	// address differs from that of the connection
+	for i := 0; i < 3; i++ {
+		r := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+		r.RemoteAddr = "192.0.2.3:1000"
+		w := httptest.NewRecorder()
+
+		Handler(next, WithResponseHeader("X-MPTCP")).ServeHTTP(w, r)
+		if h := w.Header().Get("X-MPTCP"); h != "true" {
+			t.Fatalf("[%02d] unexpected header: %q != %q", i, h, "true")
+		}
+	}
+
+	if checks != 1 {
+		t.Fatalf("unexpected check count after requests: %v != %v", checks, 1)
+	}
+
+	// A context without a result reports that no check occurred
+	if _, err := FromContext(context.Background()); err != ErrNotChecked {
+		t.Fatalf("unexpected err: %v != %v", err, ErrNotChecked)
+	}
+}
+
+// TestHandlerLazy verifies that Handler only checks a request when the result
+// is needed, and checks without the request's context.
+func TestHandlerLazy(t *testing.T) {
+	var (
+		checks int
+		done   bool
+	)
+
+	origCheck := checkMPTCP
+	defer func() { checkMPTCP = origCheck }()
+	checkMPTCP = func(ctx context.Context, host string, port uint16) (bool, error) {
+		checks++
+		done = ctx.Done() != nil
+		return true, nil
+	}
+
+	var tests = []struct {
+		desc    string
+		header  string
+		fromCtx int
+		checks  int
+	}{
+		{desc: "result not needed"},
+		{desc: "response header", header: "X-MPTCP", checks: 1},
+		{desc: "FromContext called twice", fromCtx: 2, checks: 1},
+	}
+
+	for i, test := range tests {
+		checks, done = 0, false
+
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for j := 0; j < test.fromCtx; j++ {
+				if _, err := FromContext(r.Context()); err != nil {
+					t.Fatalf("[%02d] unexpected err: %v [test: %v]", i, err, test.desc)
+				}
+			}
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		r := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+		r.RemoteAddr = "192.0.2.1:1000"
+
+		Handler(next, WithResponseHeader(test.header)).ServeHTTP(httptest.NewRecorder(), r)
+		cancel()
+
+		if checks != test.checks {
+			t.Fatalf("[%02d] unexpected check count: %v != %v [test: %v]", i, checks, test.checks, test.desc)
+		}
+
+		if done {
+			t.Fatalf("[%02d] check used a cancelable context [test: %v]", i, test.desc)
+		}
+	}
+}
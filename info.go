@@ -0,0 +1,69 @@
+package mptcp
+
+import "net"
+
+// ConnInfo contains detailed information about a multipath TCP connection owned
+// by the current process, as reported by its socket.
+type ConnInfo struct {
+	// Fallback reports whether this connection fell back to regular TCP,
+	// because the peer does not support multipath TCP.  If it is set, no
+	// other fields are populated.
+	Fallback bool
+
+	// LocalToken is the MPTCP token which identifies this connection on the
+	// local host.
+	LocalToken uint32
+
+	// BytesSent, BytesReceived, and BytesAcked are the number of bytes of
+	// data sent, received, and acknowledged at the data level on this
+	// connection, across all of its subflows.  They are zero on kernels
+	// which do not report them.
+	BytesSent     uint64
+	BytesReceived uint64
+	BytesAcked    uint64
+
+	// Subflows contains information about each subflow which makes up this
+	// connection.
+	Subflows []SubflowInfo
+}
+
+// SubflowInfo contains information about a single subflow of a multipath TCP
+// connection, as reported by its socket.
+type SubflowInfo struct {
+	// BytesSent, BytesReceived, and BytesAcked are the number of bytes sent,
+	// received, and acknowledged on this subflow, including retransmissions.
+	// They are zero on kernels which do not report them.
+	BytesSent     uint64
+	BytesReceived uint64
+	BytesAcked    uint64
+}
+
+// Info retrieves detailed information about the input connection, which must
+// be owned by the current process, directly from its socket.  Connections
+// which wrap another net.Conn and expose it using a NetConn method, such as a
+// *tls.Conn, are unwrapped before they are queried.
+//
+// On Linux, Info requires a socket created using the mainline kernel's MPTCP
+// protocol, such as one returned by Dial or accepted by a net.Listener
+// returned by Listen, and a kernel which supports the MPTCP_INFO and
+// MPTCP_TCPINFO socket options, added in Linux 5.16.  For other sockets, or
+// on older kernels, ErrNoInfo is returned.
+//
+// If the connection does not expose its file descriptor, ErrNoFileDescriptor
+// is returned.
+//
+// If multipath TCP detection is not implemented for the current operating system,
+// this function will return ErrNotImplemented.
+func Info(c net.Conn) (*ConnInfo, error) {
+	var (
+		info  *ConnInfo
+		fdErr error
+	)
+	if err := controlConn(c, func(fd int) {
+		info, fdErr = fdInfo(fd)
+	}); err != nil {
+		return nil, err
+	}
+
+	return info, fdErr
+}
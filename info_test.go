@@ -0,0 +1,101 @@
+package mptcp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// TestInfo verifies that Info queries the file descriptor of a connection,
+// unwrapping connections layered over another connection.
+func TestInfo(t *testing.T) {
+	client, _ := testDialPair(t, &Dialer{})
+
+	errFD := errors.New("fd info failed")
+	want := &ConnInfo{LocalToken: 1, Subflows: []SubflowInfo{{BytesSent: 10}}}
+
+	origInfo := fdInfo
+	defer func() { fdInfo = origInfo }()
+
+	pipe, _ := net.Pipe()
+	defer pipe.Close()
+
+	var tests = []struct {
+		desc   string
+		c      net.Conn
+		fdInfo *ConnInfo
+		fdErr  error
+		info   *ConnInfo
+		err    error
+	}{
+		{"MPTCP connection", client, want, nil, want, nil},
+		{"wrapped connection", wrappedConn{client}, want, nil, want, nil},
+		{"fd info error", client, nil, errFD, nil, errFD},
+		{"no file descriptor", pipe, want, nil, nil, ErrNoFileDescriptor},
+	}
+
+	for i, test := range tests {
+		fdInfo = func(fd int) (*ConnInfo, error) {
+			return test.fdInfo, test.fdErr
+		}
+
+		info, err := Info(test.c)
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected err: %v != %v [test: %v]", i, err, test.err, test.desc)
+		}
+
+		if !reflect.DeepEqual(info, test.info) {
+			t.Fatalf("[%02d] unexpected info: %#v != %#v [test: %v]", i, info, test.info, test.desc)
+		}
+	}
+}
+
+// TestCheckConnInfo verifies that CheckConn uses the socket information of
+// connections owned by this process when it is available, and otherwise
+// falls back to checking the connection's remote address.
+func TestCheckConnInfo(t *testing.T) {
+	client, _ := testDialPair(t, &Dialer{})
+
+	origInfo, origCheck := fdInfo, checkMPTCP
+	defer func() { fdInfo, checkMPTCP = origInfo, origCheck }()
+
+	var checked bool
+	checkMPTCP = func(ctx context.Context, host string, port uint16) (bool, error) {
+		checked = true
+		return true, nil
+	}
+
+	var tests = []struct {
+		desc    string
+		info    *ConnInfo
+		err     error
+		ok      bool
+		checked bool
+	}{
+		{"MPTCP connection", &ConnInfo{LocalToken: 1}, nil, true, false},
+		{"fallen back connection", &ConnInfo{Fallback: true}, nil, false, false},
+		{"no socket information", nil, ErrNoInfo, true, true},
+	}
+
+	for i, test := range tests {
+		checked = false
+		fdInfo = func(fd int) (*ConnInfo, error) {
+			return test.info, test.err
+		}
+
+		ok, err := CheckConn(client)
+		if err != nil {
+			t.Fatalf("[%02d] unexpected err: %v [test: %v]", i, err, test.desc)
+		}
+
+		if ok != test.ok {
+			t.Fatalf("[%02d] unexpected ok: %v != %v [test: %v]", i, ok, test.ok, test.desc)
+		}
+
+		if checked != test.checked {
+			t.Fatalf("[%02d] unexpected table check: %v != %v [test: %v]", i, checked, test.checked, test.desc)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package mptcp
+
+import (
+	"net"
+	"sync"
+)
+
+// A lazyEntry is a connection whose fields may be decoded independently.
+type lazyEntry interface {
+	tokens() (local, remote uint32, err error)
+	localAddr() (*net.TCPAddr, error)
+	remoteAddr() (*net.TCPAddr, error)
+	connection() (Connection, error)
+}
+
+// A LazyConnection is an active multipath TCP connection whose fields are
+// decoded from the raw connections table only when they are first accessed,
+// for callers which list many connections but read few of their fields.  The
+// decoded value of each field is cached.  A LazyConnection is safe for
+// concurrent use.
+//
+// Each accessor returns an error if its field cannot be decoded.  Fields
+// which are not accessed are never decoded or validated.
+type LazyConnection struct {
+	entry  lazyEntry
+	isIPv6 bool
+
+	tokensOnce  sync.Once
+	localToken  uint32
+	remoteToken uint32
+	tokensErr   error
+
+	localOnce sync.Once
+	local     *net.TCPAddr
+	localErr  error
+
+	remoteOnce sync.Once
+	remote     *net.TCPAddr
+	remoteErr  error
+}
+
+// newLazyConnection creates a LazyConnection which decodes fields from the
+// input entry.
+func newLazyConnection(entry lazyEntry, isIPv6 bool) *LazyConnection {
+	return &LazyConnection{
+		entry:  entry,
+		isIPv6: isIPv6,
+	}
+}
+
+// IsIPv6 reports whether or not this connection is using IPv6.
+func (lc *LazyConnection) IsIPv6() bool {
+	return lc.isIPv6
+}
+
+// LocalToken returns the MPTCP token which identifies this connection on
+// the local host.
+func (lc *LazyConnection) LocalToken() (uint32, error) {
+	lc.decodeTokens()
+	return lc.localToken, lc.tokensErr
+}
+
+// RemoteToken returns the MPTCP token which identifies this connection on
+// the remote host.
+func (lc *LazyConnection) RemoteToken() (uint32, error) {
+	lc.decodeTokens()
+	return lc.remoteToken, lc.tokensErr
+}
+
+// decodeTokens decodes both tokens, which are always stored together, once.
+func (lc *LazyConnection) decodeTokens() {
+	lc.tokensOnce.Do(func() {
+		lc.localToken, lc.remoteToken, lc.tokensErr = lc.entry.tokens()
+	})
+}
+
+// LocalAddr returns the local TCP address of this connection.
+func (lc *LazyConnection) LocalAddr() (*net.TCPAddr, error) {
+	lc.localOnce.Do(func() {
+		lc.local, lc.localErr = lc.entry.localAddr()
+	})
+
+	return lc.local, lc.localErr
+}
+
+// RemoteAddr returns the remote TCP address of this connection.
+func (lc *LazyConnection) RemoteAddr() (*net.TCPAddr, error) {
+	lc.remoteOnce.Do(func() {
+		lc.remote, lc.remoteErr = lc.entry.remoteAddr()
+	})
+
+	return lc.remote, lc.remoteErr
+}
+
+// Connection decodes every field of this connection into a Connection.
+func (lc *LazyConnection) Connection() (Connection, error) {
+	return lc.entry.connection()
+}
+
+// decodeAll decodes and caches every field of this connection which has an
+// accessor, returning the first error which occurs.
+func (lc *LazyConnection) decodeAll() error {
+	if _, err := lc.LocalToken(); err != nil {
+		return err
+	}
+	if _, err := lc.LocalAddr(); err != nil {
+		return err
+	}
+
+	_, err := lc.RemoteAddr()
+	return err
+}
+
+// decodedEntry is a lazyEntry for a connection which is already decoded,
+// such as one returned by a Source.
+type decodedEntry struct {
+	c Connection
+}
+
+// tokens returns the tokens of the decoded connection.
+func (e decodedEntry) tokens() (uint32, uint32, error) {
+	return e.c.LocalToken, e.c.RemoteToken, nil
+}
+
+// localAddr returns the local address of the decoded connection.
+func (e decodedEntry) localAddr() (*net.TCPAddr, error) {
+	return e.c.LocalAddr, nil
+}
+
+// remoteAddr returns the remote address of the decoded connection.
+func (e decodedEntry) remoteAddr() (*net.TCPAddr, error) {
+	return e.c.RemoteAddr, nil
+}
+
+// connection returns the decoded connection.
+func (e decodedEntry) connection() (Connection, error) {
+	return e.c, nil
+}
+
+// WithEagerDecode configures a Checker to decode every field of each
+// LazyConnection as it is listed by ListLazyConnections, so that any entry
+// which cannot be decoded causes an error to be returned immediately, exactly
+// as it is by ListConnections.
+//
+// If this option is not set, fields are decoded only when they are accessed.
+func WithEagerDecode() Option {
+	return func(c *Checker) {
+		c.eagerDecode = true
+	}
+}
+
+// ListLazyConnections returns all active multipath TCP connections on this
+// host, without decoding their fields.  Its behavior is otherwise identical
+// to Checker.ListLazyConnections.
+func ListLazyConnections() ([]*LazyConnection, error) {
+	return defaultChecker.ListLazyConnections()
+}
+
+// ListLazyConnections returns all active multipath TCP connections on this
+// host as LazyConnections, whose fields are decoded only when accessed.  It
+// is intended for listing large tables when only a few fields of each
+// connection are needed.
+//
+// Connections from any configured Sources are already decoded, and are
+// wrapped as LazyConnections.  Connections are returned in the order they are
+// reported, as ordering options require decoding, and the connections are
+// not stored for use by CheckCached.
+//
+// If multipath TCP detection is not implemented for the current operating system,
+// this function will return ErrNotImplemented.
+func (c *Checker) ListLazyConnections() ([]*LazyConnection, error) {
+	var (
+		lazy []*LazyConnection
+		err  error
+	)
+
+	if len(c.sources) == 0 {
+		err = c.inNetNS(func() error {
+			var err error
+			lazy, err = c.listLazyConnections()
+			return err
+		})
+	} else {
+		var conns []Connection
+		conns, err = c.listSourceConnections()
+		for _, conn := range conns {
+			lazy = append(lazy, newLazyConnection(decodedEntry{c: conn}, conn.IsIPv6))
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if c.eagerDecode {
+		for _, lc := range lazy {
+			if err := lc.decodeAll(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return lazy, nil
+}
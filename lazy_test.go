@@ -0,0 +1,79 @@
+package mptcp
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// TestCheckerListLazyConnectionsSources verifies that a Checker wraps the
+// connections returned by its Sources as LazyConnections.
+func TestCheckerListLazyConnectionsSources(t *testing.T) {
+	want := []Connection{
+		{
+			LocalToken:  0x1,
+			RemoteToken: 0x2,
+			LocalAddr:   &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 80},
+			RemoteAddr:  &net.TCPAddr{IP: net.ParseIP("192.0.2.2"), Port: 40000},
+		},
+		{
+			LocalToken:  0x3,
+			RemoteToken: 0x4,
+			IsIPv6:      true,
+			LocalAddr:   &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 80},
+			RemoteAddr:  &net.TCPAddr{IP: net.ParseIP("2001:db8::2"), Port: 40000},
+		},
+	}
+	source := SourceFunc(func() ([]Connection, error) {
+		return want, nil
+	})
+
+	for _, options := range [][]Option{nil, {WithEagerDecode()}} {
+		lazy, err := NewChecker(append(options, WithSources(source))...).ListLazyConnections()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(lazy) != len(want) {
+			t.Fatalf("unexpected number of connections: %d != %d", len(lazy), len(want))
+		}
+
+		for i, lc := range lazy {
+			local, lErr := lc.LocalToken()
+			remote, rErr := lc.RemoteToken()
+			localAddr, laErr := lc.LocalAddr()
+			remoteAddr, raErr := lc.RemoteAddr()
+			c, cErr := lc.Connection()
+			if err := errors.Join(lErr, rErr, laErr, raErr, cErr); err != nil {
+				t.Fatalf("[%02d] unexpected err: %v", i, err)
+			}
+
+			if local != want[i].LocalToken || remote != want[i].RemoteToken {
+				t.Fatalf("[%02d] unexpected tokens: (%x, %x)", i, local, remote)
+			}
+
+			if localAddr != want[i].LocalAddr || remoteAddr != want[i].RemoteAddr || lc.IsIPv6() != want[i].IsIPv6 {
+				t.Fatalf("[%02d] unexpected addresses: (%v, %v, %v)", i, localAddr, remoteAddr, lc.IsIPv6())
+			}
+
+			if !reflect.DeepEqual(c, want[i]) {
+				t.Fatalf("[%02d] unexpected connection: %v != %v", i, c, want[i])
+			}
+		}
+	}
+}
+
+// TestCheckerListLazyConnectionsSourceError verifies that a Checker returns
+// any error which occurs while listing connections from its Sources.
+func TestCheckerListLazyConnectionsSourceError(t *testing.T) {
+	errFoo := errors.New("foo")
+	source := SourceFunc(func() ([]Connection, error) {
+		return nil, errFoo
+	})
+
+	lazy, err := NewChecker(WithSources(source)).ListLazyConnections()
+	if lazy != nil || !errors.Is(err, errFoo) {
+		t.Fatalf("unexpected result: (%v, %v)", lazy, err)
+	}
+}
@@ -0,0 +1,66 @@
+package mptcp
+
+// A LogicalConnection is a single multipath TCP connection, composed of one
+// or more subflows which share its local token.
+type LogicalConnection struct {
+	// LocalToken and RemoteToken are the MPTCP tokens which identify this
+	// connection on the local and remote hosts.
+	LocalToken  uint32
+	RemoteToken uint32
+
+	// State is the state of this connection.  The connection is
+	// established if any of its subflows is established.
+	State State
+
+	// Subflows are the subflows which make up this connection, in the order
+	// they were reported by the operating system.
+	Subflows []Connection
+}
+
+// ListLogicalConnections returns all active multipath TCP connections on this
+// host, grouping the subflows reported by the operating system into a single
+// LogicalConnection for each local token.  Connections are returned in the
+// order their first subflow was reported.
+//
+// If multipath TCP detection is not implemented for the current operating system,
+// this function will return ErrNotImplemented.
+func ListLogicalConnections() ([]LogicalConnection, error) {
+	conns, err := listConnections()
+	if err != nil {
+		return nil, err
+	}
+
+	return groupConnections(conns), nil
+}
+
+// groupConnections groups the input subflows into logical connections by
+// their local token.
+func groupConnections(conns []Connection) []LogicalConnection {
+	var (
+		logical []LogicalConnection
+		index   = make(map[uint32]int)
+	)
+
+	for _, c := range conns {
+		i, ok := index[c.LocalToken]
+		if !ok {
+			i = len(logical)
+			index[c.LocalToken] = i
+			logical = append(logical, LogicalConnection{
+				LocalToken:  c.LocalToken,
+				RemoteToken: c.RemoteToken,
+				State:       c.State,
+			})
+		}
+
+		lc := &logical[i]
+		lc.Subflows = append(lc.Subflows, c)
+
+		// Any established subflow keeps the connection established
+		if c.State == StateEstablished {
+			lc.State = StateEstablished
+		}
+	}
+
+	return logical
+}
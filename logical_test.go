@@ -0,0 +1,52 @@
+package mptcp
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+// TestListLogicalConnections verifies that ListLogicalConnections collapses
+// subflows sharing a token into a single logical connection, using a mock
+// connection source.
+func TestListLogicalConnections(t *testing.T) {
+	origList := listConnections
+	defer func() { listConnections = origList }()
+
+	subflow := func(token uint32, state State, localIP string, port int) Connection {
+		return Connection{
+			LocalToken:  token,
+			RemoteToken: token + 1,
+			LocalAddr:   &net.TCPAddr{IP: net.ParseIP(localIP), Port: 80},
+			RemoteAddr:  &net.TCPAddr{IP: net.ParseIP(ipv4HostOne), Port: port},
+			State:       state,
+		}
+	}
+
+	conns := []Connection{
+		subflow(0x10, StateEstablished, "192.168.1.10", 2020),
+		subflow(0x20, StateSynSent, "192.168.1.10", 4040),
+		subflow(0x10, StateSynSent, "10.0.0.10", 2021),
+		subflow(0x10, StateEstablished, "172.16.0.10", 2022),
+		subflow(0x30, StateSynSent, "192.168.1.10", 6060),
+		subflow(0x30, StateEstablished, "10.0.0.10", 6061),
+	}
+	listConnections = func() ([]Connection, error) {
+		return conns, nil
+	}
+
+	logical, err := ListLogicalConnections()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []LogicalConnection{
+		{LocalToken: 0x10, RemoteToken: 0x11, State: StateEstablished, Subflows: []Connection{conns[0], conns[2], conns[3]}},
+		{LocalToken: 0x20, RemoteToken: 0x21, State: StateSynSent, Subflows: []Connection{conns[1]}},
+		{LocalToken: 0x30, RemoteToken: 0x31, State: StateEstablished, Subflows: []Connection{conns[4], conns[5]}},
+	}
+
+	if !reflect.DeepEqual(logical, want) {
+		t.Fatalf("unexpected logical connections:\n- want: %v\n-  got: %v", want, logical)
+	}
+}
@@ -0,0 +1,70 @@
+package mptcp
+
+// A Matcher checks for active multipath TCP connections against a single
+// snapshot of this host's connections table.
+//
+// A Matcher performs no I/O or table parsing once it is created, and is
+// intended for checking many hosts at once.  It is safe for concurrent use.
+type Matcher struct {
+	remotes map[string]struct{}
+	cache   *hexCache
+}
+
+// A MatcherOption configures a Matcher.
+type MatcherOption func(m *Matcher)
+
+// WithHostCache configures a Matcher to cache the encoded form of up to size
+// hosts, which avoids repeating work when the same hosts are checked many
+// times.  The least recently used hosts are evicted first.
+//
+// If this option is not set, or size is not positive, no cache is used.
+func WithHostCache(size int) MatcherOption {
+	return func(m *Matcher) {
+		if size <= 0 {
+			m.cache = nil
+			return
+		}
+
+		m.cache = newHexCache(size)
+	}
+}
+
+// NewMatcher creates a new Matcher from a snapshot of this host's
+// active multipath TCP connections, configured using the input options.
+//
+// If multipath TCP detection is not implemented for the current operating system,
+// this function will return ErrNotImplemented.
+func NewMatcher(options ...MatcherOption) (*Matcher, error) {
+	remotes, err := matcherRemotes()
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Matcher{
+		remotes: remotes,
+	}
+	for _, o := range options {
+		o(m)
+	}
+
+	return m, nil
+}
+
+// Contains reports whether a multipath TCP connection originating from the
+// input host and port was active when the Matcher was created.  Invalid
+// IP addresses are never contained in a Matcher.
+func (m *Matcher) Contains(host string, port uint16) bool {
+	// Encode the query exactly as it would appear in the table
+	encode := hostToHex
+	if m.cache != nil {
+		encode = m.cache.hostToHex
+	}
+
+	hexHost, err := encode(host)
+	if err != nil {
+		return false
+	}
+
+	_, ok := m.remotes[joinHexHostPort(hexHost, port)]
+	return ok
+}
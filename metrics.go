@@ -0,0 +1,135 @@
+package mptcp
+
+import (
+	"expvar"
+	"net"
+	"sync"
+)
+
+const (
+	// maxMetricLabels is the maximum number of distinct label sets
+	// recorded by a ConnectionMetrics.  Connections which would create
+	// additional label sets are recorded with an overflow subnet instead.
+	maxMetricLabels = 256
+
+	// metricOverflowSubnet is the subnet label used once maxMetricLabels
+	// has been reached.
+	metricOverflowSubnet = "other"
+
+	// metricIPv4PrefixLen and metricIPv6PrefixLen are the prefix lengths
+	// used to group remote addresses into subnet labels.
+	metricIPv4PrefixLen = 24
+	metricIPv6PrefixLen = 48
+)
+
+// ConnectionMetrics records metrics about the multipath TCP connections
+// observed by a Watcher, in a form which can be published using package
+// expvar.  A ConnectionMetrics is safe for concurrent use.
+//
+// To record metrics, pass the Observe method to a Watcher using
+// WithWatchObserver.
+type ConnectionMetrics struct {
+	mu      sync.Mutex
+	created *expvar.Map
+	labels  int
+}
+
+// NewConnectionMetrics creates a new ConnectionMetrics.  Its metrics are not
+// published until its Var is passed to expvar.Publish.
+func NewConnectionMetrics() *ConnectionMetrics {
+	return &ConnectionMetrics{
+		created: new(expvar.Map).Init(),
+	}
+}
+
+// Var returns an expvar.Var containing the metrics, suitable for use with
+// expvar.Publish.
+//
+// The "created" counter counts connections newly observed by a Watcher,
+// keyed by a label set of the form "family=ipv4,subnet=192.0.2.0/24".
+// Remote addresses are grouped into /24 subnets for IPv4 and /48 subnets for
+// IPv6.  At most 256 distinct subnet label sets are recorded; connections from
+// further subnets are counted using the subnet label "other" for their family.
+func (m *ConnectionMetrics) Var() expvar.Var {
+	out := new(expvar.Map).Init()
+	out.Set("created", m.created)
+
+	return out
+}
+
+// Created returns the number of connections newly observed from the input
+// family and subnet labels, as they appear in the label set of the
+// "created" counter.
+func (m *ConnectionMetrics) Created(family string, subnet string) int64 {
+	v, ok := m.created.Get(metricLabels(family, subnet)).(*expvar.Int)
+	if !ok {
+		return 0
+	}
+
+	return v.Value()
+}
+
+// Observe records metrics for the input WatchDiff.  Connections reported by
+// the initial poll of a Watcher were not necessarily established since the
+// Watcher was created, so they are not counted.
+func (m *ConnectionMetrics) Observe(d WatchDiff) {
+	if d.Initial {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, c := range d.Added {
+		family, subnet := connectionMetricLabels(c)
+
+		// Bound the number of label sets, so an unbounded number of
+		// remote subnets cannot grow the metrics without limit
+		labels := metricLabels(family, subnet)
+		if m.created.Get(labels) == nil {
+			if m.labels >= maxMetricLabels {
+				labels = metricLabels(family, metricOverflowSubnet)
+			}
+
+			if m.created.Get(labels) == nil {
+				m.labels++
+			}
+		}
+
+		m.created.Add(labels, 1)
+	}
+}
+
+// connectionMetricLabels returns the family and subnet labels for the remote
+// address of a connection.
+func connectionMetricLabels(c Connection) (string, string) {
+	family, bits, prefix := "ipv4", 8*net.IPv4len, metricIPv4PrefixLen
+	if c.IsIPv6 {
+		family, bits, prefix = "ipv6", 8*net.IPv6len, metricIPv6PrefixLen
+	}
+
+	if c.RemoteAddr == nil {
+		return family, metricOverflowSubnet
+	}
+
+	ip := c.RemoteAddr.IP
+	if !c.IsIPv6 {
+		ip = ip.To4()
+	}
+	if ip == nil {
+		return family, metricOverflowSubnet
+	}
+
+	mask := net.CIDRMask(prefix, bits)
+	subnet := &net.IPNet{
+		IP:   ip.Mask(mask),
+		Mask: mask,
+	}
+
+	return family, subnet.String()
+}
+
+// metricLabels formats family and subnet labels as a label set key.
+func metricLabels(family string, subnet string) string {
+	return "family=" + family + ",subnet=" + subnet
+}
@@ -0,0 +1,81 @@
+package mptcp
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+// TestConnectionMetricsCreated verifies that ConnectionMetrics counts
+// connections newly observed by a Watcher, labeled by family and subnet.
+func TestConnectionMetricsCreated(t *testing.T) {
+	var conns []Connection
+	source := SourceFunc(func() ([]Connection, error) {
+		return conns, nil
+	})
+
+	m := NewConnectionMetrics()
+	w := NewWatcher(NewChecker(WithSources(source)), WithWatchObserver(m.Observe))
+
+	// Connections present on the initial poll are not counted
+	conns = []Connection{testSetConnA}
+	if _, err := w.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	if n := m.Created("ipv4", "8.8.8.0/24"); n != 0 {
+		t.Fatalf("unexpected initial count: %v != %v", n, 0)
+	}
+
+	// Newly added connections are counted by family and subnet
+	conns = []Connection{testSetConnA, testSetConnB, testSetConnC}
+	if _, err := w.Poll(); err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		family string
+		subnet string
+		count  int64
+	}{
+		{"ipv4", "8.8.8.0/24", 0},
+		{"ipv4", "8.8.4.0/24", 1},
+		{"ipv6", "2001:4860:4860::/48", 1},
+	}
+
+	for i, test := range tests {
+		if n := m.Created(test.family, test.subnet); n != test.count {
+			t.Fatalf("[%02d] unexpected count: %v != %v [test: %v]", i, n, test.count, test)
+		}
+	}
+
+	// Counters are published using the label set as a key
+	if s := m.Var().String(); !strings.Contains(s, `"family=ipv4,subnet=8.8.4.0/24": 1`) {
+		t.Fatalf("unexpected expvar output: %s", s)
+	}
+}
+
+// TestConnectionMetricsCardinality verifies that ConnectionMetrics bounds the
+// number of subnet label sets it records.
+func TestConnectionMetricsCardinality(t *testing.T) {
+	var added []Connection
+	for i := 0; i < maxMetricLabels+10; i++ {
+		added = append(added, Connection{
+			RemoteAddr: &net.TCPAddr{
+				IP:   net.ParseIP(fmt.Sprintf("10.%d.%d.1", i/256, i%256)),
+				Port: 2020,
+			},
+		})
+	}
+
+	m := NewConnectionMetrics()
+	m.Observe(WatchDiff{Added: added})
+
+	if n := m.labels; n != maxMetricLabels+1 {
+		t.Fatalf("unexpected label set count: %v != %v", n, maxMetricLabels+1)
+	}
+
+	if n := m.Created("ipv4", metricOverflowSubnet); n != 10 {
+		t.Fatalf("unexpected overflow count: %v != %v", n, 10)
+	}
+}
@@ -0,0 +1,191 @@
+package mptcp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// An EventType is the type of a multipath TCP path manager event, using the
+// event numbering of the Linux kernel.
+type EventType uint8
+
+// Possible EventType values.
+const (
+	EventCreated            EventType = 1
+	EventEstablished        EventType = 2
+	EventClosed             EventType = 3
+	EventAnnounced          EventType = 6
+	EventRemoved            EventType = 7
+	EventSubflowEstablished EventType = 10
+	EventSubflowClosed      EventType = 11
+	EventSubflowPriority    EventType = 13
+	EventListenerCreated    EventType = 15
+	EventListenerClosed     EventType = 16
+)
+
+// eventTypeNames are the names of each known EventType, as used by the
+// kernel without their MPTCP_EVENT_ prefix.
+var eventTypeNames = map[EventType]string{
+	EventCreated:            "CREATED",
+	EventEstablished:        "ESTABLISHED",
+	EventClosed:             "CLOSED",
+	EventAnnounced:          "ANNOUNCED",
+	EventRemoved:            "REMOVED",
+	EventSubflowEstablished: "SUB_ESTABLISHED",
+	EventSubflowClosed:      "SUB_CLOSED",
+	EventSubflowPriority:    "SUB_PRIORITY",
+	EventListenerCreated:    "LISTENER_CREATED",
+	EventListenerClosed:     "LISTENER_CLOSED",
+}
+
+// String returns the name of the EventType, as used by the kernel.
+func (t EventType) String() string {
+	if name, ok := eventTypeNames[t]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("EventType(%d)", uint8(t))
+}
+
+// An Event is a multipath TCP path manager event, reported by the kernel
+// when a connection or one of its subflows changes, or when a peer announces
+// or removes an address.  Fields which are not reported for an event's type
+// are left unset.
+type Event struct {
+	// Type is the type of this event.
+	Type EventType
+
+	// Token is the MPTCP token which identifies the connection on the local
+	// host.  It is zero for listener events.
+	Token uint32
+
+	// LocalAddr and RemoteAddr are the local and remote TCP addresses of
+	// the connection or subflow.  For EventAnnounced, RemoteAddr is the
+	// address announced by the peer.  They are nil if not reported.
+	LocalAddr  *net.TCPAddr
+	RemoteAddr *net.TCPAddr
+
+	// LocalAddrID and RemoteAddrID are the MPTCP address IDs of the local
+	// and remote addresses.  For EventRemoved, RemoteAddrID identifies the
+	// address removed by the peer.
+	LocalAddrID  uint8
+	RemoteAddrID uint8
+
+	// Backup reports whether a subflow is marked as a backup subflow.
+	Backup bool
+
+	// ServerSide reports whether the connection was accepted by this host,
+	// rather than initiated by it.
+	ServerSide bool
+}
+
+// monitorBufferSize is the number of events which may be buffered by a
+// Monitor before it stops receiving events from the kernel.
+const monitorBufferSize = 64
+
+// An eventReceiver receives batches of multipath TCP path manager events from
+// the operating system.  Close interrupts a blocked receive.
+type eventReceiver interface {
+	receive() ([]Event, error)
+	Close() error
+}
+
+// A Monitor subscribes to multipath TCP path manager events, and delivers
+// them on a channel as they occur, so that connections can be tracked without
+// polling the connections table.
+type Monitor struct {
+	events chan Event
+
+	mu  sync.Mutex
+	err error
+}
+
+// NewMonitor subscribes to multipath TCP path manager events, which are
+// delivered by the returned Monitor until ctx is canceled or an error occurs.
+//
+// On Linux, NewMonitor requires a kernel with mainline MPTCP support, and
+// typically CAP_NET_ADMIN.  If the kernel drops events because they are not
+// received quickly enough, the dropped events are lost.
+//
+// If multipath TCP event monitoring is not implemented for the current
+// operating system, this function will return ErrNotImplemented.
+func NewMonitor(ctx context.Context) (*Monitor, error) {
+	r, err := dialMonitor()
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Monitor{
+		events: make(chan Event, monitorBufferSize),
+	}
+	go m.run(ctx, r)
+
+	return m, nil
+}
+
+// Events returns the channel on which events are delivered.  The channel is
+// closed once the Monitor stops, after which Err reports the reason.
+func (m *Monitor) Events() <-chan Event {
+	return m.events
+}
+
+// Err returns the error which stopped the Monitor, which is the error of its
+// context if the context was canceled.  It returns nil while the Monitor is
+// running.
+func (m *Monitor) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.err
+}
+
+// run receives events using r and delivers them until ctx is canceled or an
+// error occurs, then closes the events channel.
+func (m *Monitor) run(ctx context.Context, r eventReceiver) {
+	defer close(m.events)
+
+	// Close the receiver once ctx is done or run returns, which interrupts
+	// any blocked receive
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stop:
+		}
+
+		_ = r.Close()
+	}()
+
+	for {
+		events, err := r.receive()
+		if err != nil {
+			// Report cancelation rather than the error of the closed receiver
+			if ctx.Err() != nil {
+				err = ctx.Err()
+			}
+
+			m.stop(err)
+			return
+		}
+
+		for _, e := range events {
+			select {
+			case m.events <- e:
+			case <-ctx.Done():
+				m.stop(ctx.Err())
+				return
+			}
+		}
+	}
+}
+
+// stop records the error which stopped the Monitor.
+func (m *Monitor) stop(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.err = err
+}
@@ -0,0 +1,208 @@
+// +build linux
+
+package mptcp
+
+import (
+	"encoding/binary"
+	"net"
+	"os"
+	"syscall"
+)
+
+const (
+	// mptcpPMEventsGroupName is the name of the generic netlink multicast
+	// group on which the mainline Linux MPTCP path manager reports events.
+	mptcpPMEventsGroupName = "mptcp_pm_events"
+
+	// solNetlink is the socket option level for netlink socket options, and
+	// netlinkAddMembership is the socket option which joins a multicast
+	// group.
+	solNetlink           = 270
+	netlinkAddMembership = 1
+
+	// mptcpAttr* are the attributes of MPTCP path manager events.
+	mptcpAttrToken      = 1
+	mptcpAttrLocID      = 3
+	mptcpAttrRemID      = 4
+	mptcpAttrSAddr4     = 5
+	mptcpAttrSAddr6     = 6
+	mptcpAttrDAddr4     = 7
+	mptcpAttrDAddr6     = 8
+	mptcpAttrSPort      = 9
+	mptcpAttrDPort      = 10
+	mptcpAttrBackup     = 11
+	mptcpAttrServerSide = 18
+)
+
+// dialMonitor subscribes to the events of the mainline Linux MPTCP path
+// manager, using generic netlink.
+var dialMonitor = func() (eventReceiver, error) {
+	c, err := dialNetlink(netlinkGeneric)
+	if err != nil {
+		return nil, err
+	}
+
+	family, err := c.genericFamilyID(mptcpPMFamilyName)
+	if err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+
+	group, err := c.genericMulticastGroupID(mptcpPMFamilyName, mptcpPMEventsGroupName)
+	if err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+
+	if err := syscall.SetsockoptInt(c.fd, solNetlink, netlinkAddMembership, int(group)); err != nil {
+		_ = c.Close()
+		return nil, os.NewSyscallError("setsockopt", err)
+	}
+
+	// Use the runtime network poller, so that closing the socket interrupts
+	// a blocked receive
+	if err := syscall.SetNonblock(c.fd, true); err != nil {
+		_ = c.Close()
+		return nil, os.NewSyscallError("setnonblock", err)
+	}
+
+	f := os.NewFile(uintptr(c.fd), "mptcp_pm")
+	rc, err := f.SyscallConn()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return &netlinkEventReceiver{
+		f:      f,
+		rc:     rc,
+		family: family,
+		b:      make([]byte, netlinkReceiveBufferSize),
+	}, nil
+}
+
+// A netlinkEventReceiver receives MPTCP path manager events from a generic
+// netlink socket subscribed to the path manager's multicast group.
+type netlinkEventReceiver struct {
+	f      *os.File
+	rc     syscall.RawConn
+	family uint16
+	b      []byte
+}
+
+// Close closes the netlink socket.
+func (r *netlinkEventReceiver) Close() error {
+	return r.f.Close()
+}
+
+// receive blocks until at least one MPTCP path manager event is received.
+func (r *netlinkEventReceiver) receive() ([]Event, error) {
+	for {
+		var (
+			n    int
+			rerr error
+		)
+		if err := r.rc.Read(func(fd uintptr) bool {
+			n, _, rerr = syscall.Recvfrom(int(fd), r.b, 0)
+			return rerr != syscall.EAGAIN
+		}); err != nil {
+			return nil, err
+		}
+
+		switch rerr {
+		case nil:
+		case syscall.ENOBUFS:
+			// The kernel dropped events which could not be queued, but
+			// the socket remains usable
+			continue
+		default:
+			return nil, os.NewSyscallError("recvfrom", rerr)
+		}
+
+		msgs, err := syscall.ParseNetlinkMessage(r.b[:n])
+		if err != nil {
+			return nil, errInvalidNetlinkMessage
+		}
+
+		events, err := parseEventMessages(msgs, r.family)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(events) > 0 {
+			return events, nil
+		}
+	}
+}
+
+// parseEventMessages parses MPTCP path manager events from the input netlink
+// messages, skipping messages which do not belong to the input family.
+func parseEventMessages(msgs []syscall.NetlinkMessage, family uint16) ([]Event, error) {
+	var events []Event
+	for _, m := range msgs {
+		if m.Header.Type != family {
+			continue
+		}
+
+		e, err := parseEventMessage(m.Data)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, e)
+	}
+
+	return events, nil
+}
+
+// parseEventMessage parses a single MPTCP path manager event from the data of
+// a generic netlink message, whose command is the type of the event.
+func parseEventMessage(b []byte) (Event, error) {
+	if len(b) < genlHeaderLen {
+		return Event{}, errInvalidNetlinkMessage
+	}
+
+	attrs, err := parseNetlinkAttributes(b[genlHeaderLen:])
+	if err != nil {
+		return Event{}, err
+	}
+
+	e := Event{Type: EventType(b[0])}
+
+	var (
+		sip, dip     net.IP
+		sport, dport int
+	)
+	for _, a := range attrs {
+		switch {
+		case a.Type == mptcpAttrToken && len(a.Data) == 4:
+			e.Token = binary.NativeEndian.Uint32(a.Data)
+		case a.Type == mptcpAttrLocID && len(a.Data) == 1:
+			e.LocalAddrID = a.Data[0]
+		case a.Type == mptcpAttrRemID && len(a.Data) == 1:
+			e.RemoteAddrID = a.Data[0]
+		case (a.Type == mptcpAttrSAddr4 && len(a.Data) == net.IPv4len) || (a.Type == mptcpAttrSAddr6 && len(a.Data) == net.IPv6len):
+			sip = net.IP(append([]byte(nil), a.Data...))
+		case (a.Type == mptcpAttrDAddr4 && len(a.Data) == net.IPv4len) || (a.Type == mptcpAttrDAddr6 && len(a.Data) == net.IPv6len):
+			dip = net.IP(append([]byte(nil), a.Data...))
+		case a.Type == mptcpAttrSPort && len(a.Data) == 2:
+			// Ports are in network byte order
+			sport = int(binary.BigEndian.Uint16(a.Data))
+		case a.Type == mptcpAttrDPort && len(a.Data) == 2:
+			dport = int(binary.BigEndian.Uint16(a.Data))
+		case a.Type == mptcpAttrBackup && len(a.Data) == 1:
+			e.Backup = a.Data[0] != 0
+		case a.Type == mptcpAttrServerSide && len(a.Data) == 1:
+			e.ServerSide = a.Data[0] != 0
+		}
+	}
+
+	if sip != nil {
+		e.LocalAddr = &net.TCPAddr{IP: sip, Port: sport}
+	}
+	if dip != nil {
+		e.RemoteAddr = &net.TCPAddr{IP: dip, Port: dport}
+	}
+
+	return e, nil
+}
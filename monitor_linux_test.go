@@ -0,0 +1,139 @@
+// +build linux
+
+package mptcp
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestLinux_parseEventMessage verifies that parseEventMessage parses the
+// type and attributes of MPTCP path manager events.
+func TestLinux_parseEventMessage(t *testing.T) {
+	u32 := func(v uint32) []byte {
+		b := make([]byte, 4)
+		binary.NativeEndian.PutUint32(b, v)
+		return b
+	}
+	port := func(v uint16) []byte {
+		b := make([]byte, 2)
+		binary.BigEndian.PutUint16(b, v)
+		return b
+	}
+
+	var tests = []struct {
+		desc string
+		b    []byte
+		e    Event
+		err  error
+	}{
+		{desc: "short header", b: []byte{0x01}, err: errInvalidNetlinkMessage},
+		{
+			desc: "IPv4 subflow established",
+			b: genericMessage(uint8(EventSubflowEstablished), []netlinkAttribute{
+				{Type: mptcpAttrToken, Data: u32(0xdeadbeef)},
+				{Type: mptcpAttrLocID, Data: []byte{1}},
+				{Type: mptcpAttrRemID, Data: []byte{2}},
+				{Type: mptcpAttrSAddr4, Data: []byte{192, 0, 2, 1}},
+				{Type: mptcpAttrDAddr4, Data: []byte{192, 0, 2, 2}},
+				{Type: mptcpAttrSPort, Data: port(8080)},
+				{Type: mptcpAttrDPort, Data: port(40000)},
+				{Type: mptcpAttrBackup, Data: []byte{1}},
+			}),
+			e: Event{
+				Type:         EventSubflowEstablished,
+				Token:        0xdeadbeef,
+				LocalAddr:    &net.TCPAddr{IP: net.IP{192, 0, 2, 1}, Port: 8080},
+				RemoteAddr:   &net.TCPAddr{IP: net.IP{192, 0, 2, 2}, Port: 40000},
+				LocalAddrID:  1,
+				RemoteAddrID: 2,
+				Backup:       true,
+			},
+		},
+		{
+			desc: "IPv6 address announced",
+			b: genericMessage(uint8(EventAnnounced), []netlinkAttribute{
+				{Type: mptcpAttrToken, Data: u32(1)},
+				{Type: mptcpAttrRemID, Data: []byte{3}},
+				{Type: mptcpAttrDAddr6, Data: net.ParseIP("2001:db8::1")},
+				{Type: mptcpAttrDPort, Data: port(443)},
+			}),
+			e: Event{
+				Type:         EventAnnounced,
+				Token:        1,
+				RemoteAddr:   &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 443},
+				RemoteAddrID: 3,
+			},
+		},
+		{
+			desc: "created, server side",
+			b: genericMessage(uint8(EventCreated), []netlinkAttribute{
+				{Type: mptcpAttrToken, Data: u32(2)},
+				{Type: mptcpAttrServerSide, Data: []byte{1}},
+			}),
+			e: Event{Type: EventCreated, Token: 2, ServerSide: true},
+		},
+	}
+
+	for i, test := range tests {
+		e, err := parseEventMessage(test.b)
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected err: %v != %v [test: %v]", i, err, test.err, test.desc)
+		}
+
+		if !reflect.DeepEqual(e, test.e) {
+			t.Fatalf("[%02d] unexpected event: %#v != %#v [test: %v]", i, e, test.e, test.desc)
+		}
+	}
+}
+
+// TestLinux_Monitor verifies that a Monitor reports the events of a real
+// loopback MPTCP connection, and stops once its context is canceled.
+func TestLinux_Monitor(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m, err := NewMonitor(ctx)
+	if err != nil {
+		if errors.Is(err, syscall.ENOENT) || errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.EACCES) || os.IsPermission(err) {
+			t.Skipf("skipping, cannot monitor MPTCP events: %v", err)
+		}
+
+		t.Fatalf("failed to create monitor: %v", err)
+	}
+
+	client, _ := testDialPair(t, &Dialer{})
+	if ok, err := client.(*net.TCPConn).MultipathTCP(); err != nil || !ok {
+		t.Skip("skipping, connection does not use MPTCP")
+	}
+
+	info, err := Info(client)
+	if err != nil {
+		t.Skipf("skipping, cannot retrieve connection token: %v", err)
+	}
+
+	timeout := time.After(5 * time.Second)
+	for found := false; !found; {
+		select {
+		case e := <-m.Events():
+			found = e.Type == EventCreated && e.Token == info.LocalToken
+		case <-timeout:
+			t.Fatal("timed out waiting for connection created event")
+		}
+	}
+
+	cancel()
+	for range m.Events() {
+	}
+
+	if err := m.Err(); err != context.Canceled {
+		t.Fatalf("unexpected Err: %v != %v", err, context.Canceled)
+	}
+}
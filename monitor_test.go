@@ -0,0 +1,131 @@
+package mptcp
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// mockEventReceiver is an eventReceiver which returns each batch of events
+// from batches in order, and then blocks until it is closed.
+type mockEventReceiver struct {
+	batches [][]Event
+	err     error
+	closed  chan struct{}
+}
+
+// receive implements eventReceiver.
+func (r *mockEventReceiver) receive() ([]Event, error) {
+	if len(r.batches) > 0 {
+		b := r.batches[0]
+		r.batches = r.batches[1:]
+		return b, nil
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	<-r.closed
+	return nil, errors.New("receiver closed")
+}
+
+// Close implements eventReceiver.
+func (r *mockEventReceiver) Close() error {
+	close(r.closed)
+	return nil
+}
+
+// TestMonitor verifies that a Monitor delivers each received event in order,
+// and reports the error which stopped it once its channel is closed.
+func TestMonitor(t *testing.T) {
+	origDial := dialMonitor
+	defer func() { dialMonitor = origDial }()
+
+	events := []Event{
+		{Type: EventCreated, Token: 1},
+		{Type: EventEstablished, Token: 1},
+		{Type: EventClosed, Token: 1},
+	}
+	errReceive := errors.New("receive failed")
+
+	var tests = []struct {
+		desc   string
+		err    error
+		cancel bool
+		wErr   error
+	}{
+		{desc: "receive error", err: errReceive, wErr: errReceive},
+		{desc: "canceled", cancel: true, wErr: context.Canceled},
+	}
+
+	for i, test := range tests {
+		r := &mockEventReceiver{
+			batches: [][]Event{events[:2], events[2:]},
+			err:     test.err,
+			closed:  make(chan struct{}),
+		}
+		dialMonitor = func() (eventReceiver, error) {
+			return r, nil
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		m, err := NewMonitor(ctx)
+		if err != nil {
+			t.Fatalf("[%02d] unexpected err: %v [test: %v]", i, err, test.desc)
+		}
+
+		var got []Event
+		for e := range m.Events() {
+			got = append(got, e)
+			if test.cancel && len(got) == len(events) {
+				cancel()
+			}
+		}
+		cancel()
+
+		if !reflect.DeepEqual(got, events) {
+			t.Fatalf("[%02d] unexpected events: %v != %v [test: %v]", i, got, events, test.desc)
+		}
+
+		if err := m.Err(); err != test.wErr {
+			t.Fatalf("[%02d] unexpected Err: %v != %v [test: %v]", i, err, test.wErr, test.desc)
+		}
+	}
+}
+
+// TestNewMonitorError verifies that NewMonitor returns an error when it
+// cannot subscribe to events.
+func TestNewMonitorError(t *testing.T) {
+	origDial := dialMonitor
+	defer func() { dialMonitor = origDial }()
+
+	errDial := errors.New("dial failed")
+	dialMonitor = func() (eventReceiver, error) {
+		return nil, errDial
+	}
+
+	if _, err := NewMonitor(context.Background()); err != errDial {
+		t.Fatalf("unexpected err: %v != %v", err, errDial)
+	}
+}
+
+// TestEventTypeString verifies that EventType values are named as they are
+// by the kernel.
+func TestEventTypeString(t *testing.T) {
+	var tests = []struct {
+		t    EventType
+		want string
+	}{
+		{EventCreated, "CREATED"},
+		{EventSubflowEstablished, "SUB_ESTABLISHED"},
+		{EventType(99), "EventType(99)"},
+	}
+
+	for i, test := range tests {
+		if got := test.t.String(); got != test.want {
+			t.Fatalf("[%02d] unexpected string: %q != %q", i, got, test.want)
+		}
+	}
+}
@@ -3,12 +3,23 @@
 //
 // This package is inspired by the original, PHP-based multipath TCP detection
 // functions, courtesy of Christoph Paasch and http://multipath-tcp.org/.
+//
+// Detection of individual connections is fully implemented for Linux.  On
+// Darwin, Enabled reports the net.inet.mptcp.enable sysctl, and Check,
+// CheckLocal, ListConnections, and the other functions built on them read
+// connections from the net.inet.mptcp.pcblist sysctl.  Its structures are
+// private to xnu, so they are only decoded on macOS 10.13 and later, whose
+// layout is known, and ErrNotImplemented is returned on earlier releases.
 package mptcp
 
 import (
+	"context"
 	"errors"
+	"io"
+	"math"
 	"net"
 	"strconv"
+	"strings"
 )
 
 var (
@@ -16,13 +27,44 @@ var (
 	// a function.
 	ErrInvalidIPAddress = errors.New("invalid IP address")
 
-	// ErrIPv6NotImplemented is returned when an IPv6 address is passed to a
-	// function, because IPv6 detection is not yet implemented.
+	// ErrIPv6NotImplemented was returned when an IPv6 address was passed to a
+	// function, before IPv6 detection was implemented.
+	//
+	// Deprecated: IPv6 detection is implemented, and this error is no longer
+	// returned.
 	ErrIPv6NotImplemented = errors.New("IPv6 detection not yet implemented")
 
 	// ErrNotImplemented is returned when MPTCP detection functionality is not
 	// implemented for the current operating system.
 	ErrNotImplemented = errors.New("not implemented")
+
+	// ErrTableTooLarge is returned when a connections table exceeds the
+	// maximum number of bytes which may be read from it.
+	ErrTableTooLarge = errors.New("connections table too large")
+
+	// ErrInvalidInterval is returned when a polling interval which is not
+	// positive is passed to a function.
+	ErrInvalidInterval = errors.New("invalid polling interval")
+
+	// ErrNoParseableRows is returned when a connections table has a valid
+	// header, but none of its rows can be parsed.  It is only returned by a
+	// Checker configured using WithSkipInvalidRows, and the returned error
+	// also wraps the error for the first row of the table.
+	ErrNoParseableRows = errors.New("no parseable rows in connections table")
+
+	// ErrNoFileDescriptor is returned when a net.Conn which does not expose
+	// its underlying file descriptor, such as one returned by net.Pipe, is
+	// passed to a function.
+	ErrNoFileDescriptor = errors.New("connection has no file descriptor")
+
+	// ErrNotChecked is returned by FromContext when a context does not
+	// carry the result of a multipath TCP check.
+	ErrNotChecked = errors.New("connection not checked for multipath TCP")
+
+	// ErrNoInfo is returned by Info when a connection's socket does not
+	// report multipath TCP information, because it was not created using
+	// the operating system's multipath TCP protocol.
+	ErrNoInfo = errors.New("no multipath TCP information for connection")
 )
 
 // Enabled returns whether or the current host supports multipath TCP.
@@ -30,10 +72,23 @@ var (
 // If it is not enabled on this host, or an error occurs, this function will
 // return false.
 //
+// Enabled reports the capability of the host, and does not depend on any
+// multipath TCP connections being active.  On Linux, the net.mptcp.enabled
+// sysctl is authoritative when present.  Otherwise, it consults the net.mptcp
+// sysctl directory and the MPTCP path manager netlink family, and falls back
+// to the presence of the legacy connections table.  On Darwin, it reads the
+// net.inet.mptcp.enable sysctl.
+//
 // It is recommended to check the result of Enabled before attempting to check
 // for active multipath TCP connections using Check.
 func Enabled() (bool, error) {
-	return mptcpEnabled()
+	return EnabledContext(context.Background())
+}
+
+// EnabledContext is like Enabled, but stops consulting the host's multipath
+// TCP capability signals once ctx is done, and returns the error of ctx.
+func EnabledContext(ctx context.Context) (bool, error) {
+	return mptcpEnabled(ctx)
 }
 
 // Check detects if there is an active multipath TCP connection to this machine,
@@ -50,7 +105,9 @@ func Enabled() (bool, error) {
 //
 // If multipath TCP detection is implemented on the current operating system,
 // this function will return true or false, depending on if a connection with
-// the input host:port string is active and is using multipath TCP.
+// the input host:port string is active and is using multipath TCP.  On Linux
+// kernels with mainline MPTCP support, which do not publish a connections
+// table, active connections are retrieved using netlink instead.
 func Check(hostport string) (bool, error) {
 	// Split input hostport pair
 	host, port, err := net.SplitHostPort(hostport)
@@ -65,5 +122,441 @@ func Check(hostport string) (bool, error) {
 	}
 
 	// Check for multipath TCP connectivity
-	return checkMPTCP(host, uint16(uPort))
+	return CheckContext(context.Background(), host, uint16(uPort))
+}
+
+// CheckContext is like Check, but accepts a separate host IP address string
+// and port, and stops reading the connections table once ctx is done.  A
+// table scan which is interrupted returns the error of ctx, such as
+// context.Canceled or context.DeadlineExceeded, and the table is not opened
+// at all if ctx is already done.
+//
+// Concurrent calls to Check for the same host and port share a single table
+// scan, but calls to CheckContext with a context which may be canceled
+// always perform their own scan.
+//
+// If multipath TCP detection is not implemented for the current operating system,
+// this function will return ErrNotImplemented.
+func CheckContext(ctx context.Context, host string, port uint16) (bool, error) {
+	return checkMPTCP(ctx, host, port)
+}
+
+// CheckConn detects if the input connection, such as one accepted by a
+// net.Listener, is an active multipath TCP connection to this machine, using
+// the connection's remote address.  It is equivalent to calling Check with the
+// result of the connection's RemoteAddr method.
+//
+// Connections owned by this process whose socket reports multipath TCP
+// information, such as those accepted by a net.Listener returned by Listen,
+// are checked using Info instead, without reading the connections table.
+//
+// Remote addresses of type *net.TCPAddr are used directly, and other remote
+// addresses are parsed from their host:port string form.  IPv6 zone
+// identifiers are ignored, as they do not appear in the connections table.
+// If the remote address cannot be parsed, ErrInvalidIPAddress is returned.
+//
+// If multipath TCP detection is not implemented for the current operating system,
+// this function will return ErrNotImplemented.
+func CheckConn(c net.Conn) (bool, error) {
+	return CheckConnContext(context.Background(), c)
+}
+
+// CheckConnContext is like CheckConn, but stops reading the connections table
+// once ctx is done, in the same way as CheckContext.
+func CheckConnContext(ctx context.Context, c net.Conn) (bool, error) {
+	// Connections owned by this process which were created using the
+	// operating system's MPTCP protocol can be checked using their socket,
+	// without reading the connections table
+	if info, err := Info(c); err == nil {
+		return !info.Fallback, nil
+	}
+
+	host, port, err := connRemoteHostPort(c)
+	if err != nil {
+		return false, err
+	}
+
+	return CheckContext(ctx, host, port)
+}
+
+// connRemoteHostPort returns the remote host IP address string and port of
+// an input connection, without any IPv6 zone identifier.
+func connRemoteHostPort(c net.Conn) (string, uint16, error) {
+	addr := c.RemoteAddr()
+	if tcpAddr, ok := addr.(*net.TCPAddr); ok {
+		if tcpAddr == nil || tcpAddr.IP == nil || tcpAddr.Port < 0 || tcpAddr.Port > math.MaxUint16 {
+			return "", 0, ErrInvalidIPAddress
+		}
+
+		return tcpAddr.IP.String(), uint16(tcpAddr.Port), nil
+	}
+
+	if addr == nil {
+		return "", 0, ErrInvalidIPAddress
+	}
+
+	// Parse other addresses from their string form
+	return parseHostPort(addr.String())
+}
+
+// parseHostPort parses an input host:port string into its host IP address
+// string and port, without any IPv6 zone identifier.
+func parseHostPort(hostport string) (string, uint16, error) {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return "", 0, ErrInvalidIPAddress
+	}
+
+	uPort, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return "", 0, ErrInvalidIPAddress
+	}
+
+	host, _, _ = strings.Cut(host, "%")
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return "", 0, ErrInvalidIPAddress
+	}
+
+	return ip.String(), uint16(uPort), nil
+}
+
+// CheckEstablished detects if there is an established multipath TCP
+// connection to this machine, originating from the input host:port string.
+// Unlike Check, connections which are half-open or closing, such as those
+// in StateSynRecv or StateFinWait1, are not reported as a match.
+//
+// If multipath TCP detection is not implemented for the current operating system,
+// this function will return ErrNotImplemented.
+func CheckEstablished(hostport string) (bool, error) {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return false, err
+	}
+
+	uPort, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return false, err
+	}
+
+	conns, err := FindAll(host, uint16(uPort))
+	if err != nil {
+		return false, err
+	}
+
+	return len(filterStates(conns, []State{StateEstablished})) > 0, nil
+}
+
+// CheckLocal detects if there is an active multipath TCP connection on this
+// machine which is bound to the input local host and port, such as a subflow
+// accepted by a server on one of its addresses.  Unlike Check, which matches
+// the remote address of each connection, CheckLocal matches only the local
+// address.
+//
+// If host is not a valid IP address, ErrInvalidIPAddress is returned.
+//
+// If multipath TCP detection is not implemented for the current operating system,
+// this function will return ErrNotImplemented.
+func CheckLocal(host string, port uint16) (bool, error) {
+	return checkLocalMPTCP(host, port)
+}
+
+// IsFDMPTCP reports whether the socket with the input file descriptor, which
+// must be owned by the current process, is a multipath TCP connection.  This
+// is the most direct way for a process to check its own sockets.
+//
+// If multipath TCP detection is not implemented for the current operating system,
+// this function will return ErrNotImplemented.
+func IsFDMPTCP(fd int) (bool, error) {
+	return isFDMPTCP(fd)
+}
+
+// OpenRawTable opens this host's raw multipath TCP connections table, so that
+// its contents may be streamed elsewhere without being buffered or parsed.
+// The caller is responsible for closing the returned io.ReadCloser.
+//
+// As with the other package-level functions, the table at the path set using
+// the ProcPathEnv environment variable is opened instead, if it was set when
+// the program started.
+//
+// On Linux, ports in the raw table are written identically on every host, but
+// each 32-bit word of an address is written in the host's byte order.  A raw
+// table captured on a host with a different byte order will not decode to the
+// same addresses.
+//
+// If multipath TCP detection is not implemented for the current operating system,
+// this function will return ErrNotImplemented.
+func OpenRawTable() (io.ReadCloser, error) {
+	return defaultChecker.openRawTable()
+}
+
+// FindAll returns every active multipath TCP connection to this machine which
+// originates from the input host and port, such as each subflow of a
+// connection from a single remote peer.  If no connections match, FindAll
+// returns no connections and no error.
+//
+// If host is not a valid IP address, ErrInvalidIPAddress is returned.
+//
+// If multipath TCP detection is not implemented for the current operating system,
+// this function will return ErrNotImplemented.
+func FindAll(host string, port uint16) ([]Connection, error) {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, ErrInvalidIPAddress
+	}
+
+	conns, err := listConnections()
+	if err != nil {
+		return nil, err
+	}
+
+	return findConnections(conns, ip, port), nil
+}
+
+// findConnections returns the connections whose remote address matches the
+// input IP address and port.
+func findConnections(conns []Connection, ip net.IP, port uint16) []Connection {
+	var out []Connection
+	for _, c := range conns {
+		if c.RemoteAddr.IP.Equal(ip) && c.RemoteAddr.Port == int(port) {
+			out = append(out, c)
+		}
+	}
+
+	return out
+}
+
+// FindByEndpoint returns every active multipath TCP connection on this host
+// which uses the input IP address and port as either its local or remote
+// address, for callers which know only one endpoint of a connection.  If no
+// connections match, FindByEndpoint returns no connections and no error.
+//
+// If ip is not a valid IP address, ErrInvalidIPAddress is returned.
+//
+// If multipath TCP detection is not implemented for the current operating system,
+// this function will return ErrNotImplemented.
+func FindByEndpoint(ip net.IP, port uint16) ([]Connection, error) {
+	if len(ip) != net.IPv4len && len(ip) != net.IPv6len {
+		return nil, ErrInvalidIPAddress
+	}
+
+	conns, err := listConnections()
+	if err != nil {
+		return nil, err
+	}
+
+	return findEndpointConnections(conns, ip, port), nil
+}
+
+// findEndpointConnections returns the connections whose local or remote
+// address matches the input IP address and port.
+func findEndpointConnections(conns []Connection, ip net.IP, port uint16) []Connection {
+	matches := func(addr *net.TCPAddr) bool {
+		return addr != nil && addr.IP.Equal(ip) && addr.Port == int(port)
+	}
+
+	var out []Connection
+	for _, c := range conns {
+		if matches(c.LocalAddr) || matches(c.RemoteAddr) {
+			out = append(out, c)
+		}
+	}
+
+	return out
+}
+
+// ListConnections returns all active multipath TCP connections on this host.
+//
+// If multipath TCP detection is not implemented for the current operating system,
+// this function will return ErrNotImplemented.
+func ListConnections() ([]Connection, error) {
+	return listConnections()
+}
+
+// Connections returns all active multipath TCP connections on this host,
+// including the decoded addresses, address family, state, and tokens of each
+// connection.  It is identical to ListConnections, except that a table with
+// no connections always results in an empty, non-nil slice.  The addresses of
+// each connection are also available as netip.AddrPort values, using its
+// LocalAddrPort and RemoteAddrPort methods.
+//
+// If multipath TCP detection is not implemented for the current operating system,
+// this function will return ErrNotImplemented.
+func Connections() ([]Connection, error) {
+	conns, err := listConnections()
+	if err != nil {
+		return nil, err
+	}
+
+	if conns == nil {
+		conns = []Connection{}
+	}
+
+	return conns, nil
+}
+
+// ConnectionsChan returns a channel which emits each active multipath TCP
+// connection on this host as it is read, for use in data processing
+// pipelines.  The connections channel is closed once all connections are
+// emitted, or if an error occurs, or if ctx is canceled.
+//
+// The error channel emits at most one error, and is closed after the
+// connections channel.  If ctx is canceled before all connections are
+// emitted, the error channel emits the context's error.
+//
+// If multipath TCP detection is not implemented for the current operating system,
+// the error channel will emit ErrNotImplemented.
+func ConnectionsChan(ctx context.Context) (<-chan Connection, <-chan error) {
+	connC := make(chan Connection)
+	errC := make(chan error, 1)
+
+	go func() {
+		defer close(errC)
+		defer close(connC)
+
+		var canceled bool
+		err := forEachConnection(func(c Connection) bool {
+			select {
+			case connC <- c:
+				return true
+			case <-ctx.Done():
+				canceled = true
+				return false
+			}
+		})
+		if err == nil && canceled {
+			err = ctx.Err()
+		}
+
+		if err != nil {
+			errC <- err
+		}
+	}()
+
+	return connC, errC
+}
+
+// ListConnectionsByInterface returns all active multipath TCP connections on
+// this host whose local address belongs to the network interface with the
+// input name.  All addresses assigned to the interface are considered.
+//
+// If multipath TCP detection is not implemented for the current operating system,
+// this function will return ErrNotImplemented.
+func ListConnectionsByInterface(ifaceName string) ([]Connection, error) {
+	// Resolve all addresses assigned to the interface
+	addrs, err := interfaceAddrs(ifaceName)
+	if err != nil {
+		return nil, err
+	}
+
+	ips := make([]net.IP, 0, len(addrs))
+	for _, a := range addrs {
+		switch a := a.(type) {
+		case *net.IPNet:
+			ips = append(ips, a.IP)
+		case *net.IPAddr:
+			ips = append(ips, a.IP)
+		}
+	}
+
+	conns, err := listConnections()
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep only connections with a local address on this interface
+	var out []Connection
+	for _, c := range conns {
+		for _, ip := range ips {
+			if c.LocalAddr.IP.Equal(ip) {
+				out = append(out, c)
+				break
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// ListDegradedConnections returns all active multipath TCP connections on
+// this host which are using only a single subflow, as reported by
+// Connection.IsSinglePath.
+//
+// If multipath TCP detection is not implemented for the current operating system,
+// this function will return ErrNotImplemented.
+func ListDegradedConnections() ([]Connection, error) {
+	conns, err := listConnections()
+	if err != nil {
+		return nil, err
+	}
+
+	var degraded []Connection
+	for _, c := range conns {
+		if c.IsSinglePath() {
+			degraded = append(degraded, c)
+		}
+	}
+
+	return degraded, nil
+}
+
+// TotalQueuedBytes returns the total number of bytes queued for transmission
+// and awaiting receipt across all active multipath TCP connections on this
+// host, as a compact indicator of system-wide backpressure.  The table is read
+// in a single pass, without collecting connections.
+//
+// Sums which would overflow a uint64 are capped at the maximum uint64 value.
+// Queue lengths are only reported by connections tables, so zero is returned
+// on Linux kernels with mainline MPTCP support, which do not publish one.
+//
+// If multipath TCP detection is not implemented for the current operating system,
+// this function will return ErrNotImplemented.
+func TotalQueuedBytes() (tx, rx uint64, err error) {
+	return totalQueuedBytes()
+}
+
+// VerifyLocalAddrs verifies that each of the input expected local addresses
+// is being used by at least one active multipath TCP connection on this host.
+//
+// The expected addresses which are not being used by any connection are
+// returned, in the order they were passed.  If all expected addresses are in
+// use, an empty slice is returned.
+//
+// If multipath TCP detection is not implemented for the current operating system,
+// this function will return ErrNotImplemented.
+func VerifyLocalAddrs(expected []net.IP) ([]net.IP, error) {
+	conns, err := listConnections()
+	if err != nil {
+		return nil, err
+	}
+
+	unused := make([]net.IP, 0, len(expected))
+	for _, ip := range expected {
+		found := false
+		for _, c := range conns {
+			if c.LocalAddr.IP.Equal(ip) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			unused = append(unused, ip)
+		}
+	}
+
+	return unused, nil
+}
+
+// interfaceAddrs returns the addresses assigned to the network interface
+// with the input name.
+//
+// This implementation is swappable for testing with a mock data source.
+var interfaceAddrs = func(name string) ([]net.Addr, error) {
+	ifi, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return ifi.Addrs()
 }
@@ -1,8 +1,10 @@
 package mptcp
 
 import (
+	"context"
 	"errors"
 	"net"
+	"reflect"
 	"strconv"
 	"testing"
 )
@@ -41,7 +43,7 @@ var hostPorts = map[string]uint16{
 // underlying implementation.
 func TestEnabled(t *testing.T) {
 	// Check function result immediately
-	enabled, err := mptcpEnabled()
+	enabled, err := mptcpEnabled(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -87,23 +89,23 @@ func TestCheck(t *testing.T) {
 		{ipv4HostOne, hostPorts[ipv4HostOne], true, nil},
 		{ipv4HostTwo, hostPorts[ipv4HostTwo], true, nil},
 
-		// IPv6 (not yet implemented)
+		// IPv6
 
 		// Invalid hosts, invalid ports
-		{ipv6BadHostOne, 8080, false, ErrIPv6NotImplemented},
-		{ipv6BadHostTwo, 6060, false, ErrIPv6NotImplemented},
+		{ipv6BadHostOne, 8080, false, nil},
+		{ipv6BadHostTwo, 6060, false, nil},
 
 		// Valid hosts, invalid ports
-		{ipv6HostOne, 1, false, ErrIPv6NotImplemented},
-		{ipv6HostTwo, 10000, false, ErrIPv6NotImplemented},
+		{ipv6HostOne, 1, false, nil},
+		{ipv6HostTwo, 10000, false, nil},
 
 		// Invalid hosts, valid ports
-		{ipv6BadHostOne, hostPorts[ipv6HostOne], false, ErrIPv6NotImplemented},
-		{ipv6BadHostTwo, hostPorts[ipv6HostTwo], false, ErrIPv6NotImplemented},
+		{ipv6BadHostOne, hostPorts[ipv6HostOne], false, nil},
+		{ipv6BadHostTwo, hostPorts[ipv6HostTwo], false, nil},
 
 		// Valid hosts, valid ports
-		{ipv6HostOne, hostPorts[ipv6HostOne], false, ErrIPv6NotImplemented},
-		{ipv6HostTwo, hostPorts[ipv6HostTwo], false, ErrIPv6NotImplemented},
+		{ipv6HostOne, hostPorts[ipv6HostOne], true, nil},
+		{ipv6HostTwo, hostPorts[ipv6HostTwo], true, nil},
 	}
 
 	for i, test := range tests {
@@ -121,6 +123,87 @@ func TestCheck(t *testing.T) {
 	}
 }
 
+// stringAddr is a net.Addr which is only available in its string form.
+type stringAddr string
+
+// Network implements net.Addr.
+func (a stringAddr) Network() string { return "tcp" }
+
+// String implements net.Addr.
+func (a stringAddr) String() string { return string(a) }
+
+// TestCheckConn verifies that CheckConn checks the remote address of a
+// connection, regardless of the type of the address.
+func TestCheckConn(t *testing.T) {
+	type hostPort struct {
+		host string
+		port uint16
+	}
+
+	var got hostPort
+	origCheck := checkMPTCP
+	defer func() { checkMPTCP = origCheck }()
+	checkMPTCP = func(ctx context.Context, host string, port uint16) (bool, error) {
+		got = hostPort{host, port}
+		return true, nil
+	}
+
+	var tests = []struct {
+		desc   string
+		remote net.Addr
+		want   hostPort
+		err    error
+	}{
+		{"IPv4 TCP address", &net.TCPAddr{IP: net.ParseIP(ipv4HostOne), Port: 8080}, hostPort{ipv4HostOne, 8080}, nil},
+		{"IPv6 TCP address", &net.TCPAddr{IP: net.ParseIP(ipv6HostOne), Port: 8080}, hostPort{ipv6HostOne, 8080}, nil},
+		{"IPv6 TCP address with zone", &net.TCPAddr{IP: net.ParseIP("fe80::1"), Port: 8080, Zone: "eth0"}, hostPort{"fe80::1", 8080}, nil},
+		{"IPv4 string address", stringAddr("8.8.8.8:8080"), hostPort{ipv4HostOne, 8080}, nil},
+		{"IPv6 string address", stringAddr("[2001:4860:4860::8888]:8080"), hostPort{ipv6HostOne, 8080}, nil},
+		{"IPv6 string address with zone", stringAddr("[fe80::1%eth0]:8080"), hostPort{"fe80::1", 8080}, nil},
+		{"no address", nil, hostPort{}, ErrInvalidIPAddress},
+		{"nil TCP address", (*net.TCPAddr)(nil), hostPort{}, ErrInvalidIPAddress},
+		{"TCP address without IP", &net.TCPAddr{Port: 8080}, hostPort{}, ErrInvalidIPAddress},
+		{"string address without port", stringAddr("8.8.8.8"), hostPort{}, ErrInvalidIPAddress},
+		{"string address with invalid port", stringAddr("8.8.8.8:65536"), hostPort{}, ErrInvalidIPAddress},
+		{"string address with invalid host", stringAddr("foo:8080"), hostPort{}, ErrInvalidIPAddress},
+	}
+
+	for i, test := range tests {
+		got = hostPort{}
+
+		ok, err := CheckConn(&mockConn{remote: test.remote})
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected err: %v != %v [test: %v]", i, err, test.err, test.desc)
+		}
+
+		if ok != (test.err == nil) {
+			t.Fatalf("[%02d] unexpected ok: %v [test: %v]", i, ok, test.desc)
+		}
+
+		if got != test.want {
+			t.Fatalf("[%02d] unexpected checked host:port: %v != %v [test: %v]", i, got, test.want, test.desc)
+		}
+	}
+}
+
+// TestCheckConnContext verifies that CheckConnContext passes its context to
+// the underlying check.
+func TestCheckConnContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	origCheck := checkMPTCP
+	defer func() { checkMPTCP = origCheck }()
+	checkMPTCP = func(ctx context.Context, host string, port uint16) (bool, error) {
+		return false, ctx.Err()
+	}
+
+	c := &mockConn{remote: &net.TCPAddr{IP: net.ParseIP(ipv4HostOne), Port: 8080}}
+	if _, err := CheckConnContext(ctx, c); err != context.Canceled {
+		t.Fatalf("unexpected err: %v != %v", err, context.Canceled)
+	}
+}
+
 // TestCheckSplitHostPort verifies that Check properly splits a hostport
 // string, and returns the proper results.
 func TestCheckSplitHostPort(t *testing.T) {
@@ -172,3 +255,381 @@ func TestCheckSplitHostPort(t *testing.T) {
 		}
 	}
 }
+
+// TestListConnectionsByInterface verifies that ListConnectionsByInterface
+// only returns connections with a local address on the named interface,
+// using mock interface address and connection sources.
+func TestListConnectionsByInterface(t *testing.T) {
+	// Swap in mock interface addresses, with multiple addresses on eth0
+	origAddrs := interfaceAddrs
+	defer func() { interfaceAddrs = origAddrs }()
+
+	ifaces := map[string][]net.Addr{
+		"eth0": {
+			&net.IPNet{IP: net.ParseIP("192.168.1.10"), Mask: net.CIDRMask(24, 32)},
+			&net.IPNet{IP: net.ParseIP("2001:db8::10"), Mask: net.CIDRMask(64, 128)},
+		},
+		"eth1": {
+			&net.IPAddr{IP: net.ParseIP("10.0.0.10")},
+		},
+		"eth2": nil,
+	}
+	interfaceAddrs = func(name string) ([]net.Addr, error) {
+		addrs, ok := ifaces[name]
+		if !ok {
+			return nil, errors.New("no such network interface")
+		}
+
+		return addrs, nil
+	}
+
+	// Swap in mock connections, using one local address of each family
+	// on eth0, and one on eth1
+	origList := listConnections
+	defer func() { listConnections = origList }()
+
+	conns := []Connection{
+		{LocalAddr: &net.TCPAddr{IP: net.ParseIP("192.168.1.10").To4(), Port: 80}},
+		{LocalAddr: &net.TCPAddr{IP: net.ParseIP("2001:db8::10"), Port: 80}, IsIPv6: true},
+		{LocalAddr: &net.TCPAddr{IP: net.ParseIP("10.0.0.10").To4(), Port: 80}},
+	}
+	listConnections = func() ([]Connection, error) {
+		return conns, nil
+	}
+
+	var tests = []struct {
+		iface string
+		conns []Connection
+		err   bool
+	}{
+		// Unknown interface
+		{"foo0", nil, true},
+		// Interface without addresses
+		{"eth2", nil, false},
+		// Interface with multiple addresses
+		{"eth0", conns[:2], false},
+		// Interface with a single address
+		{"eth1", conns[2:], false},
+	}
+
+	for i, test := range tests {
+		got, err := ListConnectionsByInterface(test.iface)
+		if (err != nil) != test.err {
+			t.Fatalf("[%02d] unexpected err: %v [test: %v]", i, err, test)
+		}
+
+		if !reflect.DeepEqual(got, test.conns) {
+			t.Fatalf("[%02d] unexpected conns: %v != %v [test: %v]", i, got, test.conns, test)
+		}
+	}
+}
+
+// TestVerifyLocalAddrs verifies that VerifyLocalAddrs returns only the
+// expected local addresses which are not in use by any connection, using
+// a mock connection source.
+func TestVerifyLocalAddrs(t *testing.T) {
+	origList := listConnections
+	defer func() { listConnections = origList }()
+
+	listConnections = func() ([]Connection, error) {
+		return []Connection{
+			{LocalAddr: &net.TCPAddr{IP: net.ParseIP("192.168.1.10").To4(), Port: 80}},
+			{LocalAddr: &net.TCPAddr{IP: net.ParseIP("192.168.1.10").To4(), Port: 443}},
+			{LocalAddr: &net.TCPAddr{IP: net.ParseIP("2001:db8::10"), Port: 80}, IsIPv6: true},
+		}, nil
+	}
+
+	var (
+		usedIPv4   = net.ParseIP("192.168.1.10")
+		usedIPv6   = net.ParseIP("2001:db8::10")
+		unusedIPv4 = net.ParseIP("10.0.0.10")
+		unusedIPv6 = net.ParseIP("2001:db8::20")
+	)
+
+	var tests = []struct {
+		expected []net.IP
+		unused   []net.IP
+	}{
+		// No expected addresses
+		{nil, []net.IP{}},
+		// All expected addresses in use
+		{[]net.IP{usedIPv4, usedIPv6}, []net.IP{}},
+		// No expected addresses in use
+		{[]net.IP{unusedIPv4, unusedIPv6}, []net.IP{unusedIPv4, unusedIPv6}},
+		// Some expected addresses in use
+		{[]net.IP{usedIPv4, unusedIPv4, usedIPv6, unusedIPv6}, []net.IP{unusedIPv4, unusedIPv6}},
+	}
+
+	for i, test := range tests {
+		unused, err := VerifyLocalAddrs(test.expected)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(unused, test.unused) {
+			t.Fatalf("[%02d] unexpected unused addresses: %v != %v [test: %v]", i, unused, test.unused, test)
+		}
+	}
+}
+
+// TestListDegradedConnections verifies that ListDegradedConnections returns
+// only single-path connections, using a mock connection source.
+func TestListDegradedConnections(t *testing.T) {
+	origList := listConnections
+	defer func() { listConnections = origList }()
+
+	conns := []Connection{
+		{LocalToken: 0x1, Subflows: 1},
+		{LocalToken: 0x2, Subflows: 2},
+		{LocalToken: 0x3, Subflows: 1},
+		{LocalToken: 0x4, Subflows: 4},
+	}
+	listConnections = func() ([]Connection, error) {
+		return conns, nil
+	}
+
+	degraded, err := ListDegradedConnections()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []Connection{conns[0], conns[2]}; !reflect.DeepEqual(degraded, want) {
+		t.Fatalf("unexpected degraded connections: %v != %v", degraded, want)
+	}
+}
+
+// TestFindAll verifies that FindAll returns every connection from the input
+// remote host and port, using a mock connection source.
+func TestFindAll(t *testing.T) {
+	origList := listConnections
+	defer func() { listConnections = origList }()
+
+	remote := func(host string, port int) *net.TCPAddr {
+		return &net.TCPAddr{IP: net.ParseIP(host), Port: port}
+	}
+
+	conns := []Connection{
+		{LocalToken: 0x1, RemoteAddr: remote(ipv4HostOne, 2020)},
+		{LocalToken: 0x2, RemoteAddr: remote(ipv4HostOne, 4040)},
+		{LocalToken: 0x3, RemoteAddr: remote(ipv4HostOne, 2020)},
+		{LocalToken: 0x4, RemoteAddr: remote(ipv4HostTwo, 2020)},
+		{LocalToken: 0x5, RemoteAddr: remote(ipv6HostOne, 2020), IsIPv6: true},
+	}
+	listConnections = func() ([]Connection, error) {
+		return conns, nil
+	}
+
+	var tests = []struct {
+		host  string
+		port  uint16
+		conns []Connection
+		err   error
+	}{
+		// Invalid IP address
+		{"foobar", 2020, nil, ErrInvalidIPAddress},
+		// No matching connections
+		{ipv4HostTwo, 4040, nil, nil},
+		// Multiple matching rows
+		{ipv4HostOne, 2020, []Connection{conns[0], conns[2]}, nil},
+		// Single matching rows
+		{ipv4HostOne, 4040, []Connection{conns[1]}, nil},
+		{ipv6HostOne, 2020, []Connection{conns[4]}, nil},
+	}
+
+	for i, test := range tests {
+		found, err := FindAll(test.host, test.port)
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected err: %v != %v [test: %v]", i, err, test.err, test)
+		}
+
+		if !reflect.DeepEqual(found, test.conns) {
+			t.Fatalf("[%02d] unexpected connections: %v != %v [test: %v]", i, found, test.conns, test)
+		}
+	}
+}
+
+// TestFindByEndpoint verifies that FindByEndpoint returns connections which
+// use an endpoint as either their local or remote address.
+func TestFindByEndpoint(t *testing.T) {
+	origList := listConnections
+	defer func() { listConnections = origList }()
+
+	addr := func(host string, port int) *net.TCPAddr {
+		return &net.TCPAddr{IP: net.ParseIP(host), Port: port}
+	}
+
+	conns := []Connection{
+		// Endpoint appears as the local address
+		{LocalToken: 0x1, LocalAddr: addr(ipv4HostOne, 2020), RemoteAddr: addr(ipv4HostTwo, 4040)},
+		// Endpoint appears as the remote address
+		{LocalToken: 0x2, LocalAddr: addr(ipv4HostTwo, 8080), RemoteAddr: addr(ipv4HostOne, 2020)},
+		// Same IP address with a different port
+		{LocalToken: 0x3, LocalAddr: addr(ipv4HostOne, 4040), RemoteAddr: addr(ipv4HostTwo, 2020)},
+		{LocalToken: 0x4, LocalAddr: addr(ipv6HostOne, 2020), RemoteAddr: addr(ipv6HostTwo, 2020), IsIPv6: true},
+	}
+	listConnections = func() ([]Connection, error) {
+		return conns, nil
+	}
+
+	var tests = []struct {
+		ip    net.IP
+		port  uint16
+		conns []Connection
+		err   error
+	}{
+		// Invalid IP address
+		{nil, 2020, nil, ErrInvalidIPAddress},
+		{net.IP{192, 168}, 2020, nil, ErrInvalidIPAddress},
+		// No matching connections
+		{net.ParseIP(ipv4HostTwo), 9090, nil, nil},
+		// Endpoint appears as local in one row and remote in another
+		{net.ParseIP(ipv4HostOne), 2020, []Connection{conns[0], conns[1]}, nil},
+		// IPv4 addresses match in either form
+		{net.ParseIP(ipv4HostOne).To4(), 4040, []Connection{conns[2]}, nil},
+		// IPv6 local and remote endpoints
+		{net.ParseIP(ipv6HostOne), 2020, []Connection{conns[3]}, nil},
+		{net.ParseIP(ipv6HostTwo), 2020, []Connection{conns[3]}, nil},
+	}
+
+	for i, test := range tests {
+		found, err := FindByEndpoint(test.ip, test.port)
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected err: %v != %v [test: %v]", i, err, test.err, test)
+		}
+
+		if !reflect.DeepEqual(found, test.conns) {
+			t.Fatalf("[%02d] unexpected connections: %v != %v [test: %v]", i, found, test.conns, test)
+		}
+	}
+}
+
+// TestConnections verifies that Connections returns all connections, and an
+// empty, non-nil slice when no connections are active.
+func TestConnections(t *testing.T) {
+	origList := listConnections
+	defer func() { listConnections = origList }()
+
+	errFoo := errors.New("foo")
+	one := []Connection{{LocalToken: 0x1}}
+
+	var tests = []struct {
+		conns []Connection
+		err   error
+		want  []Connection
+	}{
+		// No connections
+		{nil, nil, []Connection{}},
+		{[]Connection{}, nil, []Connection{}},
+		// One connection
+		{one, nil, one},
+		// Error listing connections
+		{nil, errFoo, nil},
+	}
+
+	for i, test := range tests {
+		test := test
+		listConnections = func() ([]Connection, error) {
+			return test.conns, test.err
+		}
+
+		conns, err := Connections()
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected err: %v != %v", i, err, test.err)
+		}
+
+		if (conns == nil) != (test.want == nil) || !reflect.DeepEqual(conns, test.want) {
+			t.Fatalf("[%02d] unexpected connections: %#v != %#v", i, conns, test.want)
+		}
+	}
+}
+
+// TestConnectionsChan verifies that ConnectionsChan emits every connection
+// and closes both channels cleanly, using a mock connection source.
+func TestConnectionsChan(t *testing.T) {
+	origEach := forEachConnection
+	defer func() { forEachConnection = origEach }()
+
+	const n = 100
+	forEachConnection = func(fn func(c Connection) bool) error {
+		for i := 0; i < n; i++ {
+			if !fn(Connection{LocalToken: uint32(i)}) {
+				break
+			}
+		}
+
+		return nil
+	}
+
+	connC, errC := ConnectionsChan(context.Background())
+
+	var count int
+	for c := range connC {
+		if c.LocalToken != uint32(count) {
+			t.Fatalf("unexpected connection order: %v != %v", c.LocalToken, count)
+		}
+		count++
+	}
+	if count != n {
+		t.Fatalf("unexpected connection count: %v != %v", count, n)
+	}
+
+	// Error channel must be closed without emitting an error
+	if err, ok := <-errC; ok {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// TestConnectionsChanError verifies that ConnectionsChan emits an error
+// which stops iteration.
+func TestConnectionsChanError(t *testing.T) {
+	origEach := forEachConnection
+	defer func() { forEachConnection = origEach }()
+
+	errRead := errors.New("read failed")
+	forEachConnection = func(fn func(c Connection) bool) error {
+		fn(Connection{})
+		return errRead
+	}
+
+	connC, errC := ConnectionsChan(context.Background())
+
+	var count int
+	for range connC {
+		count++
+	}
+	if count != 1 {
+		t.Fatalf("unexpected connection count: %v != %v", count, 1)
+	}
+
+	if err := <-errC; err != errRead {
+		t.Fatalf("unexpected error: %v != %v", err, errRead)
+	}
+}
+
+// TestConnectionsChanCanceled verifies that ConnectionsChan stops emitting
+// connections and reports the context's error when canceled.
+func TestConnectionsChanCanceled(t *testing.T) {
+	origEach := forEachConnection
+	defer func() { forEachConnection = origEach }()
+
+	// Emit connections forever, until iteration is stopped
+	forEachConnection = func(fn func(c Connection) bool) error {
+		for fn(Connection{}) {
+		}
+
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	connC, errC := ConnectionsChan(ctx)
+
+	// Receive a single connection, then cancel and drain
+	<-connC
+	cancel()
+	for range connC {
+	}
+
+	if err := <-errC; err != context.Canceled {
+		t.Fatalf("unexpected error: %v != %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,209 @@
+// +build linux
+
+package mptcp
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"syscall"
+)
+
+const (
+	// netlinkReceiveBufferSize is the size of the buffer used to receive
+	// netlink messages, which is large enough for any single dump message.
+	netlinkReceiveBufferSize = 1 << 16
+
+	// netlinkAttributeHeaderLen is the length of a netlink attribute header.
+	netlinkAttributeHeaderLen = 4
+
+	// netlinkAttributeTypeMask masks the nested and byte order flags out of
+	// a netlink attribute type.
+	netlinkAttributeTypeMask = 0x3fff
+
+	// netlinkAttributeNested is the flag set on nested netlink attributes.
+	netlinkAttributeNested = 0x8000
+)
+
+var (
+	// errInvalidNetlinkMessage is returned when a netlink message is not
+	// in the expected format.
+	errInvalidNetlinkMessage = errors.New("invalid netlink message")
+
+	// errInvalidNetlinkAttribute is returned when a netlink attribute is
+	// not in the expected format.
+	errInvalidNetlinkAttribute = errors.New("invalid netlink attribute")
+)
+
+// netlinkConn is a minimal netlink socket, used to query the kernel's
+// MPTCP implementation on mainline Linux kernels.
+type netlinkConn struct {
+	fd  int
+	seq uint32
+}
+
+// dialNetlink opens a netlink socket using the input netlink family.
+func dialNetlink(family int) (*netlinkConn, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, family)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+
+	// Let the kernel assign a port ID to this socket
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		_ = syscall.Close(fd)
+		return nil, os.NewSyscallError("bind", err)
+	}
+
+	return &netlinkConn{
+		fd: fd,
+	}, nil
+}
+
+// Close closes the netlink socket.
+func (c *netlinkConn) Close() error {
+	return syscall.Close(c.fd)
+}
+
+// execute sends a netlink request message with the input type, flags, and
+// data to the kernel, and returns all reply messages.  If the request is a
+// dump request, replies are received until the dump is done.
+func (c *netlinkConn) execute(typ uint16, flags uint16, data []byte) ([]syscall.NetlinkMessage, error) {
+	c.seq++
+	seq := c.seq
+
+	// Prepend a netlink message header to the request data
+	b := make([]byte, syscall.NLMSG_HDRLEN+len(data))
+	binary.NativeEndian.PutUint32(b[0:4], uint32(len(b)))
+	binary.NativeEndian.PutUint16(b[4:6], typ)
+	binary.NativeEndian.PutUint16(b[6:8], flags|syscall.NLM_F_REQUEST)
+	binary.NativeEndian.PutUint32(b[8:12], seq)
+	copy(b[syscall.NLMSG_HDRLEN:], data)
+
+	if err := syscall.Sendto(c.fd, b, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return nil, os.NewSyscallError("sendto", err)
+	}
+
+	dump := flags&syscall.NLM_F_DUMP == syscall.NLM_F_DUMP
+
+	var replies []syscall.NetlinkMessage
+	for {
+		msgs, err := c.receive()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range msgs {
+			// Skip replies to any earlier request
+			if m.Header.Seq != seq {
+				continue
+			}
+
+			switch m.Header.Type {
+			case syscall.NLMSG_DONE:
+				return replies, nil
+			case syscall.NLMSG_ERROR:
+				// An error code of zero acknowledges the request
+				if err := parseNetlinkError(m.Data); err != nil {
+					return nil, err
+				}
+
+				return replies, nil
+			}
+
+			replies = append(replies, m)
+			if !dump && m.Header.Flags&syscall.NLM_F_MULTI == 0 {
+				return replies, nil
+			}
+		}
+	}
+}
+
+// receive receives and parses a batch of netlink messages from the kernel.
+func (c *netlinkConn) receive() ([]syscall.NetlinkMessage, error) {
+	b := make([]byte, netlinkReceiveBufferSize)
+	n, _, err := syscall.Recvfrom(c.fd, b, 0)
+	if err != nil {
+		return nil, os.NewSyscallError("recvfrom", err)
+	}
+
+	msgs, err := syscall.ParseNetlinkMessage(b[:n])
+	if err != nil {
+		return nil, errInvalidNetlinkMessage
+	}
+
+	return msgs, nil
+}
+
+// parseNetlinkError parses the error code from the data of a netlink error
+// message, returning nil if the error code indicates success.
+func parseNetlinkError(b []byte) error {
+	if len(b) < 4 {
+		return errInvalidNetlinkMessage
+	}
+
+	// Error codes are negative errno values
+	code := int32(binary.NativeEndian.Uint32(b[0:4]))
+	if code == 0 {
+		return nil
+	}
+
+	return syscall.Errno(-code)
+}
+
+// netlinkAttribute is a netlink attribute, with its type and data.
+type netlinkAttribute struct {
+	Type uint16
+	Data []byte
+}
+
+// parseNetlinkAttributes parses a series of netlink attributes from the
+// input bytes.  Any nested or byte order flags are masked out of the type
+// of each attribute.
+func parseNetlinkAttributes(b []byte) ([]netlinkAttribute, error) {
+	var attrs []netlinkAttribute
+	for len(b) >= netlinkAttributeHeaderLen {
+		l := int(binary.NativeEndian.Uint16(b[0:2]))
+		if l < netlinkAttributeHeaderLen || l > len(b) {
+			return nil, errInvalidNetlinkAttribute
+		}
+
+		attrs = append(attrs, netlinkAttribute{
+			Type: binary.NativeEndian.Uint16(b[2:4]) & netlinkAttributeTypeMask,
+			Data: b[netlinkAttributeHeaderLen:l],
+		})
+
+		// Attributes are padded to a four byte boundary
+		l = netlinkAlign(l)
+		if l > len(b) {
+			break
+		}
+		b = b[l:]
+	}
+
+	return attrs, nil
+}
+
+// marshalNetlinkAttributes marshals a series of netlink attributes into
+// their binary form.
+func marshalNetlinkAttributes(attrs []netlinkAttribute) []byte {
+	var b []byte
+	for _, a := range attrs {
+		l := netlinkAttributeHeaderLen + len(a.Data)
+
+		ab := make([]byte, netlinkAlign(l))
+		binary.NativeEndian.PutUint16(ab[0:2], uint16(l))
+		binary.NativeEndian.PutUint16(ab[2:4], a.Type)
+		copy(ab[netlinkAttributeHeaderLen:], a.Data)
+
+		b = append(b, ab...)
+	}
+
+	return b
+}
+
+// netlinkAlign rounds the input length up to the four byte alignment used
+// by netlink messages and attributes.
+func netlinkAlign(l int) int {
+	return (l + syscall.NLMSG_ALIGNTO - 1) & ^(syscall.NLMSG_ALIGNTO - 1)
+}
@@ -0,0 +1,82 @@
+// +build linux
+
+package mptcp
+
+import (
+	"reflect"
+	"syscall"
+	"testing"
+)
+
+// TestLinux_netlinkAttributes verifies that netlink attributes survive a
+// round trip through marshalNetlinkAttributes and parseNetlinkAttributes,
+// including padding of unaligned attribute data.
+func TestLinux_netlinkAttributes(t *testing.T) {
+	attrs := []netlinkAttribute{
+		{Type: 1, Data: []byte{}},
+		{Type: 2, Data: []byte{0x01}},
+		{Type: 3, Data: []byte{0x01, 0x02, 0x03, 0x04}},
+		{Type: 4, Data: []byte{0x01, 0x02, 0x03, 0x04, 0x05}},
+	}
+
+	b := marshalNetlinkAttributes(attrs)
+	if l := len(b); l != 4+8+8+12 {
+		t.Fatalf("unexpected marshaled length: %v != %v", l, 4+8+8+12)
+	}
+
+	out, err := parseNetlinkAttributes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(out, attrs) {
+		t.Fatalf("unexpected attributes: %v != %v", out, attrs)
+	}
+
+	// Nested flags must be masked out of parsed attribute types
+	out, err = parseNetlinkAttributes(marshalNetlinkAttributes([]netlinkAttribute{{
+		Type: 5 | netlinkAttributeNested,
+	}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out[0].Type != 5 {
+		t.Fatalf("unexpected nested attribute type: %v != %v", out[0].Type, 5)
+	}
+}
+
+// TestLinux_parseNetlinkAttributesInvalid verifies that parseNetlinkAttributes
+// rejects attributes with invalid lengths.
+func TestLinux_parseNetlinkAttributesInvalid(t *testing.T) {
+	var tests = [][]byte{
+		// Length shorter than header
+		{0x02, 0x00, 0x01, 0x00},
+		// Length longer than buffer
+		{0x08, 0x00, 0x01, 0x00, 0xff},
+	}
+
+	for i, test := range tests {
+		if _, err := parseNetlinkAttributes(test); err != errInvalidNetlinkAttribute {
+			t.Fatalf("[%02d] unexpected err: %v != %v", i, err, errInvalidNetlinkAttribute)
+		}
+	}
+}
+
+// TestLinux_parseNetlinkError verifies that parseNetlinkError converts netlink
+// error codes into errors.
+func TestLinux_parseNetlinkError(t *testing.T) {
+	var tests = []struct {
+		b   []byte
+		err error
+	}{
+		{nil, errInvalidNetlinkMessage},
+		{nlenc32(0), nil},
+		{nlenc32(^uint32(syscall.ENOENT) + 1), syscall.ENOENT},
+	}
+
+	for i, test := range tests {
+		if err := parseNetlinkError(test.b); err != test.err {
+			t.Fatalf("[%02d] unexpected err: %v != %v", i, err, test.err)
+		}
+	}
+}
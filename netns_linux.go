@@ -0,0 +1,123 @@
+// +build linux
+
+package mptcp
+
+import (
+	"errors"
+	"io"
+	"os"
+	"runtime"
+	"syscall"
+)
+
+const (
+	// procThreadSelfMPTCP is the location of the MPTCP connections table of
+	// the network namespace of the calling thread.  Unlike procMPTCP, it does
+	// not follow the network namespace of the process's main thread.
+	procThreadSelfMPTCP = "/proc/thread-self/net/mptcp"
+
+	// procThreadSelfNetNS is the location of the network namespace of the
+	// calling thread.
+	procThreadSelfNetNS = "/proc/thread-self/ns/net"
+)
+
+var (
+	// errNetNSAborted is returned by runInNetNS when its function exits
+	// without returning.
+	errNetNSAborted = errors.New("function in network namespace did not return")
+)
+
+// sysSetns is the setns system call number of each architecture, as package
+// syscall does not define it for every architecture.
+var sysSetns = map[string]uintptr{
+	"386":      346,
+	"amd64":    308,
+	"arm":      375,
+	"arm64":    268,
+	"loong64":  268,
+	"mips":     4344,
+	"mipsle":   4344,
+	"mips64":   5303,
+	"mips64le": 5303,
+	"ppc64":    350,
+	"ppc64le":  350,
+	"riscv64":  268,
+	"s390x":    339,
+}
+
+// runInNetNS invokes fn on a dedicated OS thread which has joined the network
+// namespace referred to by the input file descriptor, and waits for it to
+// return.  Files and sockets opened by fn remain in that network namespace.
+// The caller's own thread never changes namespace.
+var runInNetNS = func(fd int, fn func() error) error {
+	if _, ok := sysSetns[runtime.GOARCH]; !ok {
+		return ErrNotImplemented
+	}
+
+	errC := make(chan error, 1)
+	go func() {
+		// Report fn exiting its goroutine without returning, such as by
+		// calling runtime.Goexit, so the caller does not wait forever.  The
+		// goroutine is then still locked to its thread, which the runtime
+		// terminates rather than reusing in the foreign namespace
+		returned := false
+		defer func() {
+			if !returned {
+				errC <- errNetNSAborted
+			}
+		}()
+
+		// Namespaces are joined by threads, so this goroutine must remain
+		// on its thread until the original namespace is restored
+		runtime.LockOSThread()
+
+		orig, err := os.Open(procThreadSelfNetNS)
+		if err != nil {
+			returned = true
+			runtime.UnlockOSThread()
+			errC <- err
+			return
+		}
+		defer orig.Close()
+
+		if err := setNetNS(fd); err != nil {
+			returned = true
+			runtime.UnlockOSThread()
+			errC <- err
+			return
+		}
+
+		fnErr := fn()
+		returned = true
+
+		if err := setNetNS(int(orig.Fd())); err != nil {
+			// The thread cannot be returned to its original namespace,
+			// so this goroutine exits while still locked to it, and the
+			// runtime terminates the thread rather than reusing it
+			errC <- err
+			return
+		}
+		runtime.UnlockOSThread()
+
+		errC <- fnErr
+	}()
+
+	return <-errC
+}
+
+// setNetNS moves the calling thread into the network namespace referred to by
+// the input file descriptor.
+func setNetNS(fd int) error {
+	_, _, errno := syscall.RawSyscall(sysSetns[runtime.GOARCH], uintptr(fd), syscall.CLONE_NEWNET, 0)
+	if errno != 0 {
+		return os.NewSyscallError("setns", errno)
+	}
+
+	return nil
+}
+
+// openNetNSTable opens the MPTCP connections table of the network namespace
+// of the calling thread.
+var openNetNSTable = func() (io.ReadCloser, error) {
+	return openMPTCPTableLinux(procThreadSelfMPTCP)
+}
@@ -0,0 +1,208 @@
+// +build linux
+
+package mptcp
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"runtime"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+// TestLinux_CheckerNetNS verifies that a Checker configured using WithNetNS
+// lists connections from the default table of the configured network
+// namespace, but not from its Sources.
+func TestLinux_CheckerNetNS(t *testing.T) {
+	origRun, origNetNS, origOpen := runInNetNS, openNetNSTable, openRawTable
+	defer func() { runInNetNS, openNetNSTable, openRawTable = origRun, origNetNS, origOpen }()
+
+	// The default table contains no entries, and the namespace's table
+	// contains two entries
+	openRawTable = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(append(mptcpTableHeader, '\n'))), nil
+	}
+
+	var inNS bool
+	openNetNSTable = func() (io.ReadCloser, error) {
+		if !inNS {
+			t.Fatal("namespace table opened outside of namespace")
+		}
+
+		return ioutil.NopCloser(bytes.NewReader(testLargeMPTCPTable(1))), nil
+	}
+
+	var fds []int
+	runInNetNS = func(fd int, fn func() error) error {
+		fds = append(fds, fd)
+
+		inNS = true
+		defer func() { inNS = false }()
+
+		return fn()
+	}
+
+	source := SourceFunc(func() ([]Connection, error) {
+		return []Connection{{}}, nil
+	})
+
+	var tests = []struct {
+		desc    string
+		options []Option
+		count   int
+		fds     []int
+	}{
+		{"no namespace", nil, 0, nil},
+		{"namespace", []Option{WithNetNS(3)}, 2, []int{3}},
+		{"namespace with sources", []Option{WithNetNS(3), WithSources(source)}, 1, nil},
+	}
+
+	for i, test := range tests {
+		fds = nil
+
+		conns, err := NewChecker(test.options...).ListConnections()
+		if err != nil {
+			t.Fatalf("[%02d] unexpected err: %v [test: %v]", i, err, test.desc)
+		}
+
+		if len(conns) != test.count {
+			t.Fatalf("[%02d] unexpected connection count: %v != %v [test: %v]", i, len(conns), test.count, test.desc)
+		}
+
+		if len(fds) != len(test.fds) || (len(fds) > 0 && fds[0] != test.fds[0]) {
+			t.Fatalf("[%02d] unexpected namespace fds: %v != %v [test: %v]", i, fds, test.fds, test.desc)
+		}
+
+		// Lazy listing uses the same namespace as regular listing
+		fds = nil
+		lazy, err := NewChecker(test.options...).ListLazyConnections()
+		if err != nil {
+			t.Fatalf("[%02d] unexpected lazy err: %v [test: %v]", i, err, test.desc)
+		}
+
+		if len(lazy) != test.count {
+			t.Fatalf("[%02d] unexpected lazy connection count: %v != %v [test: %v]", i, len(lazy), test.count, test.desc)
+		}
+	}
+}
+
+// TestLinux_runInNetNS verifies that runInNetNS invokes a function in another
+// network namespace, and then restores the original network namespace.
+func TestLinux_runInNetNS(t *testing.T) {
+	orig, err := os.Readlink(procThreadSelfNetNS)
+	if err != nil {
+		t.Skipf("skipping, cannot read network namespace: %v", err)
+	}
+
+	ns := testNewNetNS(t)
+	defer ns.Close()
+
+	want, err := os.Readlink("/proc/self/fd/" + strconv.Itoa(int(ns.Fd())))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got string
+	if err := runInNetNS(int(ns.Fd()), func() error {
+		var err error
+		got, err = os.Readlink(procThreadSelfNetNS)
+		return err
+	}); err != nil {
+		t.Fatalf("failed to run in namespace: %v", err)
+	}
+
+	if got != want {
+		t.Fatalf("function ran in unexpected namespace: %q != %q", got, want)
+	}
+
+	// The caller's thread never joins the other namespace
+	after, err := os.Readlink(procThreadSelfNetNS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after != orig {
+		t.Fatalf("namespace not restored: %q != %q", after, orig)
+	}
+}
+
+// TestLinux_runInNetNSGoexit verifies that runInNetNS returns an error when
+// its function exits without returning, rather than waiting forever.
+func TestLinux_runInNetNSGoexit(t *testing.T) {
+	ns := testNewNetNS(t)
+	defer ns.Close()
+
+	err := runInNetNS(int(ns.Fd()), func() error {
+		runtime.Goexit()
+		return nil
+	})
+	if err != errNetNSAborted {
+		t.Fatalf("unexpected err: %v != %v", err, errNetNSAborted)
+	}
+}
+
+// TestLinux_CheckerNetNSLoopback verifies that a Checker configured using
+// WithNetNS does not report a real loopback MPTCP connection of the current
+// network namespace, when listing connections in a new network namespace.
+func TestLinux_CheckerNetNSLoopback(t *testing.T) {
+	ns := testNewNetNS(t)
+	defer ns.Close()
+
+	client, _ := testDialPair(t, &Dialer{})
+	if ok, err := client.(*net.TCPConn).MultipathTCP(); err != nil || !ok {
+		t.Skip("skipping, connection does not use MPTCP")
+	}
+
+	conns, err := NewChecker().ListConnections()
+	if err != nil {
+		t.Skipf("skipping, cannot list connections: %v", err)
+	}
+	if len(conns) == 0 {
+		t.Skip("skipping, kernel does not report loopback connections")
+	}
+
+	conns, err = NewChecker(WithNetNS(int(ns.Fd()))).ListConnections()
+	if err != nil {
+		t.Fatalf("failed to list connections in namespace: %v", err)
+	}
+
+	if len(conns) != 0 {
+		t.Fatalf("unexpected connections in new namespace: %v", conns)
+	}
+}
+
+// testNewNetNS creates a new, empty network namespace, and returns a file
+// which refers to it.  The test is skipped if namespaces cannot be created.
+func testNewNetNS(t *testing.T) *os.File {
+	t.Helper()
+
+	type result struct {
+		f   *os.File
+		err error
+	}
+	resC := make(chan result, 1)
+
+	go func() {
+		// The thread is never unlocked, so it is terminated along with
+		// its namespace when the goroutine exits
+		runtime.LockOSThread()
+
+		if err := syscall.Unshare(syscall.CLONE_NEWNET); err != nil {
+			resC <- result{err: err}
+			return
+		}
+
+		f, err := os.Open(procThreadSelfNetNS)
+		resC <- result{f: f, err: err}
+	}()
+
+	res := <-resC
+	if res.err != nil {
+		t.Skipf("skipping, cannot create network namespace: %v", res.err)
+	}
+
+	return res.f
+}
@@ -0,0 +1,102 @@
+package mptcp
+
+import (
+	"bytes"
+	"net"
+	"sort"
+)
+
+// sortConnections sorts connections in place, using the input comparison
+// function.  Connections which compare as equal keep their original order.
+func sortConnections(conns []Connection, cmp func(a, b Connection) int) {
+	sort.SliceStable(conns, func(i, j int) bool {
+		return cmp(conns[i], conns[j]) < 0
+	})
+}
+
+// ByTriagePriority compares two connections by how likely they are to need
+// attention from an operator, for use with WithOrder or slices.SortFunc.  It
+// returns a negative number if a should be listed before b.
+//
+// Connections which are not established sort first, as they are opening,
+// closing, or stuck.  Connections are then sorted by the total number of
+// bytes in their transmit and receive queues, largest first, as a growing
+// queue indicates a stalled path or a slow reader.  Any remaining ties are
+// broken using the canonical order of WithStableOrder, so the result is
+// deterministic.
+//
+// The heuristic may be refined in future releases, so callers should not
+// depend on the relative order of any two particular connections.
+func ByTriagePriority(a, b Connection) int {
+	aEst, bEst := a.State == StateEstablished, b.State == StateEstablished
+	switch {
+	case !aEst && bEst:
+		return -1
+	case aEst && !bEst:
+		return 1
+	}
+
+	// Larger queues sort first
+	aQueued := uint64(a.TxQueue) + uint64(a.RxQueue)
+	bQueued := uint64(b.TxQueue) + uint64(b.RxQueue)
+	if c := compareUint64(bQueued, aQueued); c != 0 {
+		return c
+	}
+
+	return compareConnections(a, b)
+}
+
+// compareConnections compares two connections by their remote IP, remote
+// port, local IP, local port, local token, remote token, and inode, in that
+// order.  It returns a negative number if a sorts before b, a positive number
+// if a sorts after b, and zero if they are equal by each of these fields.
+func compareConnections(a, b Connection) int {
+	if c := compareTCPAddrs(a.RemoteAddr, b.RemoteAddr); c != 0 {
+		return c
+	}
+	if c := compareTCPAddrs(a.LocalAddr, b.LocalAddr); c != 0 {
+		return c
+	}
+
+	// Break any remaining ties using fields which identify the connection
+	if c := compareUint64(uint64(a.LocalToken), uint64(b.LocalToken)); c != 0 {
+		return c
+	}
+	if c := compareUint64(uint64(a.RemoteToken), uint64(b.RemoteToken)); c != 0 {
+		return c
+	}
+
+	return compareUint64(a.Inode, b.Inode)
+}
+
+// compareTCPAddrs compares two TCP addresses by IP, and then by port.  IP
+// addresses are compared in their 16 byte form, so IPv4 addresses sort before
+// IPv6 addresses.  A nil address sorts before any other address.
+func compareTCPAddrs(a, b *net.TCPAddr) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	if c := bytes.Compare(a.IP.To16(), b.IP.To16()); c != 0 {
+		return c
+	}
+
+	return compareUint64(uint64(a.Port), uint64(b.Port))
+}
+
+// compareUint64 compares two unsigned integers.
+func compareUint64(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
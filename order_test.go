@@ -0,0 +1,103 @@
+package mptcp
+
+import (
+	"math/rand"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// TestCheckerWithStableOrder verifies that a Checker configured with
+// WithStableOrder returns connections in an identical, canonical order
+// across calls, even when the underlying source order changes.
+func TestCheckerWithStableOrder(t *testing.T) {
+	tcpAddr := func(ip string, port int) *net.TCPAddr {
+		return &net.TCPAddr{IP: net.ParseIP(ip), Port: port}
+	}
+
+	// Connections in canonical order
+	want := []Connection{
+		// Remote IP orders IPv4 before IPv6
+		{LocalAddr: tcpAddr("192.168.1.10", 80), RemoteAddr: tcpAddr(ipv4HostTwo, 2020)},
+		{LocalAddr: tcpAddr("192.168.1.10", 80), RemoteAddr: tcpAddr(ipv4HostOne, 2020)},
+		// Remote port breaks remote IP tie
+		{LocalAddr: tcpAddr("192.168.1.10", 80), RemoteAddr: tcpAddr(ipv4HostOne, 4040)},
+		// Local IP breaks remote address tie
+		{LocalAddr: tcpAddr("192.168.1.20", 80), RemoteAddr: tcpAddr(ipv4HostOne, 4040)},
+		// Local port breaks remote address and local IP tie
+		{LocalAddr: tcpAddr("192.168.1.20", 443), RemoteAddr: tcpAddr(ipv4HostOne, 4040)},
+		// Tokens break address ties
+		{LocalToken: 0x1, LocalAddr: tcpAddr("192.168.1.20", 443), RemoteAddr: tcpAddr(ipv4HostOne, 4040)},
+		{LocalToken: 0x1, RemoteToken: 0x1, LocalAddr: tcpAddr("192.168.1.20", 443), RemoteAddr: tcpAddr(ipv4HostOne, 4040)},
+		{IsIPv6: true, LocalAddr: tcpAddr("2001:db8::10", 80), RemoteAddr: tcpAddr(ipv6HostOne, 2020)},
+	}
+
+	// Source which returns connections in a different order on each call
+	rng := rand.New(rand.NewSource(1))
+	source := SourceFunc(func() ([]Connection, error) {
+		conns := append([]Connection(nil), want...)
+		rng.Shuffle(len(conns), func(i, j int) {
+			conns[i], conns[j] = conns[j], conns[i]
+		})
+
+		return conns, nil
+	})
+
+	c := NewChecker(WithSources(source), WithStableOrder())
+	for i := 0; i < 10; i++ {
+		conns, err := c.ListConnections()
+		if err != nil {
+			t.Fatalf("[%02d] unexpected err: %v", i, err)
+		}
+
+		if !reflect.DeepEqual(conns, want) {
+			t.Fatalf("[%02d] unexpected connection order:\n- want: %v\n-  got: %v", i, want, conns)
+		}
+	}
+}
+
+// TestByTriagePriority verifies that ByTriagePriority orders a mixed set of
+// connections with problem connections first.
+func TestByTriagePriority(t *testing.T) {
+	tcpAddr := func(ip string, port int) *net.TCPAddr {
+		return &net.TCPAddr{IP: net.ParseIP(ip), Port: port}
+	}
+	local := tcpAddr("192.168.1.10", 80)
+
+	// Connections in triage order
+	want := []Connection{
+		// Non-established connections first, by descending queue size
+		{State: StateCloseWait, TxQueue: 4096, LocalAddr: local, RemoteAddr: tcpAddr(ipv4HostOne, 2020)},
+		{State: StateSynRecv, RxQueue: 10, LocalAddr: local, RemoteAddr: tcpAddr(ipv4HostTwo, 2020)},
+		// Canonical order breaks queue size ties
+		{State: StateFinWait1, LocalAddr: local, RemoteAddr: tcpAddr(ipv4HostTwo, 2020)},
+		{State: StateFinWait1, LocalAddr: local, RemoteAddr: tcpAddr(ipv4HostOne, 2020)},
+		// Established connections by descending total queue size
+		{State: StateEstablished, TxQueue: 0xffffffff, RxQueue: 0xffffffff, LocalAddr: local, RemoteAddr: tcpAddr(ipv4HostOne, 4040)},
+		{State: StateEstablished, TxQueue: 100, RxQueue: 200, LocalAddr: local, RemoteAddr: tcpAddr(ipv4HostOne, 6060)},
+		{State: StateEstablished, RxQueue: 250, LocalAddr: local, RemoteAddr: tcpAddr(ipv4HostOne, 8080)},
+		{State: StateEstablished, LocalAddr: local, RemoteAddr: tcpAddr(ipv6HostOne, 2020), IsIPv6: true},
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	source := SourceFunc(func() ([]Connection, error) {
+		conns := append([]Connection(nil), want...)
+		rng.Shuffle(len(conns), func(i, j int) {
+			conns[i], conns[j] = conns[j], conns[i]
+		})
+
+		return conns, nil
+	})
+
+	c := NewChecker(WithSources(source), WithOrder(ByTriagePriority))
+	for i := 0; i < 10; i++ {
+		conns, err := c.ListConnections()
+		if err != nil {
+			t.Fatalf("[%02d] unexpected err: %v", i, err)
+		}
+
+		if !reflect.DeepEqual(conns, want) {
+			t.Fatalf("[%02d] unexpected connection order:\n- want: %v\n-  got: %v", i, want, conns)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+// +build linux
+
+package mptcp
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"syscall"
+)
+
+const (
+	// mptcpPMCmd* are the commands of the mainline Linux MPTCP path manager
+	// generic netlink family.
+	mptcpPMCmdAddAddr    = 1
+	mptcpPMCmdDelAddr    = 2
+	mptcpPMCmdGetAddr    = 3
+	mptcpPMCmdFlushAddrs = 4
+	mptcpPMCmdSetLimits  = 5
+	mptcpPMCmdGetLimits  = 6
+
+	// mptcpPMAttr* are the attributes of path manager commands.
+	mptcpPMAttrAddr        = 1
+	mptcpPMAttrRcvAddAddrs = 2
+	mptcpPMAttrSubflows    = 3
+
+	// mptcpPMAddrAttr* are the attributes nested within mptcpPMAttrAddr,
+	// which describe an endpoint.
+	mptcpPMAddrAttrFamily = 1
+	mptcpPMAddrAttrID     = 2
+	mptcpPMAddrAttrAddr4  = 3
+	mptcpPMAddrAttrAddr6  = 4
+	mptcpPMAddrAttrPort   = 5
+	mptcpPMAddrAttrFlags  = 6
+	mptcpPMAddrAttrIfIdx  = 7
+)
+
+// pmEndpoints uses the Linux MPTCP path manager to retrieve all endpoints.
+var pmEndpoints = func() ([]Endpoint, error) {
+	var endpoints []Endpoint
+	err := executePathManager(mptcpPMCmdGetAddr, syscall.NLM_F_DUMP, nil, func(b []byte) error {
+		e, err := parseEndpointMessage(b)
+		if err != nil {
+			return err
+		}
+
+		endpoints = append(endpoints, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return endpoints, nil
+}
+
+// pmAddEndpoint uses the Linux MPTCP path manager to add an endpoint.
+var pmAddEndpoint = func(e Endpoint) error {
+	return executePathManager(mptcpPMCmdAddAddr, syscall.NLM_F_ACK, []netlinkAttribute{
+		marshalEndpoint(e),
+	}, nil)
+}
+
+// pmDeleteEndpoint uses the Linux MPTCP path manager to remove the endpoint
+// with the input ID.
+var pmDeleteEndpoint = func(id uint8) error {
+	return executePathManager(mptcpPMCmdDelAddr, syscall.NLM_F_ACK, []netlinkAttribute{
+		marshalEndpoint(Endpoint{ID: id}),
+	}, nil)
+}
+
+// pmFlushEndpoints uses the Linux MPTCP path manager to remove all endpoints.
+var pmFlushEndpoints = func() error {
+	return executePathManager(mptcpPMCmdFlushAddrs, syscall.NLM_F_ACK, nil, nil)
+}
+
+// pmLimits uses the Linux MPTCP path manager to retrieve its limits.
+var pmLimits = func() (Limits, error) {
+	var l Limits
+	err := executePathManager(mptcpPMCmdGetLimits, 0, nil, func(b []byte) error {
+		var err error
+		l, err = parseLimitsMessage(b)
+		return err
+	})
+	if err != nil {
+		return Limits{}, err
+	}
+
+	return l, nil
+}
+
+// pmSetLimits uses the Linux MPTCP path manager to set its limits.
+var pmSetLimits = func(l Limits) error {
+	return executePathManager(mptcpPMCmdSetLimits, syscall.NLM_F_ACK, []netlinkAttribute{
+		{Type: mptcpPMAttrRcvAddAddrs, Data: nativeUint32(l.AddAddrAccepted)},
+		{Type: mptcpPMAttrSubflows, Data: nativeUint32(l.Subflows)},
+	}, nil)
+}
+
+// executePathManager sends a command with the input flags and attributes to
+// the Linux MPTCP path manager, and invokes fn, if set, with the data of each
+// reply message.  Commands which do not otherwise reply must set
+// syscall.NLM_F_ACK, so that the kernel acknowledges them.
+func executePathManager(cmd uint8, flags uint16, attrs []netlinkAttribute, fn func(b []byte) error) error {
+	c, err := dialNetlink(netlinkGeneric)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	family, err := c.genericFamilyID(mptcpPMFamilyName)
+	if err != nil {
+		return err
+	}
+
+	msgs, err := c.execute(family, flags, genericMessage(cmd, attrs))
+	if err != nil {
+		return err
+	}
+
+	if fn == nil {
+		return nil
+	}
+
+	for _, m := range msgs {
+		if err := fn(m.Data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// marshalEndpoint marshals an Endpoint into a nested path manager address
+// attribute, omitting any fields which are not set.
+func marshalEndpoint(e Endpoint) netlinkAttribute {
+	var attrs []netlinkAttribute
+	if e.Addr.IsValid() {
+		// IPv4-mapped IPv6 addresses are configured as IPv4 addresses
+		addr := e.Addr.Unmap()
+		if addr.Is4() {
+			a4 := addr.As4()
+			attrs = append(attrs,
+				netlinkAttribute{Type: mptcpPMAddrAttrFamily, Data: nativeUint16(syscall.AF_INET)},
+				netlinkAttribute{Type: mptcpPMAddrAttrAddr4, Data: a4[:]},
+			)
+		} else {
+			a16 := addr.As16()
+			attrs = append(attrs,
+				netlinkAttribute{Type: mptcpPMAddrAttrFamily, Data: nativeUint16(syscall.AF_INET6)},
+				netlinkAttribute{Type: mptcpPMAddrAttrAddr6, Data: a16[:]},
+			)
+		}
+	}
+
+	if e.ID != 0 {
+		attrs = append(attrs, netlinkAttribute{Type: mptcpPMAddrAttrID, Data: []byte{e.ID}})
+	}
+	if e.Port != 0 {
+		attrs = append(attrs, netlinkAttribute{Type: mptcpPMAddrAttrPort, Data: nativeUint16(e.Port)})
+	}
+	if e.Flags != 0 {
+		attrs = append(attrs, netlinkAttribute{Type: mptcpPMAddrAttrFlags, Data: nativeUint32(uint32(e.Flags))})
+	}
+	if e.Interface != 0 {
+		attrs = append(attrs, netlinkAttribute{Type: mptcpPMAddrAttrIfIdx, Data: nativeUint32(uint32(e.Interface))})
+	}
+
+	return netlinkAttribute{
+		Type: mptcpPMAttrAddr | netlinkAttributeNested,
+		Data: marshalNetlinkAttributes(attrs),
+	}
+}
+
+// parseEndpointMessage parses an Endpoint from the data of a path manager
+// reply message.
+func parseEndpointMessage(b []byte) (Endpoint, error) {
+	if len(b) < genlHeaderLen {
+		return Endpoint{}, errInvalidNetlinkMessage
+	}
+
+	attrs, err := parseNetlinkAttributes(b[genlHeaderLen:])
+	if err != nil {
+		return Endpoint{}, err
+	}
+
+	for _, a := range attrs {
+		if a.Type != mptcpPMAttrAddr {
+			continue
+		}
+
+		nattrs, err := parseNetlinkAttributes(a.Data)
+		if err != nil {
+			return Endpoint{}, err
+		}
+
+		var e Endpoint
+		for _, na := range nattrs {
+			switch {
+			case na.Type == mptcpPMAddrAttrID && len(na.Data) == 1:
+				e.ID = na.Data[0]
+			case na.Type == mptcpPMAddrAttrAddr4 && len(na.Data) == 4:
+				e.Addr = netip.AddrFrom4([4]byte(na.Data))
+			case na.Type == mptcpPMAddrAttrAddr6 && len(na.Data) == 16:
+				e.Addr = netip.AddrFrom16([16]byte(na.Data))
+			case na.Type == mptcpPMAddrAttrPort && len(na.Data) == 2:
+				e.Port = binary.NativeEndian.Uint16(na.Data)
+			case na.Type == mptcpPMAddrAttrFlags && len(na.Data) == 4:
+				e.Flags = EndpointFlags(binary.NativeEndian.Uint32(na.Data))
+			case na.Type == mptcpPMAddrAttrIfIdx && len(na.Data) == 4:
+				e.Interface = int(int32(binary.NativeEndian.Uint32(na.Data)))
+			}
+		}
+
+		return e, nil
+	}
+
+	return Endpoint{}, errInvalidNetlinkMessage
+}
+
+// parseLimitsMessage parses Limits from the data of a path manager reply
+// message.
+func parseLimitsMessage(b []byte) (Limits, error) {
+	if len(b) < genlHeaderLen {
+		return Limits{}, errInvalidNetlinkMessage
+	}
+
+	attrs, err := parseNetlinkAttributes(b[genlHeaderLen:])
+	if err != nil {
+		return Limits{}, err
+	}
+
+	var l Limits
+	for _, a := range attrs {
+		if len(a.Data) != 4 {
+			continue
+		}
+
+		switch a.Type {
+		case mptcpPMAttrRcvAddAddrs:
+			l.AddAddrAccepted = binary.NativeEndian.Uint32(a.Data)
+		case mptcpPMAttrSubflows:
+			l.Subflows = binary.NativeEndian.Uint32(a.Data)
+		}
+	}
+
+	return l, nil
+}
+
+// nativeUint16 returns the input value in the host's byte order.
+func nativeUint16(v uint16) []byte {
+	b := make([]byte, 2)
+	binary.NativeEndian.PutUint16(b, v)
+	return b
+}
+
+// nativeUint32 returns the input value in the host's byte order.
+func nativeUint32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.NativeEndian.PutUint32(b, v)
+	return b
+}
@@ -0,0 +1,168 @@
+// +build linux
+
+package mptcp
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"reflect"
+	"syscall"
+	"testing"
+)
+
+// TestLinux_marshalEndpoint verifies that endpoints marshaled by
+// marshalEndpoint are parsed identically by parseEndpointMessage.
+func TestLinux_marshalEndpoint(t *testing.T) {
+	var tests = []struct {
+		desc string
+		in   Endpoint
+		out  Endpoint
+	}{
+		{
+			desc: "IPv4",
+			in: Endpoint{
+				ID:        1,
+				Addr:      netip.MustParseAddr("192.0.2.1"),
+				Port:      8080,
+				Interface: 2,
+				Flags:     EndpointSignal | EndpointBackup,
+			},
+		},
+		{
+			desc: "IPv6",
+			in: Endpoint{
+				ID:    2,
+				Addr:  netip.MustParseAddr("2001:db8::1"),
+				Flags: EndpointSubflow | EndpointFullmesh,
+			},
+		},
+		{
+			desc: "IPv4-mapped IPv6",
+			in:   Endpoint{Addr: netip.MustParseAddr("::ffff:192.0.2.1")},
+			out:  Endpoint{Addr: netip.MustParseAddr("192.0.2.1")},
+		},
+		{
+			desc: "ID only",
+			in:   Endpoint{ID: 3},
+		},
+	}
+
+	for i, test := range tests {
+		want := test.out
+		if want == (Endpoint{}) {
+			want = test.in
+		}
+
+		b := genericMessage(mptcpPMCmdGetAddr, []netlinkAttribute{marshalEndpoint(test.in)})
+
+		e, err := parseEndpointMessage(b)
+		if err != nil {
+			t.Fatalf("[%02d] unexpected err: %v [test: %v]", i, err, test.desc)
+		}
+
+		if !reflect.DeepEqual(e, want) {
+			t.Fatalf("[%02d] unexpected endpoint: %#v != %#v [test: %v]", i, e, want, test.desc)
+		}
+	}
+
+	// Messages without an address are invalid
+	if _, err := parseEndpointMessage(genericMessage(mptcpPMCmdGetAddr, nil)); err != errInvalidNetlinkMessage {
+		t.Fatalf("unexpected err for message without address: %v", err)
+	}
+}
+
+// TestLinux_parseLimitsMessage verifies that parseLimitsMessage parses the
+// limits of the path manager.
+func TestLinux_parseLimitsMessage(t *testing.T) {
+	b := genericMessage(mptcpPMCmdGetLimits, []netlinkAttribute{
+		{Type: mptcpPMAttrRcvAddAddrs, Data: nativeUint32(3)},
+		{Type: mptcpPMAttrSubflows, Data: nativeUint32(2)},
+	})
+
+	l, err := parseLimitsMessage(b)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	if want := (Limits{Subflows: 2, AddAddrAccepted: 3}); l != want {
+		t.Fatalf("unexpected limits: %#v != %#v", l, want)
+	}
+
+	if _, err := parseLimitsMessage([]byte{0x01}); err != errInvalidNetlinkMessage {
+		t.Fatalf("unexpected err for short message: %v", err)
+	}
+}
+
+// TestLinux_PathManager verifies that endpoints and limits can be configured
+// using the path manager.  The test runs in a new network namespace, so the
+// configuration of the current network namespace is not modified.
+func TestLinux_PathManager(t *testing.T) {
+	ns := testNewNetNS(t)
+	defer ns.Close()
+
+	// The function runs on another goroutine, so it reports failures as
+	// errors rather than failing the test itself
+	err := runInNetNS(int(ns.Fd()), func() error {
+		e := Endpoint{
+			ID:    5,
+			Addr:  netip.MustParseAddr("192.0.2.1"),
+			Flags: EndpointSignal,
+		}
+		if err := AddEndpoint(e); err != nil {
+			return err
+		}
+
+		endpoints, err := Endpoints()
+		if err != nil {
+			return fmt.Errorf("failed to list endpoints: %v", err)
+		}
+		if want := []Endpoint{e}; !reflect.DeepEqual(endpoints, want) {
+			return fmt.Errorf("unexpected endpoints: %#v != %#v", endpoints, want)
+		}
+
+		want := Limits{Subflows: 2, AddAddrAccepted: 3}
+		if err := SetLimits(want); err != nil {
+			return fmt.Errorf("failed to set limits: %v", err)
+		}
+
+		l, err := ReadLimits()
+		if err != nil {
+			return fmt.Errorf("failed to read limits: %v", err)
+		}
+		if l != want {
+			return fmt.Errorf("unexpected limits: %#v != %#v", l, want)
+		}
+
+		if err := DeleteEndpoint(e.ID); err != nil {
+			return fmt.Errorf("failed to delete endpoint: %v", err)
+		}
+
+		// Add two endpoints, which are then removed together
+		for _, s := range []string{"192.0.2.2", "2001:db8::2"} {
+			if err := AddEndpoint(Endpoint{Addr: netip.MustParseAddr(s)}); err != nil {
+				return fmt.Errorf("failed to add endpoint %s: %v", s, err)
+			}
+		}
+		if err := FlushEndpoints(); err != nil {
+			return fmt.Errorf("failed to flush endpoints: %v", err)
+		}
+
+		endpoints, err = Endpoints()
+		if err != nil {
+			return fmt.Errorf("failed to list endpoints: %v", err)
+		}
+		if len(endpoints) != 0 {
+			return fmt.Errorf("unexpected endpoints after flush: %#v", endpoints)
+		}
+
+		return nil
+	})
+	if errors.Is(err, syscall.ENOENT) || errors.Is(err, syscall.EPERM) || os.IsPermission(err) {
+		t.Skipf("skipping, cannot configure path manager: %v", err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,231 @@
+// +build darwin
+
+package mptcp
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+const (
+	// sysctlMPTCPPCBList is the name of the Darwin-specific sysctl which
+	// reports a conninfo_mptcp structure for each MPTCP connection.
+	sysctlMPTCPPCBList = "net.inet.mptcp.pcblist"
+
+	// pcbListMinRelease is the earliest Darwin major release, used by
+	// macOS 10.13, whose conninfo_mptcp layout is decoded by parsePCBList.
+	pcbListMinRelease = 17
+
+	// conninfoMPTCP*Offset are the offsets of fields within xnu's struct
+	// conninfo_mptcp on 64-bit systems.  conninfoMPTCPFlowsOffset is the
+	// offset of its first struct mptcp_flow, which each structure also
+	// reports in its mptcpci_flow_offset field.
+	conninfoMPTCPLenOffset         = 0
+	conninfoMPTCPFlowOffsetOffset  = 8
+	conninfoMPTCPNFlowsOffset      = 16
+	conninfoMPTCPStateOffset       = 24
+	conninfoMPTCPLocalTokenOffset  = 48
+	conninfoMPTCPRemoteTokenOffset = 52
+	conninfoMPTCPFlowsOffset       = 136
+
+	// mptcpFlow*Offset are the offsets of fields within xnu's struct
+	// mptcp_flow, which describes a single subflow.  mptcpFlowMinLen is the
+	// length of its fields which precede its trailing struct conninfo_tcp,
+	// which is not decoded.
+	mptcpFlowLenOffset         = 0
+	mptcpFlowTCPCIOffsetOffset = 8
+	mptcpFlowSrcOffset         = 24
+	mptcpFlowDstOffset         = 152
+	mptcpFlowMinLen            = 292
+
+	// sockaddrStorageLen is the length of a struct sockaddr_storage.
+	sockaddrStorageLen = 128
+)
+
+// errInvalidPCBListEntry is returned when a conninfo_mptcp structure reported
+// by the net.inet.mptcp.pcblist sysctl does not use the expected layout.
+var errInvalidPCBListEntry = errors.New("invalid net.inet.mptcp.pcblist entry")
+
+// pcbListStates maps the MPTCP-level connection states of xnu to an
+// equivalent State.  MPTCPS_TERMINATE has no equivalent, and is reported
+// as StateClose.
+var pcbListStates = map[uint64]State{
+	0: StateClose,
+	1: StateListen,
+	2: StateEstablished,
+	3: StateCloseWait,
+	4: StateFinWait1,
+	5: StateClosing,
+	6: StateLastAck,
+	7: StateFinWait2,
+	8: StateTimeWait,
+	9: StateClose,
+}
+
+var (
+	// pcbListOnce and pcbListSupported cache the result of hasPCBList.
+	pcbListOnce      sync.Once
+	pcbListSupported bool
+)
+
+// hasPCBList reports whether the running Darwin release reports connections
+// in the conninfo_mptcp layout decoded by parsePCBList.  The layout is
+// private to xnu, and earlier releases use a different one.
+//
+// This implementation is swappable for testing with a mock data source.
+var hasPCBList = func() bool {
+	pcbListOnce.Do(func() {
+		release, err := syscall.Sysctl("kern.osrelease")
+		if err != nil {
+			return
+		}
+
+		major, ok := parseDarwinRelease(release)
+		pcbListSupported = ok && major >= pcbListMinRelease
+	})
+
+	return pcbListSupported
+}
+
+// parseDarwinRelease parses the major version of Darwin from its release
+// string, such as "23.1.0".
+func parseDarwinRelease(release string) (int, bool) {
+	major, _, _ := strings.Cut(release, ".")
+	v, err := strconv.Atoi(major)
+	if err != nil {
+		return 0, false
+	}
+
+	return v, true
+}
+
+// listPCBListConnections uses the Darwin net.inet.mptcp.pcblist sysctl to
+// retrieve all active MPTCP connections.  If the running Darwin release does
+// not use a known layout, ErrNotImplemented is returned.  If the kernel does
+// not publish the sysctl, no connections and no error are returned.
+//
+// This implementation is swappable for testing with a mock data source.
+var listPCBListConnections = func() ([]Connection, error) {
+	if !hasPCBList() {
+		return nil, ErrNotImplemented
+	}
+
+	s, err := syscall.Sysctl(sysctlMPTCPPCBList)
+	if err != nil {
+		if err == syscall.ENOENT {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	// syscall.Sysctl strips a trailing NUL byte, which may be the last byte
+	// of the final structure, so one is restored.  A trailing byte which is
+	// not part of a structure is ignored by parsePCBList
+	return parsePCBList(append([]byte(s), 0))
+}
+
+// parsePCBList parses the conninfo_mptcp structures reported by the
+// net.inet.mptcp.pcblist sysctl into Connections.  Connections which have no
+// subflows, and therefore no addresses, are skipped.  Trailing bytes which
+// are too short to hold a structure are ignored.
+func parsePCBList(b []byte) ([]Connection, error) {
+	var conns []Connection
+	for len(b) >= conninfoMPTCPFlowsOffset {
+		n := binary.NativeEndian.Uint64(b[conninfoMPTCPLenOffset:])
+		if n < conninfoMPTCPFlowsOffset || n > uint64(len(b)) {
+			return nil, errInvalidPCBListEntry
+		}
+
+		c, ok, err := parseConninfoMPTCP(b[:n])
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			conns = append(conns, c)
+		}
+
+		b = b[n:]
+	}
+
+	return conns, nil
+}
+
+// parseConninfoMPTCP parses a single conninfo_mptcp structure, verifying that
+// the offsets and lengths it reports match the layout known to this package.
+// The connection's addresses are those of its initial subflow.  If the
+// connection has no subflows, false is returned.
+func parseConninfoMPTCP(b []byte) (Connection, bool, error) {
+	if binary.NativeEndian.Uint64(b[conninfoMPTCPFlowOffsetOffset:]) != conninfoMPTCPFlowsOffset {
+		return Connection{}, false, errInvalidPCBListEntry
+	}
+
+	nflows := binary.NativeEndian.Uint64(b[conninfoMPTCPNFlowsOffset:])
+	if nflows == 0 {
+		return Connection{}, false, nil
+	}
+
+	// Each subflow reports its own length, which depends on the layout of
+	// its trailing conninfo_tcp structure
+	var first []byte
+	flows := b[conninfoMPTCPFlowsOffset:]
+	for i := uint64(0); i < nflows; i++ {
+		if len(flows) < mptcpFlowMinLen {
+			return Connection{}, false, errInvalidPCBListEntry
+		}
+
+		l := binary.NativeEndian.Uint64(flows[mptcpFlowLenOffset:])
+		tcpci := binary.NativeEndian.Uint64(flows[mptcpFlowTCPCIOffsetOffset:])
+		if tcpci < mptcpFlowMinLen || l < tcpci || l > uint64(len(flows)) {
+			return Connection{}, false, errInvalidPCBListEntry
+		}
+
+		if i == 0 {
+			first = flows[:l]
+		}
+		flows = flows[l:]
+	}
+
+	local, isIPv6, ok := parseSockaddr(first[mptcpFlowSrcOffset : mptcpFlowSrcOffset+sockaddrStorageLen])
+	if !ok {
+		return Connection{}, false, errInvalidPCBListEntry
+	}
+	remote, _, ok := parseSockaddr(first[mptcpFlowDstOffset : mptcpFlowDstOffset+sockaddrStorageLen])
+	if !ok {
+		return Connection{}, false, errInvalidPCBListEntry
+	}
+
+	return Connection{
+		LocalToken:  binary.NativeEndian.Uint32(b[conninfoMPTCPLocalTokenOffset:]),
+		RemoteToken: binary.NativeEndian.Uint32(b[conninfoMPTCPRemoteTokenOffset:]),
+		IsIPv6:      isIPv6,
+		LocalAddr:   local,
+		RemoteAddr:  remote,
+		State:       pcbListStates[binary.NativeEndian.Uint64(b[conninfoMPTCPStateOffset:])],
+		Subflows:    int(nflows),
+	}, true, nil
+}
+
+// parseSockaddr parses a Darwin struct sockaddr_in or sockaddr_in6 into a TCP
+// address, and reports whether it is an IPv6 address.
+func parseSockaddr(b []byte) (*net.TCPAddr, bool, bool) {
+	port := int(binary.BigEndian.Uint16(b[2:4]))
+
+	switch b[1] {
+	case syscall.AF_INET:
+		ip := make(net.IP, net.IPv4len)
+		copy(ip, b[4:8])
+		return &net.TCPAddr{IP: ip, Port: port}, false, true
+	case syscall.AF_INET6:
+		ip := make(net.IP, net.IPv6len)
+		copy(ip, b[8:24])
+		return &net.TCPAddr{IP: ip, Port: port}, true, true
+	default:
+		return nil, false, false
+	}
+}
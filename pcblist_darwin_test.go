@@ -0,0 +1,324 @@
+// +build darwin
+
+package mptcp
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"reflect"
+	"syscall"
+	"testing"
+)
+
+// testFlowLen is the length of the mptcp_flow structures built by
+// testConninfoMPTCP, including a trailing conninfo_tcp which is not decoded.
+const testFlowLen = mptcpFlowMinLen + 4 + 208
+
+// A testFlow describes a subflow for testConninfoMPTCP.
+type testFlow struct {
+	src, dst *net.TCPAddr
+}
+
+// testConninfoMPTCP builds a conninfo_mptcp structure in the layout reported
+// by the net.inet.mptcp.pcblist sysctl.
+func testConninfoMPTCP(state uint64, ltoken, rtoken uint32, flows ...testFlow) []byte {
+	nflows := len(flows)
+	if nflows == 0 {
+		// Connections without subflows still report one empty flow
+		flows = []testFlow{{}}
+	}
+
+	b := make([]byte, conninfoMPTCPFlowsOffset+len(flows)*testFlowLen)
+	binary.NativeEndian.PutUint64(b[conninfoMPTCPLenOffset:], uint64(len(b)))
+	binary.NativeEndian.PutUint64(b[conninfoMPTCPFlowOffsetOffset:], conninfoMPTCPFlowsOffset)
+	binary.NativeEndian.PutUint64(b[conninfoMPTCPNFlowsOffset:], uint64(nflows))
+	binary.NativeEndian.PutUint64(b[conninfoMPTCPStateOffset:], state)
+	binary.NativeEndian.PutUint32(b[conninfoMPTCPLocalTokenOffset:], ltoken)
+	binary.NativeEndian.PutUint32(b[conninfoMPTCPRemoteTokenOffset:], rtoken)
+
+	for i, f := range flows {
+		fb := b[conninfoMPTCPFlowsOffset+i*testFlowLen:]
+		binary.NativeEndian.PutUint64(fb[mptcpFlowLenOffset:], testFlowLen)
+		binary.NativeEndian.PutUint64(fb[mptcpFlowTCPCIOffsetOffset:], mptcpFlowMinLen+4)
+		testPutSockaddr(fb[mptcpFlowSrcOffset:], f.src)
+		testPutSockaddr(fb[mptcpFlowDstOffset:], f.dst)
+	}
+
+	return b
+}
+
+// testPutSockaddr encodes addr into b as a Darwin sockaddr_in or sockaddr_in6.
+func testPutSockaddr(b []byte, addr *net.TCPAddr) {
+	if addr == nil {
+		return
+	}
+
+	binary.BigEndian.PutUint16(b[2:4], uint16(addr.Port))
+	if ip4 := addr.IP.To4(); ip4 != nil {
+		b[0], b[1] = syscall.SizeofSockaddrInet4, syscall.AF_INET
+		copy(b[4:8], ip4)
+		return
+	}
+
+	b[0], b[1] = syscall.SizeofSockaddrInet6, syscall.AF_INET6
+	copy(b[8:24], addr.IP.To16())
+}
+
+// TestDarwin_parsePCBList verifies that parsePCBList decodes connections from
+// conninfo_mptcp structures, and rejects structures with an unknown layout.
+func TestDarwin_parsePCBList(t *testing.T) {
+	local4 := &net.TCPAddr{IP: net.IP{192, 0, 2, 1}, Port: 5000}
+	remote4 := &net.TCPAddr{IP: net.IP{192, 0, 2, 2}, Port: 443}
+	local6 := &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 5001}
+	remote6 := &net.TCPAddr{IP: net.ParseIP("2001:db8::2"), Port: 8443}
+
+	badOffset := testConninfoMPTCP(2, 1, 2, testFlow{src: local4, dst: remote4})
+	binary.NativeEndian.PutUint64(badOffset[conninfoMPTCPFlowOffsetOffset:], conninfoMPTCPFlowsOffset-8)
+
+	badFlow := testConninfoMPTCP(2, 1, 2, testFlow{src: local4, dst: remote4})
+	binary.NativeEndian.PutUint64(badFlow[conninfoMPTCPFlowsOffset+mptcpFlowTCPCIOffsetOffset:], mptcpFlowMinLen-4)
+
+	badLen := testConninfoMPTCP(2, 1, 2, testFlow{src: local4, dst: remote4})
+	binary.NativeEndian.PutUint64(badLen[conninfoMPTCPLenOffset:], uint64(len(badLen)+1))
+
+	badFamily := testConninfoMPTCP(2, 1, 2, testFlow{src: local4, dst: remote4})
+	badFamily[conninfoMPTCPFlowsOffset+mptcpFlowDstOffset+1] = 0
+
+	var tests = []struct {
+		desc  string
+		b     []byte
+		conns []Connection
+		err   error
+	}{
+		{
+			desc: "empty",
+		},
+		{
+			desc: "trailing byte",
+			b:    []byte{0},
+		},
+		{
+			desc: "IPv4 connection",
+			b:    testConninfoMPTCP(2, 0x01020304, 0x05060708, testFlow{src: local4, dst: remote4}),
+			conns: []Connection{{
+				LocalToken:  0x01020304,
+				RemoteToken: 0x05060708,
+				LocalAddr:   local4,
+				RemoteAddr:  remote4,
+				State:       StateEstablished,
+				Subflows:    1,
+			}},
+		},
+		{
+			desc: "IPv6 connection with two subflows",
+			b: testConninfoMPTCP(4, 1, 2,
+				testFlow{src: local6, dst: remote6},
+				testFlow{src: &net.TCPAddr{IP: net.ParseIP("2001:db8::3"), Port: 5002}, dst: remote6},
+			),
+			conns: []Connection{{
+				LocalToken:  1,
+				RemoteToken: 2,
+				IsIPv6:      true,
+				LocalAddr:   local6,
+				RemoteAddr:  remote6,
+				State:       StateFinWait1,
+				Subflows:    2,
+			}},
+		},
+		{
+			desc: "connection without subflows skipped",
+			b: append(
+				testConninfoMPTCP(9, 1, 2),
+				testConninfoMPTCP(2, 3, 4, testFlow{src: local4, dst: remote4})...,
+			),
+			conns: []Connection{{
+				LocalToken:  3,
+				RemoteToken: 4,
+				LocalAddr:   local4,
+				RemoteAddr:  remote4,
+				State:       StateEstablished,
+				Subflows:    1,
+			}},
+		},
+		{
+			desc: "unknown state",
+			b:    testConninfoMPTCP(100, 1, 2, testFlow{src: local4, dst: remote4}),
+			conns: []Connection{{
+				LocalToken:  1,
+				RemoteToken: 2,
+				LocalAddr:   local4,
+				RemoteAddr:  remote4,
+				Subflows:    1,
+			}},
+		},
+		{
+			desc: "unknown flow offset",
+			b:    badOffset,
+			err:  errInvalidPCBListEntry,
+		},
+		{
+			desc: "unknown flow layout",
+			b:    badFlow,
+			err:  errInvalidPCBListEntry,
+		},
+		{
+			desc: "truncated structure",
+			b:    badLen,
+			err:  errInvalidPCBListEntry,
+		},
+		{
+			desc: "unknown address family",
+			b:    badFamily,
+			err:  errInvalidPCBListEntry,
+		},
+	}
+
+	for i, test := range tests {
+		conns, err := parsePCBList(test.b)
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected err: %v != %v [test: %v]", i, err, test.err, test.desc)
+		}
+
+		if !reflect.DeepEqual(conns, test.conns) {
+			t.Fatalf("[%02d] unexpected connections: %+v != %+v [test: %v]", i, conns, test.conns, test.desc)
+		}
+	}
+}
+
+// TestDarwin_parseDarwinRelease verifies that parseDarwinRelease parses the
+// major version of Darwin release strings.
+func TestDarwin_parseDarwinRelease(t *testing.T) {
+	var tests = []struct {
+		release string
+		major   int
+		ok      bool
+	}{
+		{release: "23.1.0", major: 23, ok: true},
+		{release: "17.0.0", major: 17, ok: true},
+		{release: "16", major: 16, ok: true},
+		{release: ""},
+		{release: "foo.1.0"},
+	}
+
+	for i, test := range tests {
+		major, ok := parseDarwinRelease(test.release)
+		if major != test.major || ok != test.ok {
+			t.Fatalf("[%02d] unexpected result for %q: (%v, %v) != (%v, %v)",
+				i, test.release, major, ok, test.major, test.ok)
+		}
+	}
+}
+
+// TestDarwin_listPCBListConnectionsOldRelease verifies that Darwin releases
+// which do not use a known conninfo_mptcp layout are not implemented.
+func TestDarwin_listPCBListConnectionsOldRelease(t *testing.T) {
+	orig := hasPCBList
+	defer func() {
+		hasPCBList = orig
+	}()
+
+	hasPCBList = func() bool { return false }
+
+	conns, err := listPCBListConnections()
+	if conns != nil || err != ErrNotImplemented {
+		t.Fatalf("old release should return (nil, %v), but returned: (%v, %v)", ErrNotImplemented, conns, err)
+	}
+}
+
+// TestDarwin_checkMPTCP verifies that checkMPTCP and checkLocalMPTCP match
+// connections reported by the net.inet.mptcp.pcblist sysctl.
+func TestDarwin_checkMPTCP(t *testing.T) {
+	orig := listPCBListConnections
+	defer func() {
+		listPCBListConnections = orig
+	}()
+
+	listPCBListConnections = func() ([]Connection, error) {
+		return []Connection{{
+			LocalAddr:  &net.TCPAddr{IP: net.IP{192, 0, 2, 1}, Port: 443},
+			RemoteAddr: &net.TCPAddr{IP: net.IP{192, 0, 2, 2}, Port: 5000},
+		}}, nil
+	}
+
+	var tests = []struct {
+		desc string
+		fn   func(host string, port uint16) (bool, error)
+		host string
+		port uint16
+		ok   bool
+		err  error
+	}{
+		{
+			desc: "remote match",
+			fn:   func(host string, port uint16) (bool, error) { return checkMPTCP(context.Background(), host, port) },
+			host: "192.0.2.2",
+			port: 5000,
+			ok:   true,
+		},
+		{
+			desc: "remote port mismatch",
+			fn:   func(host string, port uint16) (bool, error) { return checkMPTCP(context.Background(), host, port) },
+			host: "192.0.2.2",
+			port: 5001,
+		},
+		{
+			desc: "remote invalid host",
+			fn:   func(host string, port uint16) (bool, error) { return checkMPTCP(context.Background(), host, port) },
+			host: "foo",
+			err:  ErrInvalidIPAddress,
+		},
+		{
+			desc: "local match",
+			fn:   checkLocalMPTCP,
+			host: "192.0.2.1",
+			port: 443,
+			ok:   true,
+		},
+		{
+			desc: "local address which is remote",
+			fn:   checkLocalMPTCP,
+			host: "192.0.2.2",
+			port: 5000,
+		},
+	}
+
+	for i, test := range tests {
+		ok, err := test.fn(test.host, test.port)
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected err: %v != %v [test: %v]", i, err, test.err, test.desc)
+		}
+
+		if ok != test.ok {
+			t.Fatalf("[%02d] unexpected ok: %v != %v [test: %v]", i, ok, test.ok, test.desc)
+		}
+	}
+}
+
+// TestDarwin_checkMPTCPCanceled verifies that checkMPTCP returns the error of
+// a context which is already done.
+func TestDarwin_checkMPTCPCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ok, err := checkMPTCP(ctx, "192.0.2.1", 443)
+	if ok || err != context.Canceled {
+		t.Fatalf("canceled checkMPTCP should return (false, %v), but returned: (%v, %v)", context.Canceled, ok, err)
+	}
+}
+
+// TestDarwin_Connections verifies that Connections lists connections using
+// the net.inet.mptcp.pcblist sysctl, if this release supports it.
+func TestDarwin_Connections(t *testing.T) {
+	if !hasPCBList() {
+		t.Skip("net.inet.mptcp.pcblist layout is not known for this release")
+	}
+
+	if _, err := Connections(); err != nil {
+		if err == syscall.EPERM {
+			t.Skipf("net.inet.mptcp.pcblist is not readable: %v", err)
+		}
+
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,133 @@
+package mptcp
+
+import (
+	"net"
+)
+
+// A Decision is the outcome of evaluating a Policy against a connection.
+type Decision int
+
+// Possible Decision values.
+const (
+	DecisionAllow Decision = iota
+	DecisionDeny
+)
+
+// String returns the name of the Decision.
+func (d Decision) String() string {
+	switch d {
+	case DecisionAllow:
+		return "allow"
+	case DecisionDeny:
+		return "deny"
+	default:
+		return "unknown"
+	}
+}
+
+// A Rule matches connections by their remote network, remote port, and
+// state.  Each field which is not set matches any connection, so the zero
+// value of a Rule matches every connection.
+type Rule struct {
+	// RemoteNet, if set, matches connections whose remote IP address is
+	// within the network.
+	RemoteNet *net.IPNet
+
+	// Ports, if set, matches connections whose remote port is any of
+	// the ports.
+	Ports []uint16
+
+	// States, if set, matches connections whose state is any of the states.
+	States []State
+}
+
+// Matches reports whether the Rule matches the input connection.
+func (r Rule) Matches(c Connection) bool {
+	if r.RemoteNet != nil && (c.RemoteAddr == nil || !r.RemoteNet.Contains(c.RemoteAddr.IP)) {
+		return false
+	}
+
+	if len(r.Ports) > 0 {
+		if c.RemoteAddr == nil {
+			return false
+		}
+
+		var ok bool
+		for _, p := range r.Ports {
+			if int(p) == c.RemoteAddr.Port {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+
+	if len(r.States) > 0 {
+		var ok bool
+		for _, s := range r.States {
+			if s == c.State {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// A Policy decides whether connections are expected, using a series of
+// declarative rules.  Deny rules take precedence over allow rules, and a
+// connection which matches no rule receives the default decision.
+//
+// The zero value of a Policy allows every connection.
+type Policy struct {
+	// Allow and Deny are the rules which allow and deny connections.
+	Allow []Rule
+	Deny  []Rule
+
+	// Default is the decision for connections which match no rule.
+	Default Decision
+}
+
+// Evaluate decides whether the Policy allows or denies the input connection.
+func (p Policy) Evaluate(c Connection) Decision {
+	for _, r := range p.Deny {
+		if r.Matches(c) {
+			return DecisionDeny
+		}
+	}
+
+	for _, r := range p.Allow {
+		if r.Matches(c) {
+			return DecisionAllow
+		}
+	}
+
+	return p.Default
+}
+
+// ListViolations returns all active multipath TCP connections on this host
+// which are denied by the input Policy.
+//
+// If multipath TCP detection is not implemented for the current operating system,
+// this function will return ErrNotImplemented.
+func ListViolations(p Policy) ([]Connection, error) {
+	conns, err := listConnections()
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []Connection
+	for _, c := range conns {
+		if p.Evaluate(c) == DecisionDeny {
+			violations = append(violations, c)
+		}
+	}
+
+	return violations, nil
+}
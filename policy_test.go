@@ -0,0 +1,86 @@
+package mptcp
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+// TestPolicyEvaluate verifies that Policy.Evaluate applies deny rules, then
+// allow rules, and then the default decision.
+func TestPolicyEvaluate(t *testing.T) {
+	_, internal, err := net.ParseCIDR("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, bad, err := net.ParseCIDR("10.66.0.0/16")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn := func(ip string, port int, state State) Connection {
+		return Connection{
+			RemoteAddr: &net.TCPAddr{IP: net.ParseIP(ip), Port: port},
+			State:      state,
+		}
+	}
+
+	strict := Policy{
+		Allow: []Rule{
+			{RemoteNet: internal, Ports: []uint16{443, 8443}},
+			{States: []State{StateTimeWait}},
+		},
+		Deny: []Rule{
+			{RemoteNet: bad},
+		},
+		Default: DecisionDeny,
+	}
+
+	var tests = []struct {
+		desc string
+		p    Policy
+		c    Connection
+		d    Decision
+	}{
+		{"empty policy default", Policy{}, conn("192.0.2.1", 443, StateEstablished), DecisionAllow},
+		{"allow by network and port", strict, conn("10.1.2.3", 8443, StateEstablished), DecisionAllow},
+		{"allow by state", strict, conn("192.0.2.1", 22, StateTimeWait), DecisionAllow},
+		{"deny overrides allow", strict, conn("10.66.1.1", 443, StateEstablished), DecisionDeny},
+		{"default for wrong port", strict, conn("10.1.2.3", 22, StateEstablished), DecisionDeny},
+		{"default for wrong network", strict, conn("192.0.2.1", 443, StateEstablished), DecisionDeny},
+		{"default for unknown address", strict, Connection{State: StateEstablished}, DecisionDeny},
+	}
+
+	for i, test := range tests {
+		if d := test.p.Evaluate(test.c); d != test.d {
+			t.Fatalf("[%02d] unexpected decision: %v != %v [test: %v]", i, d, test.d, test.desc)
+		}
+	}
+}
+
+// TestListViolations verifies that ListViolations returns only connections
+// denied by a Policy, using a mock connection source.
+func TestListViolations(t *testing.T) {
+	origList := listConnections
+	defer func() { listConnections = origList }()
+
+	conns := []Connection{testSetConnA, testSetConnB, testSetConnC}
+	listConnections = func() ([]Connection, error) {
+		return conns, nil
+	}
+
+	// Only expect connections to port 2020
+	p := Policy{
+		Allow:   []Rule{{Ports: []uint16{2020}}},
+		Default: DecisionDeny,
+	}
+
+	violations, err := ListViolations(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []Connection{testSetConnB}; !reflect.DeepEqual(violations, want) {
+		t.Fatalf("unexpected violations: %v != %v", violations, want)
+	}
+}
@@ -0,0 +1,132 @@
+package mptcp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"strconv"
+)
+
+// ErrInvalidURL is returned when a URL passed to CheckURL does not contain
+// a host, or its port cannot be determined.
+var ErrInvalidURL = errors.New("invalid URL")
+
+// A Resolver resolves host names to IP addresses.  *net.Resolver implements
+// Resolver.
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// WithResolver configures a Checker to resolve host names passed to CheckHost
+// and CheckURL using r.
+//
+// If this option is not set, net.DefaultResolver is used.
+func WithResolver(r Resolver) Option {
+	return func(c *Checker) {
+		c.resolver = r
+	}
+}
+
+// CheckHost detects if there is an active multipath TCP connection to this
+// machine, originating from any IP address of the input host and the input
+// port, using the default Checker.  Its behavior is otherwise identical to
+// Checker.CheckHost.
+func CheckHost(ctx context.Context, host string, port uint16) (bool, error) {
+	return defaultChecker.CheckHost(ctx, host, port)
+}
+
+// CheckURL detects if there is an active multipath TCP connection to this
+// machine, originating from the host and port of the input URL, using the
+// default Checker.  Its behavior is otherwise identical to Checker.CheckURL.
+func CheckURL(ctx context.Context, rawURL string) (bool, error) {
+	return defaultChecker.CheckURL(ctx, rawURL)
+}
+
+// CheckHost detects if there is an active multipath TCP connection to this
+// machine, originating from any IP address of the input host and the input
+// port.  If host is an IP address, it is used directly.  Otherwise, host is
+// resolved using the Checker's Resolver.
+func (c *Checker) CheckHost(ctx context.Context, host string, port uint16) (bool, error) {
+	ips, err := c.lookupHost(ctx, host)
+	if err != nil {
+		return false, err
+	}
+
+	// Retrieve connections once, and check each resolved address
+	conns, err := c.ListConnections()
+	if err != nil {
+		return false, err
+	}
+
+	for _, ip := range ips {
+		if len(findConnections(conns, ip, port)) > 0 {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// CheckURL detects if there is an active multipath TCP connection to this
+// machine, originating from the host and port of the input URL.  If the URL
+// does not specify a port, the default port for the http and https schemes
+// is used.  Its behavior is otherwise identical to CheckHost.
+//
+// If the URL does not contain a host, or the port cannot be determined,
+// ErrInvalidURL is returned.
+func (c *Checker) CheckURL(ctx context.Context, rawURL string) (bool, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false, err
+	}
+
+	host := u.Hostname()
+	if host == "" {
+		return false, ErrInvalidURL
+	}
+
+	port := u.Port()
+	if port == "" {
+		switch u.Scheme {
+		case "http":
+			port = "80"
+		case "https":
+			port = "443"
+		default:
+			return false, ErrInvalidURL
+		}
+	}
+
+	uPort, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return false, ErrInvalidURL
+	}
+
+	return c.CheckHost(ctx, host, uint16(uPort))
+}
+
+// lookupHost returns the IP addresses of the input host, resolving it using
+// the Checker's Resolver if it is not an IP address.
+func (c *Checker) lookupHost(ctx context.Context, host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+
+	r := c.resolver
+	if r == nil {
+		r = net.DefaultResolver
+	}
+
+	addrs, err := r.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	ips := make([]net.IP, 0, len(addrs))
+	for _, a := range addrs {
+		ips = append(ips, a.IP)
+	}
+
+	return ips, nil
+}
@@ -0,0 +1,77 @@
+package mptcp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+// fakeResolver is a Resolver which returns fixed addresses for each host.
+type fakeResolver map[string][]net.IPAddr
+
+func (r fakeResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	addrs, ok := r[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	return addrs, nil
+}
+
+// TestCheckerCheckHost verifies that CheckHost and CheckURL check every
+// address returned by a Checker's Resolver.
+func TestCheckerCheckHost(t *testing.T) {
+	source := SourceFunc(func() ([]Connection, error) {
+		return []Connection{testSetConnB, testSetConnC}, nil
+	})
+
+	r := fakeResolver{
+		// IPv4 address of testSetConnB's remote host, after one which
+		// does not match
+		"dual.example.com": {{IP: net.ParseIP(ipv4BadHostOne)}, {IP: net.ParseIP(ipv4HostTwo)}},
+		// IPv6 address of testSetConnC's remote host
+		"v6.example.com": {{IP: net.ParseIP(ipv6HostOne)}},
+		// No matching addresses
+		"other.example.com": {{IP: net.ParseIP(ipv4BadHostTwo)}},
+	}
+
+	c := NewChecker(WithSources(source), WithResolver(r))
+
+	var tests = []struct {
+		desc string
+		fn   func() (bool, error)
+		ok   bool
+		err  bool
+	}{
+		{"host, second address", func() (bool, error) { return c.CheckHost(context.Background(), "dual.example.com", 4040) }, true, false},
+		{"host, wrong port", func() (bool, error) { return c.CheckHost(context.Background(), "dual.example.com", 2020) }, false, false},
+		{"host, IPv6", func() (bool, error) { return c.CheckHost(context.Background(), "v6.example.com", 2020) }, true, false},
+		{"host, no match", func() (bool, error) { return c.CheckHost(context.Background(), "other.example.com", 4040) }, false, false},
+		{"host, not found", func() (bool, error) { return c.CheckHost(context.Background(), "missing.example.com", 4040) }, false, true},
+		{"host, IP address", func() (bool, error) { return c.CheckHost(context.Background(), ipv4HostTwo, 4040) }, true, false},
+		{"URL, explicit port", func() (bool, error) { return c.CheckURL(context.Background(), "http://dual.example.com:4040/") }, true, false},
+		{"URL, IPv6 literal", func() (bool, error) { return c.CheckURL(context.Background(), "http://[2001:4860:4860::8888]:2020/") }, true, false},
+		{"URL, default port", func() (bool, error) { return c.CheckURL(context.Background(), "https://dual.example.com/") }, false, false},
+		{"URL, no host", func() (bool, error) { return c.CheckURL(context.Background(), "/foo") }, false, true},
+		{"URL, unknown scheme", func() (bool, error) { return c.CheckURL(context.Background(), "gopher://dual.example.com/") }, false, true},
+	}
+
+	for i, test := range tests {
+		ok, err := test.fn()
+		if (err != nil) != test.err {
+			t.Fatalf("[%02d] unexpected err: %v [test: %v]", i, err, test.desc)
+		}
+
+		if ok != test.ok {
+			t.Fatalf("[%02d] unexpected ok: %v != %v [test: %v]", i, ok, test.ok, test.desc)
+		}
+	}
+
+	// Resolution errors are returned unmodified
+	_, err := c.CheckHost(context.Background(), "missing.example.com", 4040)
+	var dnsErr *net.DNSError
+	if !errors.As(err, &dnsErr) {
+		t.Fatalf("unexpected err: %v", err)
+	}
+}
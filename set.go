@@ -0,0 +1,105 @@
+package mptcp
+
+import (
+	"sort"
+)
+
+// A ConnectionSet is a set of connections, keyed on the ID of each
+// connection.  ConnectionSets are useful for comparing snapshots of the
+// connections table taken at different times.
+//
+// The zero value of a ConnectionSet is an empty set, ready to use.
+type ConnectionSet struct {
+	m map[string]Connection
+}
+
+// NewConnectionSet creates a new ConnectionSet containing the input
+// connections.
+func NewConnectionSet(conns ...Connection) *ConnectionSet {
+	s := &ConnectionSet{
+		m: make(map[string]Connection, len(conns)),
+	}
+	for _, c := range conns {
+		s.Add(c)
+	}
+
+	return s
+}
+
+// Add adds a connection to the set.  If a connection with the same ID is
+// already present, it is replaced.
+func (s *ConnectionSet) Add(c Connection) {
+	if s.m == nil {
+		s.m = make(map[string]Connection)
+	}
+
+	s.m[c.ID()] = c
+}
+
+// Contains reports whether a connection with the same ID as the input
+// connection is present in the set.
+func (s *ConnectionSet) Contains(c Connection) bool {
+	_, ok := s.m[c.ID()]
+	return ok
+}
+
+// Len returns the number of connections in the set.
+func (s *ConnectionSet) Len() int {
+	return len(s.m)
+}
+
+// Connections returns the connections in the set, sorted by ID.
+func (s *ConnectionSet) Connections() []Connection {
+	ids := make([]string, 0, len(s.m))
+	for id := range s.m {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	conns := make([]Connection, 0, len(ids))
+	for _, id := range ids {
+		conns = append(conns, s.m[id])
+	}
+
+	return conns
+}
+
+// Union returns a new set containing the connections present in either
+// this set or the other set.
+func (s *ConnectionSet) Union(other *ConnectionSet) *ConnectionSet {
+	out := NewConnectionSet()
+	for id, c := range s.m {
+		out.m[id] = c
+	}
+	for id, c := range other.m {
+		out.m[id] = c
+	}
+
+	return out
+}
+
+// Intersect returns a new set containing the connections present in both
+// this set and the other set.
+func (s *ConnectionSet) Intersect(other *ConnectionSet) *ConnectionSet {
+	out := NewConnectionSet()
+	for id, c := range s.m {
+		if _, ok := other.m[id]; ok {
+			out.m[id] = c
+		}
+	}
+
+	return out
+}
+
+// Difference returns a new set containing the connections present in this
+// set, but not in the other set.
+func (s *ConnectionSet) Difference(other *ConnectionSet) *ConnectionSet {
+	out := NewConnectionSet()
+	for id, c := range s.m {
+		if _, ok := other.m[id]; !ok {
+			out.m[id] = c
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,87 @@
+package mptcp
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+var (
+	// Connections used for set operation tests
+	testSetConnA = Connection{
+		LocalToken: 0x1, RemoteToken: 0x2,
+		LocalAddr:  &net.TCPAddr{IP: net.ParseIP("192.168.1.10"), Port: 80},
+		RemoteAddr: &net.TCPAddr{IP: net.ParseIP(ipv4HostOne), Port: 2020},
+	}
+	testSetConnB = Connection{
+		LocalToken: 0x3, RemoteToken: 0x4,
+		LocalAddr:  &net.TCPAddr{IP: net.ParseIP("192.168.1.10"), Port: 80},
+		RemoteAddr: &net.TCPAddr{IP: net.ParseIP(ipv4HostTwo), Port: 4040},
+	}
+	testSetConnC = Connection{
+		LocalToken: 0x5, RemoteToken: 0x6, IsIPv6: true,
+		LocalAddr:  &net.TCPAddr{IP: net.ParseIP("2001:db8::10"), Port: 80},
+		RemoteAddr: &net.TCPAddr{IP: net.ParseIP(ipv6HostOne), Port: 2020},
+	}
+)
+
+// TestConnectionSet verifies that each ConnectionSet operation produces the
+// expected set of connections.
+func TestConnectionSet(t *testing.T) {
+	ab := NewConnectionSet(testSetConnA, testSetConnB)
+	bc := NewConnectionSet(testSetConnB, testSetConnC)
+	empty := NewConnectionSet()
+
+	var tests = []struct {
+		desc  string
+		set   *ConnectionSet
+		conns []Connection
+	}{
+		{"union", ab.Union(bc), []Connection{testSetConnA, testSetConnB, testSetConnC}},
+		{"union empty", ab.Union(empty), []Connection{testSetConnA, testSetConnB}},
+		{"intersect", ab.Intersect(bc), []Connection{testSetConnB}},
+		{"intersect empty", ab.Intersect(empty), []Connection{}},
+		{"difference", ab.Difference(bc), []Connection{testSetConnA}},
+		{"reverse difference", bc.Difference(ab), []Connection{testSetConnC}},
+		{"difference empty", ab.Difference(empty), []Connection{testSetConnA, testSetConnB}},
+		{"difference self", ab.Difference(ab), []Connection{}},
+	}
+
+	for i, test := range tests {
+		if conns := test.set.Connections(); !reflect.DeepEqual(conns, test.conns) {
+			t.Fatalf("[%02d] unexpected %s connections: %v != %v", i, test.desc, conns, test.conns)
+		}
+
+		if l := test.set.Len(); l != len(test.conns) {
+			t.Fatalf("[%02d] unexpected %s length: %v != %v", i, test.desc, l, len(test.conns))
+		}
+	}
+
+	// Operations must not modify their inputs
+	if l := ab.Len(); l != 2 {
+		t.Fatalf("set operations modified input set: %v", ab.Connections())
+	}
+}
+
+// TestConnectionSetContains verifies that Contains reports membership using
+// connection IDs, and that the zero value ConnectionSet is usable.
+func TestConnectionSetContains(t *testing.T) {
+	var s ConnectionSet
+	if s.Contains(testSetConnA) {
+		t.Fatal("empty set contains connection")
+	}
+
+	s.Add(testSetConnA)
+	if !s.Contains(testSetConnA) {
+		t.Fatal("set does not contain added connection")
+	}
+	if s.Contains(testSetConnB) {
+		t.Fatal("set contains connection which was not added")
+	}
+
+	// Adding a duplicate connection must not grow the set
+	s.Add(testSetConnA)
+	if l := s.Len(); l != 1 {
+		t.Fatalf("unexpected length after adding duplicate: %v != 1", l)
+	}
+}
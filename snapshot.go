@@ -0,0 +1,192 @@
+package mptcp
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"time"
+)
+
+// A snapshot is the set of connections retrieved by a Checker at a point
+// in time, indexed by remote address.
+type snapshot struct {
+	conns  []Connection
+	at     time.Time
+	remote map[netip.AddrPort][]Connection
+}
+
+// newSnapshot creates a snapshot of the input connections, taken at the
+// input time.
+func newSnapshot(conns []Connection, at time.Time) *snapshot {
+	// Copy the connections, as the caller may modify its slice
+	snap := &snapshot{
+		conns:  append([]Connection(nil), conns...),
+		at:     at,
+		remote: make(map[netip.AddrPort][]Connection, len(conns)),
+	}
+
+	for _, c := range snap.conns {
+		if c.RemoteAddr == nil {
+			continue
+		}
+
+		if key, ok := remoteKey(c.RemoteAddr.IP, c.RemoteAddr.Port); ok {
+			snap.remote[key] = append(snap.remote[key], c)
+		}
+	}
+
+	return snap
+}
+
+// find returns the connections in the snapshot whose remote address matches
+// the input IP address and port, in the order they were retrieved.
+func (s *snapshot) find(ip net.IP, port uint16) []Connection {
+	key, ok := remoteKey(ip, int(port))
+	if !ok {
+		return nil
+	}
+
+	// Copy the matches, so callers cannot modify the snapshot
+	return append([]Connection(nil), s.remote[key]...)
+}
+
+// remoteKey returns the key under which connections with the input remote IP
+// address and port are indexed.  IPv4-mapped IPv6 addresses are unmapped, so
+// that keys compare identically to net.IP.Equal.
+func remoteKey(ip net.IP, port int) (netip.AddrPort, bool) {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return netip.AddrPort{}, false
+	}
+
+	return netip.AddrPortFrom(addr.Unmap(), uint16(port)), true
+}
+
+// CheckCached detects if there was an active multipath TCP connection to this
+// machine, originating from the input host and port, using the snapshot of
+// connections taken the last time the default Checker retrieved connections.
+// Its behavior is otherwise identical to Checker.CheckCached.
+func CheckCached(host string, port uint16) (bool, time.Duration, error) {
+	return defaultChecker.CheckCached(host, port)
+}
+
+// CheckCached detects if there was an active multipath TCP connection to this
+// machine, originating from the input host and port, using the snapshot of
+// connections taken the last time the Checker retrieved connections.  The age
+// of the snapshot is returned, so the caller can decide whether the answer is
+// fresh enough, or whether to call Refresh and check again.
+//
+// If the Checker has not yet retrieved connections, a snapshot is taken
+// immediately, and its age is zero.
+//
+// If host is not a valid IP address, ErrInvalidIPAddress is returned.
+func (c *Checker) CheckCached(host string, port uint16) (bool, time.Duration, error) {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false, 0, ErrInvalidIPAddress
+	}
+
+	c.snapshots.Store(true)
+
+	snap := c.loadSnapshot()
+	if snap == nil {
+		if err := c.Refresh(); err != nil {
+			return false, 0, err
+		}
+
+		snap = c.loadSnapshot()
+	}
+
+	found := len(snap.remote[mustRemoteKey(ip, port)]) > 0
+	return found, c.now().Sub(snap.at), nil
+}
+
+// mustRemoteKey returns the index key for a valid, parsed IP address.
+func mustRemoteKey(ip net.IP, port uint16) netip.AddrPort {
+	key, _ := remoteKey(ip, int(port))
+	return key
+}
+
+// freshSnapshot returns the Checker's snapshot if it was taken within the
+// TTL configured by WithCacheTTL, or nil otherwise.
+func (c *Checker) freshSnapshot() *snapshot {
+	if c.cacheTTL <= 0 {
+		return nil
+	}
+
+	snap := c.loadSnapshot()
+	if snap == nil || c.now().Sub(snap.at) >= c.cacheTTL {
+		return nil
+	}
+
+	return snap
+}
+
+// loadSnapshot returns the Checker's most recent snapshot, or nil if the
+// Checker has not yet retrieved connections.
+func (c *Checker) loadSnapshot() *snapshot {
+	c.snapMu.RLock()
+	defer c.snapMu.RUnlock()
+
+	return c.snap
+}
+
+// RefreshEvery starts refreshing the Checker's snapshot at each interval in
+// the background, until ctx is canceled.  Combined with WithCacheTTL, this
+// allows Check and FindAll to be answered from memory on busy hosts, without
+// any caller waiting for the connections table to be read.
+//
+// Refreshes which fail are skipped, and the previous snapshot is kept until
+// it expires.
+//
+// If interval is not positive, ErrInvalidInterval is returned.
+func (c *Checker) RefreshEvery(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return ErrInvalidInterval
+	}
+	c.snapshots.Store(true)
+
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+
+			_ = c.Refresh()
+		}
+	}()
+
+	return nil
+}
+
+// Refresh retrieves the active multipath TCP connections, replacing the
+// snapshot used by CheckCached.  Once CheckCached, Refresh, or RefreshEvery
+// has been called, or if WithCacheTTL is configured, every successful call to
+// ListConnections, Check, or FindAll also replaces the snapshot.  Otherwise,
+// no snapshot is stored, so that listing connections does not copy and index
+// them.
+func (c *Checker) Refresh() error {
+	c.snapshots.Store(true)
+
+	_, err := c.ListConnections()
+	return err
+}
+
+// storeSnapshot replaces the Checker's snapshot with the input connections,
+// timestamped using the Checker's clock, if snapshots are in use.
+func (c *Checker) storeSnapshot(conns []Connection) {
+	if c.cacheTTL <= 0 && !c.snapshots.Load() {
+		return
+	}
+
+	snap := newSnapshot(conns, c.now())
+
+	c.snapMu.Lock()
+	c.snap = snap
+	c.snapMu.Unlock()
+}
@@ -0,0 +1,244 @@
+package mptcp
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestCheckerCheckCached verifies that CheckCached answers from the most
+// recent snapshot, and reports an age which tracks the Checker's clock.
+func TestCheckerCheckCached(t *testing.T) {
+	var (
+		conns []Connection
+		polls int
+	)
+	source := SourceFunc(func() ([]Connection, error) {
+		polls++
+		return conns, nil
+	})
+
+	// Fake clock, advanced manually by the test
+	start := time.Date(2016, time.January, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+
+	c := NewChecker(WithSources(source))
+	c.now = func() time.Time { return now }
+
+	conns = []Connection{testSetConnA}
+
+	var tests = []struct {
+		desc    string
+		advance time.Duration
+		refresh bool
+		host    string
+		port    uint16
+		found   bool
+		age     time.Duration
+		polls   int
+	}{
+		{"initial snapshot", 0, false, ipv4HostOne, 2020, true, 0, 1},
+		{"cached snapshot", 5 * time.Second, false, ipv4HostOne, 2020, true, 5 * time.Second, 1},
+		{"not found in snapshot", 5 * time.Second, false, ipv4HostTwo, 4040, false, 10 * time.Second, 1},
+		{"refreshed snapshot", time.Second, true, ipv4HostTwo, 4040, true, 0, 2},
+		{"aged refreshed snapshot", time.Minute, false, ipv4HostTwo, 4040, true, time.Minute, 2},
+	}
+
+	for i, test := range tests {
+		now = now.Add(test.advance)
+
+		// The connection appears only once the snapshot is refreshed
+		if test.refresh {
+			conns = []Connection{testSetConnA, testSetConnB}
+			if err := c.Refresh(); err != nil {
+				t.Fatalf("[%02d] unexpected refresh err: %v", i, err)
+			}
+		}
+
+		found, age, err := c.CheckCached(test.host, test.port)
+		if err != nil {
+			t.Fatalf("[%02d] unexpected err: %v [test: %v]", i, err, test.desc)
+		}
+
+		if found != test.found {
+			t.Fatalf("[%02d] unexpected found: %v != %v [test: %v]", i, found, test.found, test.desc)
+		}
+
+		if age != test.age {
+			t.Fatalf("[%02d] unexpected age: %v != %v [test: %v]", i, age, test.age, test.desc)
+		}
+
+		if polls != test.polls {
+			t.Fatalf("[%02d] unexpected poll count: %v != %v [test: %v]", i, polls, test.polls, test.desc)
+		}
+	}
+
+	if _, _, err := c.CheckCached(badIPHostOne, 0); err != ErrInvalidIPAddress {
+		t.Fatalf("unexpected err: %v != %v", err, ErrInvalidIPAddress)
+	}
+}
+
+// TestCheckerSnapshotsUnused verifies that a Checker does not store snapshots
+// until they are used, unless a cache TTL is configured.
+func TestCheckerSnapshotsUnused(t *testing.T) {
+	source := SourceFunc(func() ([]Connection, error) {
+		return []Connection{testSetConnA}, nil
+	})
+
+	var tests = []struct {
+		desc    string
+		options []Option
+		use     func(c *Checker) error
+		stored  bool
+	}{
+		{desc: "unused", use: func(*Checker) error { return nil }},
+		{desc: "cache TTL", options: []Option{WithCacheTTL(time.Minute)}, use: func(*Checker) error { return nil }, stored: true},
+		{desc: "Refresh", use: func(c *Checker) error { return c.Refresh() }, stored: true},
+		{
+			desc: "CheckCached",
+			use: func(c *Checker) error {
+				_, _, err := c.CheckCached(ipv4HostOne, 2020)
+				return err
+			},
+			stored: true,
+		},
+		{
+			desc: "RefreshEvery",
+			use: func(c *Checker) error {
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+				return c.RefreshEvery(ctx, time.Hour)
+			},
+			stored: true,
+		},
+	}
+
+	for i, test := range tests {
+		c := NewChecker(append(test.options, WithSources(source))...)
+		if err := test.use(c); err != nil {
+			t.Fatalf("[%02d] unexpected err: %v [test: %v]", i, err, test.desc)
+		}
+
+		// Drop any snapshot taken by the use itself, to observe whether
+		// listing connections stores one
+		c.snapMu.Lock()
+		c.snap = nil
+		c.snapMu.Unlock()
+
+		if _, err := c.ListConnections(); err != nil {
+			t.Fatalf("[%02d] unexpected list err: %v [test: %v]", i, err, test.desc)
+		}
+
+		if stored := c.loadSnapshot() != nil; stored != test.stored {
+			t.Fatalf("[%02d] unexpected snapshot stored: %v != %v [test: %v]", i, stored, test.stored, test.desc)
+		}
+	}
+}
+
+// TestCheckerCacheTTL verifies that a Checker configured with WithCacheTTL
+// answers checks from its indexed snapshot until the snapshot expires.
+func TestCheckerCacheTTL(t *testing.T) {
+	var polls int
+	conns := []Connection{testSetConnA}
+	source := SourceFunc(func() ([]Connection, error) {
+		polls++
+		return conns, nil
+	})
+
+	// Fake clock, advanced manually by the test
+	now := time.Date(2016, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	c := NewChecker(WithSources(source), WithCacheTTL(10*time.Second))
+	c.now = func() time.Time { return now }
+
+	var tests = []struct {
+		desc    string
+		advance time.Duration
+		change  bool
+		host    string
+		port    uint16
+		found   []Connection
+		polls   int
+	}{
+		{"initial read", 0, false, ipv4HostOne, 2020, []Connection{testSetConnA}, 1},
+		{"cached match", 5 * time.Second, false, ipv4HostOne, 2020, []Connection{testSetConnA}, 1},
+		{"cached IPv4-mapped match", 0, false, "::ffff:" + ipv4HostOne, 2020, []Connection{testSetConnA}, 1},
+		{"new connection not yet cached", time.Second, true, ipv4HostTwo, 4040, nil, 1},
+		{"expired snapshot", 4 * time.Second, false, ipv4HostTwo, 4040, []Connection{testSetConnB}, 2},
+		{"cached IPv6 match", 9 * time.Second, false, ipv6HostOne, 2020, []Connection{testSetConnC}, 2},
+		{"cached miss", 0, false, ipv6HostOne, 2021, nil, 2},
+	}
+
+	for i, test := range tests {
+		now = now.Add(test.advance)
+		if test.change {
+			conns = []Connection{testSetConnA, testSetConnB, testSetConnC}
+		}
+
+		found, err := c.FindAll(test.host, test.port)
+		if err != nil {
+			t.Fatalf("[%02d] unexpected err: %v [test: %v]", i, err, test.desc)
+		}
+
+		if !reflect.DeepEqual(found, test.found) {
+			t.Fatalf("[%02d] unexpected connections:\n- want: %v\n-  got: %v [test: %v]", i, test.found, found, test.desc)
+		}
+
+		ok, err := c.Check(net.JoinHostPort(test.host, "0"))
+		if err != nil || ok {
+			t.Fatalf("[%02d] unexpected check result: %v, %v [test: %v]", i, ok, err, test.desc)
+		}
+
+		if polls != test.polls {
+			t.Fatalf("[%02d] unexpected poll count: %v != %v [test: %v]", i, polls, test.polls, test.desc)
+		}
+	}
+}
+
+// TestCheckerRefreshEvery verifies that RefreshEvery refreshes a Checker's
+// snapshot in the background until its context is canceled.
+func TestCheckerRefreshEvery(t *testing.T) {
+	var polls int32
+	source := SourceFunc(func() ([]Connection, error) {
+		atomic.AddInt32(&polls, 1)
+		return []Connection{testSetConnA}, nil
+	})
+
+	c := NewChecker(WithSources(source))
+
+	if err := c.RefreshEvery(context.Background(), 0); err != ErrInvalidInterval {
+		t.Fatalf("unexpected err: %v != %v", err, ErrInvalidInterval)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := c.RefreshEvery(ctx, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	// Wait for several refreshes
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&polls) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for refreshes")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	if c.loadSnapshot() == nil {
+		t.Fatal("no snapshot stored by background refreshes")
+	}
+
+	// No refreshes occur once canceled, allowing for one in progress
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	stopped := atomic.LoadInt32(&polls)
+	time.Sleep(20 * time.Millisecond)
+
+	if p := atomic.LoadInt32(&polls); p != stopped {
+		t.Fatalf("refreshes continued after cancellation: %v != %v", p, stopped)
+	}
+}
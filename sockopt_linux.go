@@ -0,0 +1,24 @@
+// +build linux,!386
+
+package mptcp
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// getsockopt invokes the getsockopt system call directly, storing the value
+// of the socket option in the buffer at p and its length in l.
+func getsockopt(fd, level, opt int, p unsafe.Pointer, l *uint32) syscall.Errno {
+	_, _, errno := syscall.Syscall6(
+		syscall.SYS_GETSOCKOPT,
+		uintptr(fd),
+		uintptr(level),
+		uintptr(opt),
+		uintptr(p),
+		uintptr(unsafe.Pointer(l)),
+		0,
+	)
+
+	return errno
+}
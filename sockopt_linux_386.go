@@ -0,0 +1,34 @@
+// +build linux,386
+
+package mptcp
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// sysGetsockopt is the socketcall call number of getsockopt.  Linux on 386
+// multiplexes socket system calls through socketcall, so package syscall does
+// not define SYS_GETSOCKOPT there.
+const sysGetsockopt = 15
+
+// getsockopt invokes getsockopt through the socketcall system call, storing
+// the value of the socket option in the buffer at p and its length in l.
+func getsockopt(fd, level, opt int, p unsafe.Pointer, l *uint32) syscall.Errno {
+	args := [5]uintptr{
+		uintptr(fd),
+		uintptr(level),
+		uintptr(opt),
+		uintptr(p),
+		uintptr(unsafe.Pointer(l)),
+	}
+
+	_, _, errno := syscall.Syscall(
+		syscall.SYS_SOCKETCALL,
+		sysGetsockopt,
+		uintptr(unsafe.Pointer(&args)),
+		0,
+	)
+
+	return errno
+}
@@ -0,0 +1,38 @@
+package mptcp
+
+// A Source is a source of active multipath TCP connections, such as an
+// operating system interface.
+//
+// A Checker which is not configured with any Sources is itself a Source,
+// which reads the operating system's connections table.
+type Source interface {
+	ListConnections() ([]Connection, error)
+}
+
+// SourceFunc is an adapter which allows an ordinary function to be used as
+// a Source.
+type SourceFunc func() ([]Connection, error)
+
+// ListConnections calls f.
+func (f SourceFunc) ListConnections() ([]Connection, error) {
+	return f()
+}
+
+// NetlinkSource returns a Source which retrieves connections from the same
+// operating system interface as ListConnectionDetails.  Address IDs are not
+// retrieved, so LocalAddrID and RemoteAddrID are always zero.
+func NetlinkSource() Source {
+	return SourceFunc(func() ([]Connection, error) {
+		details, err := listDiagConnections()
+		if err != nil {
+			return nil, err
+		}
+
+		conns := make([]Connection, 0, len(details))
+		for _, d := range details {
+			conns = append(conns, d.Connection)
+		}
+
+		return conns, nil
+	})
+}
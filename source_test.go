@@ -0,0 +1,114 @@
+package mptcp
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// TestCheckerWithSources verifies that a Checker configured with multiple
+// sources uses the first source which succeeds, and aggregates errors only
+// if every source fails.
+func TestCheckerWithSources(t *testing.T) {
+	var (
+		errPrimary  = errors.New("primary failed")
+		errFallback = errors.New("fallback failed")
+
+		primaryConns = []Connection{{
+			LocalAddr:  &net.TCPAddr{IP: net.ParseIP("192.168.1.10"), Port: 80},
+			RemoteAddr: &net.TCPAddr{IP: net.ParseIP(ipv4HostOne), Port: 2020},
+		}}
+		fallbackConns = []Connection{{
+			LocalAddr:  &net.TCPAddr{IP: net.ParseIP("192.168.1.10"), Port: 80},
+			RemoteAddr: &net.TCPAddr{IP: net.ParseIP(ipv4HostTwo), Port: 4040},
+		}}
+	)
+
+	// Sources which record whether they were consulted
+	var consulted []string
+	source := func(name string, conns []Connection, err error) Source {
+		return SourceFunc(func() ([]Connection, error) {
+			consulted = append(consulted, name)
+			return conns, err
+		})
+	}
+
+	var tests = []struct {
+		desc      string
+		sources   []Source
+		conns     []Connection
+		errs      []error
+		consulted []string
+	}{
+		{
+			desc:      "primary succeeds",
+			sources:   []Source{source("primary", primaryConns, nil), source("fallback", fallbackConns, nil)},
+			conns:     primaryConns,
+			consulted: []string{"primary"},
+		},
+		{
+			desc:      "primary succeeds with no connections",
+			sources:   []Source{source("primary", nil, nil), source("fallback", fallbackConns, nil)},
+			consulted: []string{"primary"},
+		},
+		{
+			desc:      "primary fails, fallback succeeds",
+			sources:   []Source{source("primary", nil, errPrimary), source("fallback", fallbackConns, nil)},
+			conns:     fallbackConns,
+			consulted: []string{"primary", "fallback"},
+		},
+		{
+			desc:      "all sources fail",
+			sources:   []Source{source("primary", nil, errPrimary), source("fallback", nil, errFallback)},
+			errs:      []error{errPrimary, errFallback},
+			consulted: []string{"primary", "fallback"},
+		},
+	}
+
+	for i, test := range tests {
+		consulted = nil
+
+		conns, err := NewChecker(WithSources(test.sources...)).ListConnections()
+		if len(test.errs) == 0 && err != nil {
+			t.Fatalf("[%02d] %s: unexpected err: %v", i, test.desc, err)
+		}
+		for _, e := range test.errs {
+			if !errors.Is(err, e) {
+				t.Fatalf("[%02d] %s: err %v does not contain %v", i, test.desc, err, e)
+			}
+		}
+
+		if !reflect.DeepEqual(conns, test.conns) {
+			t.Fatalf("[%02d] %s: unexpected conns: %v != %v", i, test.desc, conns, test.conns)
+		}
+
+		if !reflect.DeepEqual(consulted, test.consulted) {
+			t.Fatalf("[%02d] %s: unexpected sources consulted: %v != %v", i, test.desc, consulted, test.consulted)
+		}
+	}
+}
+
+// TestCheckerCheckWithSources verifies that Check uses the fallback source
+// when the primary source fails.
+func TestCheckerCheckWithSources(t *testing.T) {
+	c := NewChecker(WithSources(
+		SourceFunc(func() ([]Connection, error) {
+			return nil, ErrNotImplemented
+		}),
+		SourceFunc(func() ([]Connection, error) {
+			return []Connection{{
+				LocalAddr:  &net.TCPAddr{IP: net.ParseIP("192.168.1.10"), Port: 80},
+				RemoteAddr: &net.TCPAddr{IP: net.ParseIP(ipv4HostOne), Port: 2020},
+			}}, nil
+		}),
+	))
+
+	ok, err := c.Check(net.JoinHostPort(ipv4HostOne, "2020"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("expected connection from fallback source")
+	}
+}
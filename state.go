@@ -0,0 +1,67 @@
+package mptcp
+
+import (
+	"fmt"
+)
+
+// A State is the TCP state of a multipath TCP connection, using the state
+// numbering of the Linux kernel.
+type State uint8
+
+// Possible State values.  The zero value indicates that the state of a
+// connection is not known.
+const (
+	StateUnknown State = iota
+	StateEstablished
+	StateSynSent
+	StateSynRecv
+	StateFinWait1
+	StateFinWait2
+	StateTimeWait
+	StateClose
+	StateCloseWait
+	StateLastAck
+	StateListen
+	StateClosing
+)
+
+// stateNames are the names of each known State, as used by the kernel.
+var stateNames = map[State]string{
+	StateUnknown:     "UNKNOWN",
+	StateEstablished: "ESTABLISHED",
+	StateSynSent:     "SYN_SENT",
+	StateSynRecv:     "SYN_RECV",
+	StateFinWait1:    "FIN_WAIT1",
+	StateFinWait2:    "FIN_WAIT2",
+	StateTimeWait:    "TIME_WAIT",
+	StateClose:       "CLOSE",
+	StateCloseWait:   "CLOSE_WAIT",
+	StateLastAck:     "LAST_ACK",
+	StateListen:      "LISTEN",
+	StateClosing:     "CLOSING",
+}
+
+// String returns the name of the State, as used by the kernel.
+func (s State) String() string {
+	if name, ok := stateNames[s]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("State(%d)", uint8(s))
+}
+
+// filterStates returns a new slice containing only the input connections
+// which are in one of the input states.
+func filterStates(conns []Connection, states []State) []Connection {
+	var out []Connection
+	for _, c := range conns {
+		for _, s := range states {
+			if c.State == s {
+				out = append(out, c)
+				break
+			}
+		}
+	}
+
+	return out
+}
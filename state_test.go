@@ -0,0 +1,149 @@
+package mptcp
+
+import (
+	"net"
+	"testing"
+)
+
+// TestStateString verifies that State.String returns the kernel's name for
+// each known state.
+func TestStateString(t *testing.T) {
+	var tests = []struct {
+		s    State
+		name string
+	}{
+		{StateUnknown, "UNKNOWN"},
+		{StateEstablished, "ESTABLISHED"},
+		{StateSynSent, "SYN_SENT"},
+		{StateTimeWait, "TIME_WAIT"},
+		{StateListen, "LISTEN"},
+		{StateClosing, "CLOSING"},
+		{State(0xff), "State(255)"},
+	}
+
+	for i, test := range tests {
+		if name := test.s.String(); name != test.name {
+			t.Fatalf("[%02d] unexpected name: %v != %v", i, name, test.name)
+		}
+	}
+}
+
+// TestStateValues verifies that each State uses the numbering of the Linux
+// kernel's st column.
+func TestStateValues(t *testing.T) {
+	states := []State{
+		StateEstablished,
+		StateSynSent,
+		StateSynRecv,
+		StateFinWait1,
+		StateFinWait2,
+		StateTimeWait,
+		StateClose,
+		StateCloseWait,
+		StateLastAck,
+		StateListen,
+		StateClosing,
+	}
+
+	for i, s := range states {
+		if want := State(i + 1); s != want {
+			t.Fatalf("[%02d] unexpected value for %v: %d != %d", i, s, s, want)
+		}
+	}
+}
+
+// TestCheckerWithStates verifies that a Checker configured with WithStates
+// only lists and checks connections in the input states.
+func TestCheckerWithStates(t *testing.T) {
+	remote := func(port int) *net.TCPAddr {
+		return &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: port}
+	}
+
+	source := SourceFunc(func() ([]Connection, error) {
+		return []Connection{
+			{State: StateEstablished, RemoteAddr: remote(1000)},
+			{State: StateSynRecv, RemoteAddr: remote(2000)},
+			{State: StateFinWait1, RemoteAddr: remote(3000)},
+		}, nil
+	})
+
+	var tests = []struct {
+		states []State
+		count  int
+		ports  map[string]bool
+	}{
+		// No filter
+		{nil, 3, map[string]bool{"1000": true, "2000": true, "3000": true}},
+		// Established only
+		{[]State{StateEstablished}, 1, map[string]bool{"1000": true, "2000": false, "3000": false}},
+		// Multiple states
+		{[]State{StateSynRecv, StateFinWait1}, 2, map[string]bool{"1000": false, "2000": true, "3000": true}},
+		// No matching states
+		{[]State{StateClosing}, 0, map[string]bool{"1000": false, "2000": false, "3000": false}},
+	}
+
+	for i, test := range tests {
+		c := NewChecker(WithSources(source), WithStates(test.states...))
+
+		conns, err := c.ListConnections()
+		if err != nil {
+			t.Fatalf("[%02d] unexpected err: %v", i, err)
+		}
+		if len(conns) != test.count {
+			t.Fatalf("[%02d] unexpected connection count: %d != %d", i, len(conns), test.count)
+		}
+
+		for port, want := range test.ports {
+			ok, err := c.Check(net.JoinHostPort("192.0.2.1", port))
+			if err != nil {
+				t.Fatalf("[%02d] unexpected err: %v", i, err)
+			}
+			if ok != want {
+				t.Fatalf("[%02d] unexpected match for port %s: %v != %v", i, port, ok, want)
+			}
+		}
+	}
+}
+
+// TestCheckEstablished verifies that CheckEstablished does not report a match
+// for connections which are not established.
+func TestCheckEstablished(t *testing.T) {
+	origList := listConnections
+	defer func() { listConnections = origList }()
+
+	remote := func(port int) *net.TCPAddr {
+		return &net.TCPAddr{IP: net.ParseIP(ipv4HostOne), Port: port}
+	}
+	listConnections = func() ([]Connection, error) {
+		return []Connection{
+			{State: StateEstablished, RemoteAddr: remote(1000)},
+			{State: StateSynRecv, RemoteAddr: remote(2000)},
+			// Subflows of one peer in different states
+			{State: StateFinWait1, RemoteAddr: remote(3000)},
+			{State: StateEstablished, RemoteAddr: remote(3000)},
+		}, nil
+	}
+
+	var tests = []struct {
+		hostport string
+		ok       bool
+		err      error
+	}{
+		{"foo:1000", false, ErrInvalidIPAddress},
+		{net.JoinHostPort(ipv4HostOne, "1000"), true, nil},
+		{net.JoinHostPort(ipv4HostOne, "2000"), false, nil},
+		{net.JoinHostPort(ipv4HostOne, "3000"), true, nil},
+		{net.JoinHostPort(ipv4HostOne, "4000"), false, nil},
+	}
+
+	for i, test := range tests {
+		ok, err := CheckEstablished(test.hostport)
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected err: %v != %v", i, err, test.err)
+		}
+
+		if ok != test.ok {
+			t.Fatalf("[%02d] unexpected ok: %v != %v", i, ok, test.ok)
+		}
+	}
+}
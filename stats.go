@@ -0,0 +1,23 @@
+package mptcp
+
+// Counters contains the kernel-wide multipath TCP counters of this host, keyed
+// by the name the kernel uses for each counter, such as "MPCapableSYNRX",
+// "MPJoinAckRx", or "MPTCPRetrans".
+//
+// The set of counters depends on the kernel and its version, so callers
+// should not assume that any particular counter is present.  A counter which
+// is not present reads as zero.
+type Counters map[string]uint64
+
+// Stats returns the kernel-wide multipath TCP counters of this host,
+// suitable for export to monitoring systems.
+//
+// On Linux, counters are read from the MPTcpExt section of /proc/net/netstat
+// on kernels with mainline MPTCP support, or from /proc/net/mptcp_net/snmp on
+// the out-of-tree kernel.
+//
+// If multipath TCP statistics are not available on the current operating
+// system, this function will return ErrNotImplemented.
+func Stats() (Counters, error) {
+	return mptcpStats()
+}
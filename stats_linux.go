@@ -0,0 +1,148 @@
+// +build linux
+
+package mptcp
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// procNetstat is the location of the Linux-specific file which contains
+	// extended network statistics, including the MPTcpExt counters of
+	// kernels with mainline MPTCP support.
+	procNetstat = "/proc/net/netstat"
+
+	// procMPTCPSNMP is the location of the Linux-specific file which contains
+	// the MPTCP counters of the out-of-tree kernel.
+	procMPTCPSNMP = "/proc/net/mptcp_net/snmp"
+
+	// netstatMPTCPPrefix is the prefix of the lines in procNetstat which
+	// contain the names and values of the MPTCP counters.
+	netstatMPTCPPrefix = "MPTcpExt:"
+)
+
+var (
+	// errInvalidStats is returned when an input statistics file is not in
+	// the expected format.
+	errInvalidStats = errors.New("invalid MPTCP statistics")
+)
+
+// mptcpStats uses the Linux /proc filesystem to retrieve the kernel-wide MPTCP
+// counters, preferring the counters of the mainline kernel.
+var mptcpStats = func() (Counters, error) {
+	stats, ok, err := readStatsFileLinux(procNetstat, parseNetstatMPTCPLinux)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if ok {
+		return stats, nil
+	}
+
+	// Fall back to the counters of the out-of-tree kernel
+	stats, _, err = readStatsFileLinux(procMPTCPSNMP, parseSNMPMPTCPLinux)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// readStatsFileLinux opens the statistics file at the input path, and parses
+// it using parse.
+func readStatsFileLinux(path string, parse func(r io.Reader) (Counters, bool, error)) (Counters, bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+
+	return parse(f)
+}
+
+// parseNetstatMPTCPLinux parses the MPTcpExt counters from an input stream in
+// the format of /proc/net/netstat, in which each section is a line of counter
+// names followed by a line of their values.  If the stream contains no MPTCP
+// counters, ok is false.
+func parseNetstatMPTCPLinux(r io.Reader) (stats Counters, ok bool, err error) {
+	var names []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, netstatMPTCPPrefix) {
+			continue
+		}
+
+		fields := strings.Fields(strings.TrimPrefix(line, netstatMPTCPPrefix))
+
+		// The first line of a section contains names
+		if names == nil {
+			names = fields
+			continue
+		}
+
+		// The second line contains values
+		if len(fields) != len(names) {
+			return nil, false, errInvalidStats
+		}
+
+		stats = make(Counters, len(names))
+		for i, f := range fields {
+			v, err := strconv.ParseUint(f, 10, 64)
+			if err != nil {
+				return nil, false, errInvalidStats
+			}
+
+			stats[names[i]] = v
+		}
+
+		return stats, true, nil
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, false, err
+	}
+
+	// A names line without a values line is truncated
+	if names != nil {
+		return nil, false, errInvalidStats
+	}
+
+	return nil, false, nil
+}
+
+// parseSNMPMPTCPLinux parses MPTCP counters from an input stream in the format
+// of /proc/net/mptcp_net/snmp, in which each line contains a counter name and
+// its value.
+func parseSNMPMPTCPLinux(r io.Reader) (Counters, bool, error) {
+	stats := make(Counters)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, false, errInvalidStats
+		}
+
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, false, errInvalidStats
+		}
+
+		stats[fields[0]] = v
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, false, err
+	}
+
+	return stats, true, nil
+}
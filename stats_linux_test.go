@@ -0,0 +1,166 @@
+// +build linux
+
+package mptcp
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestLinux_parseNetstatMPTCPLinux verifies that MPTcpExt counters are parsed
+// correctly from input streams in the format of /proc/net/netstat.
+func TestLinux_parseNetstatMPTCPLinux(t *testing.T) {
+	var tests = []struct {
+		desc  string
+		s     string
+		stats Counters
+		ok    bool
+		err   error
+	}{
+		{
+			desc: "no MPTCP section",
+			s: strings.Join([]string{
+				"TcpExt: SyncookiesSent SyncookiesRecv",
+				"TcpExt: 0 0",
+			}, "\n"),
+		},
+		{
+			desc: "names without values",
+			s:    "MPTcpExt: MPCapableSYNRX MPJoinAckRx",
+			err:  errInvalidStats,
+		},
+		{
+			desc: "mismatched values",
+			s: strings.Join([]string{
+				"MPTcpExt: MPCapableSYNRX MPJoinAckRx",
+				"MPTcpExt: 1",
+			}, "\n"),
+			err: errInvalidStats,
+		},
+		{
+			desc: "invalid value",
+			s: strings.Join([]string{
+				"MPTcpExt: MPCapableSYNRX MPJoinAckRx",
+				"MPTcpExt: 1 foo",
+			}, "\n"),
+			err: errInvalidStats,
+		},
+		{
+			desc: "OK",
+			s: strings.Join([]string{
+				"TcpExt: SyncookiesSent SyncookiesRecv",
+				"TcpExt: 0 0",
+				"MPTcpExt: MPCapableSYNRX MPJoinAckRx MPTCPRetrans MPFallbackTokenInit",
+				"MPTcpExt: 10 2 3 4",
+			}, "\n"),
+			stats: Counters{
+				"MPCapableSYNRX":      10,
+				"MPJoinAckRx":         2,
+				"MPTCPRetrans":        3,
+				"MPFallbackTokenInit": 4,
+			},
+			ok: true,
+		},
+	}
+
+	for i, tt := range tests {
+		stats, ok, err := parseNetstatMPTCPLinux(strings.NewReader(tt.s))
+		if want, got := tt.err, err; want != got {
+			t.Fatalf("[%02d] test %q, unexpected error: %v != %v",
+				i, tt.desc, want, got)
+		}
+		if err != nil {
+			continue
+		}
+
+		if want, got := tt.ok, ok; want != got {
+			t.Fatalf("[%02d] test %q, unexpected ok: %v != %v",
+				i, tt.desc, want, got)
+		}
+		if want, got := tt.stats, stats; !reflect.DeepEqual(want, got) {
+			t.Fatalf("[%02d] test %q, unexpected stats: %v != %v",
+				i, tt.desc, want, got)
+		}
+	}
+}
+
+// TestLinux_parseSNMPMPTCPLinux verifies that MPTCP counters are parsed
+// correctly from input streams in the format of /proc/net/mptcp_net/snmp.
+func TestLinux_parseSNMPMPTCPLinux(t *testing.T) {
+	var tests = []struct {
+		desc  string
+		s     string
+		stats Counters
+		err   error
+	}{
+		{
+			desc: "too many fields",
+			s:    "MPCapableSYNRX 1 2",
+			err:  errInvalidStats,
+		},
+		{
+			desc: "invalid value",
+			s:    "MPCapableSYNRX foo",
+			err:  errInvalidStats,
+		},
+		{
+			desc:  "empty",
+			stats: Counters{},
+		},
+		{
+			desc: "OK",
+			s: strings.Join([]string{
+				"MPCapableSYNRX                  \t10",
+				"MPJoinAckRx                     \t2",
+				"",
+				"MPTCPRetrans                    \t3",
+			}, "\n"),
+			stats: Counters{
+				"MPCapableSYNRX": 10,
+				"MPJoinAckRx":    2,
+				"MPTCPRetrans":   3,
+			},
+		},
+	}
+
+	for i, tt := range tests {
+		stats, _, err := parseSNMPMPTCPLinux(strings.NewReader(tt.s))
+		if want, got := tt.err, err; want != got {
+			t.Fatalf("[%02d] test %q, unexpected error: %v != %v",
+				i, tt.desc, want, got)
+		}
+		if err != nil {
+			continue
+		}
+
+		if want, got := tt.stats, stats; !reflect.DeepEqual(want, got) {
+			t.Fatalf("[%02d] test %q, unexpected stats: %v != %v",
+				i, tt.desc, want, got)
+		}
+	}
+}
+
+// TestLinux_Stats verifies that Stats returns the counters of the
+// current kernel, if it exposes any.
+func TestLinux_Stats(t *testing.T) {
+	f, err := os.Open(procNetstat)
+	if err != nil {
+		t.Skipf("skipping, cannot open %s: %v", procNetstat, err)
+	}
+	_, ok, err := parseNetstatMPTCPLinux(f)
+	_ = f.Close()
+	if err != nil || !ok {
+		t.Skip("skipping, kernel does not expose MPTcpExt counters")
+	}
+
+	stats, err := Stats()
+	if err != nil {
+		t.Fatalf("failed to read stats: %v", err)
+	}
+
+	if _, ok := stats["MPCapableSYNRX"]; !ok {
+		t.Fatalf("stats do not contain MPCapableSYNRX: %v", stats)
+	}
+}
@@ -0,0 +1,79 @@
+package mptcp
+
+import "net"
+
+// A Subflow is one of the TCP subflows which make up an active multipath TCP
+// connection.
+type Subflow struct {
+	// LocalToken is the local MPTCP token of the connection which this
+	// subflow belongs to.
+	LocalToken uint32
+
+	// LocalAddr and RemoteAddr are the local and remote TCP addresses of
+	// this subflow, which may differ from those of other subflows of the
+	// same connection.
+	LocalAddr  *net.TCPAddr
+	RemoteAddr *net.TCPAddr
+
+	// State is the TCP state of this subflow.
+	State State
+
+	// LocalAddrID and RemoteAddrID are the MPTCP address IDs assigned to
+	// the local and remote addresses of this subflow.
+	LocalAddrID  uint8
+	RemoteAddrID uint8
+
+	// Backup reports whether either host marked this subflow as a backup
+	// path, which is only used when no other subflow is available.
+	Backup bool
+}
+
+// Subflows returns the TCP subflows of every active multipath TCP connection
+// to this machine which has a subflow originating from the input host and
+// port.  Subflows are correlated with their connection using its token, so
+// the subflows of a connection are returned even if their remote addresses
+// differ from the input host and port.  If no connections match, Subflows
+// returns no subflows and no error.
+//
+// If host is not a valid IP address, ErrInvalidIPAddress is returned.
+//
+// On Linux, subflows are retrieved using the netlink sock_diag interface of
+// the mainline kernel's MPTCP implementation.  The /proc/net/mptcp
+// connections table of the out-of-tree kernel only reports the number of
+// subflows of each connection.
+//
+// If subflow information is not available on the current operating system,
+// this function will return ErrNotImplemented.
+func Subflows(host string, port uint16) ([]Subflow, error) {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, ErrInvalidIPAddress
+	}
+
+	subflows, err := listAllSubflows()
+	if err != nil {
+		return nil, err
+	}
+
+	return findSubflows(subflows, ip, port), nil
+}
+
+// findSubflows returns the subflows of each connection which has a subflow
+// whose remote address matches the input IP address and port.
+func findSubflows(subflows []Subflow, ip net.IP, port uint16) []Subflow {
+	tokens := make(map[uint32]struct{})
+	for _, sf := range subflows {
+		if sf.RemoteAddr != nil && sf.RemoteAddr.IP.Equal(ip) && sf.RemoteAddr.Port == int(port) {
+			tokens[sf.LocalToken] = struct{}{}
+		}
+	}
+
+	var out []Subflow
+	for _, sf := range subflows {
+		if _, ok := tokens[sf.LocalToken]; ok {
+			out = append(out, sf)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,78 @@
+package mptcp
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// TestSubflows verifies that Subflows returns every subflow of each
+// connection with a subflow originating from the input host and port.
+func TestSubflows(t *testing.T) {
+	var (
+		initial = Subflow{
+			LocalToken: 1,
+			LocalAddr:  &net.TCPAddr{IP: net.ParseIP("192.168.1.10"), Port: 80},
+			RemoteAddr: &net.TCPAddr{IP: net.ParseIP(ipv4HostOne), Port: 2020},
+			State:      StateEstablished,
+		}
+		joined = Subflow{
+			LocalToken:   1,
+			LocalAddr:    &net.TCPAddr{IP: net.ParseIP("10.0.0.10"), Port: 80},
+			RemoteAddr:   &net.TCPAddr{IP: net.ParseIP(ipv4HostTwo), Port: 3030},
+			State:        StateEstablished,
+			LocalAddrID:  1,
+			RemoteAddrID: 2,
+			Backup:       true,
+		}
+		other = Subflow{
+			LocalToken: 2,
+			LocalAddr:  &net.TCPAddr{IP: net.ParseIP("192.168.1.10"), Port: 80},
+			RemoteAddr: &net.TCPAddr{IP: net.ParseIP(ipv4HostOne), Port: 4040},
+			State:      StateEstablished,
+		}
+	)
+
+	origList := listAllSubflows
+	defer func() { listAllSubflows = origList }()
+	listAllSubflows = func() ([]Subflow, error) {
+		return []Subflow{initial, other, joined}, nil
+	}
+
+	var tests = []struct {
+		desc     string
+		host     string
+		port     uint16
+		subflows []Subflow
+		err      error
+	}{
+		{"invalid host", "foo", 2020, nil, ErrInvalidIPAddress},
+		{"no match", ipv4HostOne, 5050, nil, nil},
+		{"initial subflow", ipv4HostOne, 2020, []Subflow{initial, joined}, nil},
+		{"joined subflow", ipv4HostTwo, 3030, []Subflow{initial, joined}, nil},
+		{"other connection", ipv4HostOne, 4040, []Subflow{other}, nil},
+		{"local address", "192.168.1.10", 80, nil, nil},
+	}
+
+	for i, test := range tests {
+		subflows, err := Subflows(test.host, test.port)
+		if err != test.err {
+			t.Fatalf("[%02d] unexpected err: %v != %v [test: %v]", i, err, test.err, test.desc)
+		}
+
+		if !reflect.DeepEqual(subflows, test.subflows) {
+			t.Fatalf("[%02d] unexpected subflows:\n- want: %+v\n-  got: %+v [test: %v]", i, test.subflows, subflows, test.desc)
+		}
+	}
+
+	// Errors retrieving subflows are returned
+	errList := errors.New("list failed")
+	listAllSubflows = func() ([]Subflow, error) {
+		return nil, errList
+	}
+
+	if _, err := Subflows(ipv4HostOne, 2020); err != errList {
+		t.Fatalf("unexpected err: %v != %v", err, errList)
+	}
+}
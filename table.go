@@ -0,0 +1,68 @@
+package mptcp
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// tableHeaders are the column headers returned by ConnectionsTable.  They
+// are named and ordered after the columns of the kernel's connections table,
+// omitting those which are not decoded into a Connection.
+var tableHeaders = []string{
+	"loc_tok",
+	"rem_tok",
+	"v6",
+	"local_address",
+	"remote_address",
+	"st",
+	"ns",
+	"inode",
+}
+
+// ConnectionsTable returns all active multipath TCP connections on this host
+// as a generic grid of human-readable strings, for tools which render
+// arbitrary tables.
+//
+// The headers are named after the columns of the kernel's connections table,
+// and each row contains one connection's decoded values in the same order:
+//   - loc_tok and rem_tok: the MPTCP tokens, in hex
+//   - v6: "true" if the connection uses IPv6, or "false" otherwise
+//   - local_address and remote_address: the TCP addresses, in host:port form
+//   - st: the name of the connection's State
+//   - ns: the number of subflows, in decimal
+//   - inode: the socket inode, in decimal
+//
+// If multipath TCP detection is not implemented for the current operating system,
+// this function will return ErrNotImplemented.
+func ConnectionsTable() (headers []string, rows [][]string, err error) {
+	conns, err := listConnections()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	headers, rows = connectionsTable(conns)
+	return headers, rows, nil
+}
+
+// connectionsTable converts the input connections into table headers and
+// rows, using the column order of tableHeaders.
+func connectionsTable(conns []Connection) ([]string, [][]string) {
+	headers := make([]string, len(tableHeaders))
+	copy(headers, tableHeaders)
+
+	rows := make([][]string, 0, len(conns))
+	for _, c := range conns {
+		rows = append(rows, []string{
+			fmt.Sprintf("%08X", c.LocalToken),
+			fmt.Sprintf("%08X", c.RemoteToken),
+			strconv.FormatBool(c.IsIPv6),
+			c.LocalAddr.String(),
+			c.RemoteAddr.String(),
+			c.State.String(),
+			strconv.Itoa(c.Subflows),
+			strconv.FormatUint(c.Inode, 10),
+		})
+	}
+
+	return headers, rows
+}
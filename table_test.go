@@ -0,0 +1,87 @@
+package mptcp
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// TestConnectionsTable verifies that ConnectionsTable returns the expected
+// headers and decoded row values for an input set of connections.
+func TestConnectionsTable(t *testing.T) {
+	origList := listConnections
+	defer func() { listConnections = origList }()
+
+	listConnections = func() ([]Connection, error) {
+		return []Connection{
+			{
+				LocalToken:  0x9C290BF6,
+				RemoteToken: 0x4CC0A727,
+				LocalAddr:   &net.TCPAddr{IP: net.ParseIP("104.131.14.231").To4(), Port: 22},
+				RemoteAddr:  &net.TCPAddr{IP: net.ParseIP("24.176.52.17").To4(), Port: 48104},
+				State:       StateEstablished,
+				Subflows:    2,
+				Inode:       15666,
+			},
+			{
+				LocalToken:  0xF6635734,
+				RemoteToken: 0x353F1E98,
+				IsIPv6:      true,
+				LocalAddr:   &net.TCPAddr{IP: net.ParseIP("2604:a880:800:10::74:c001"), Port: 8080},
+				RemoteAddr:  &net.TCPAddr{IP: net.ParseIP("2604:a880:800:10::289:2001"), Port: 37797},
+				State:       StateCloseWait,
+				Subflows:    1,
+				Inode:       39893,
+			},
+		}, nil
+	}
+
+	headers, rows, err := ConnectionsTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantHeaders := []string{"loc_tok", "rem_tok", "v6", "local_address", "remote_address", "st", "ns", "inode"}
+	if !reflect.DeepEqual(headers, wantHeaders) {
+		t.Fatalf("unexpected headers: %v != %v", headers, wantHeaders)
+	}
+
+	wantRows := [][]string{
+		{"9C290BF6", "4CC0A727", "false", "104.131.14.231:22", "24.176.52.17:48104", "ESTABLISHED", "2", "15666"},
+		{"F6635734", "353F1E98", "true", "[2604:a880:800:10::74:c001]:8080", "[2604:a880:800:10::289:2001]:37797", "CLOSE_WAIT", "1", "39893"},
+	}
+	if !reflect.DeepEqual(rows, wantRows) {
+		t.Fatalf("unexpected rows:\n%v\n!=\n%v", rows, wantRows)
+	}
+
+	// Each row must have one value per header
+	for i, r := range rows {
+		if len(r) != len(headers) {
+			t.Fatalf("[%02d] unexpected row length: %d != %d", i, len(r), len(headers))
+		}
+	}
+
+	// Modifying the returned headers must not affect later calls
+	headers[0] = "foo"
+	if headers, _, _ = ConnectionsTable(); headers[0] != "loc_tok" {
+		t.Fatalf("headers were modified by caller: %v", headers)
+	}
+}
+
+// TestConnectionsTableError verifies that ConnectionsTable returns any error
+// which occurs while listing connections.
+func TestConnectionsTableError(t *testing.T) {
+	origList := listConnections
+	defer func() { listConnections = origList }()
+
+	errFoo := errors.New("foo")
+	listConnections = func() ([]Connection, error) {
+		return nil, errFoo
+	}
+
+	headers, rows, err := ConnectionsTable()
+	if headers != nil || rows != nil || err != errFoo {
+		t.Fatalf("unexpected result: (%v, %v, %v)", headers, rows, err)
+	}
+}
@@ -0,0 +1,36 @@
+package mptcp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// tableByteOrder is the byte order of the 32-bit address words written in
+// the kernel's connections tables.  The kernel writes each word of a network
+// byte order address as a host integer, so the table format depends on the
+// host's byte order.  Ports are converted to host integers before they are
+// written, so they appear identically on every host.
+//
+// This value is swappable for testing the byte order of other hosts.
+var tableByteOrder binary.ByteOrder = binary.NativeEndian
+
+// ipToHex converts an input IPv4 or IPv6 address into its equivalent hex
+// form, writing each 32-bit word of the address as an integer in the input
+// byte order, exactly as the kernel does.
+func ipToHex(ip net.IP, order binary.ByteOrder) string {
+	var hexHost strings.Builder
+	for i := 0; i < len(ip); i += 4 {
+		fmt.Fprintf(&hexHost, "%08x", order.Uint32(ip[i:i+4]))
+	}
+
+	return hexHost.String()
+}
+
+// u16PortToHex converts an input uint16 port into its equivalent hex form,
+// for use with MPTCP connection lookup.  The kernel writes ports as integers,
+// so their hex form does not depend on the host's byte order.
+func u16PortToHex(port uint16) string {
+	return fmt.Sprintf("%04x", port)
+}
@@ -0,0 +1,196 @@
+package mptcp
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// A WatchDiff describes the changes to the active multipath TCP connections
+// observed between two polls of a Watcher.
+type WatchDiff struct {
+	// Initial reports whether this is the first poll of a Watcher.  On the
+	// first poll, every active connection is reported as added, even if it
+	// was established before the Watcher was created.
+	Initial bool
+
+	// Added and Removed are the connections which appeared and disappeared
+	// since the previous poll, sorted by ID.
+	Added   []Connection
+	Removed []Connection
+}
+
+// Empty reports whether the WatchDiff contains no changes.
+func (d WatchDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0
+}
+
+// DefaultStuckThreshold is the default number of consecutive polls for which
+// a connection must remain in the same state, other than established, for a
+// Watcher to report it as stuck.
+const DefaultStuckThreshold = 3
+
+// A Watcher polls for active multipath TCP connections, and reports the
+// changes between each poll.  A Watcher is safe for concurrent use.
+type Watcher struct {
+	c              *Checker
+	observers      []func(d WatchDiff)
+	stuckThreshold int
+
+	mu    sync.Mutex
+	prev  *ConnectionSet
+	stuck map[string]stuckConnection
+}
+
+// A stuckConnection tracks the number of consecutive polls for which a
+// connection has remained in a state other than established.
+type stuckConnection struct {
+	conn  Connection
+	polls int
+}
+
+// A WatcherOption configures a Watcher.
+type WatcherOption func(w *Watcher)
+
+// WithWatchObserver configures a Watcher to invoke fn with the WatchDiff
+// produced by each successful poll, including polls which observe no
+// changes.  Observers are invoked in the order they are configured.
+func WithWatchObserver(fn func(d WatchDiff)) WatcherOption {
+	return func(w *Watcher) {
+		w.observers = append(w.observers, fn)
+	}
+}
+
+// WithStuckThreshold configures a Watcher to report a connection as stuck
+// once it remains in the same state, other than established, for n
+// consecutive polls.
+//
+// If this option is not set, DefaultStuckThreshold is used.
+func WithStuckThreshold(n int) WatcherOption {
+	return func(w *Watcher) {
+		w.stuckThreshold = n
+	}
+}
+
+// NewWatcher creates a new Watcher which retrieves connections using the
+// input Checker, and is configured using the input options.  If c is nil,
+// the operating system's connections table is used.
+func NewWatcher(c *Checker, options ...WatcherOption) *Watcher {
+	if c == nil {
+		c = defaultChecker
+	}
+
+	w := &Watcher{
+		c:              c,
+		stuckThreshold: DefaultStuckThreshold,
+		stuck:          make(map[string]stuckConnection),
+	}
+
+	for _, o := range options {
+		o(w)
+	}
+
+	return w
+}
+
+// Poll retrieves the active connections, and returns the changes since the
+// previous poll.  If an error occurs, the previous poll is retained so that
+// the next successful poll reports every change since then.
+func (w *Watcher) Poll() (WatchDiff, error) {
+	conns, err := w.c.ListConnections()
+	if err != nil {
+		return WatchDiff{}, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// Compare against the previous poll, or an empty set for the first poll
+	next := NewConnectionSet(conns...)
+	prev := w.prev
+	if prev == nil {
+		prev = NewConnectionSet()
+	}
+
+	d := WatchDiff{
+		Initial: w.prev == nil,
+		Added:   next.Difference(prev).Connections(),
+		Removed: prev.Difference(next).Connections(),
+	}
+	w.prev = next
+	w.trackStuck(conns)
+
+	for _, fn := range w.observers {
+		fn(d)
+	}
+
+	return d, nil
+}
+
+// trackStuck updates the number of consecutive polls for which each of the
+// input connections has remained in the same state, other than established.
+// The caller must hold w.mu.
+func (w *Watcher) trackStuck(conns []Connection) {
+	stuck := make(map[string]stuckConnection)
+	for _, c := range conns {
+		// Connections which are established, or whose state is not
+		// reported, cannot be stuck
+		if c.State == StateEstablished || c.State == StateUnknown {
+			continue
+		}
+
+		id := c.ID()
+		sc := stuckConnection{conn: c, polls: 1}
+		if prev, ok := w.stuck[id]; ok && prev.conn.State == c.State {
+			sc.polls = prev.polls + 1
+		}
+
+		stuck[id] = sc
+	}
+
+	w.stuck = stuck
+}
+
+// StuckConnections returns the connections which have remained in the same
+// state, other than established, for at least the stuck threshold number of
+// consecutive polls, sorted by ID.  Such connections may indicate failing
+// handshakes.
+func (w *Watcher) StuckConnections() []Connection {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	set := NewConnectionSet()
+	for _, sc := range w.stuck {
+		if sc.polls >= w.stuckThreshold {
+			set.Add(sc.conn)
+		}
+	}
+
+	return set.Connections()
+}
+
+// Run polls for active connections immediately, and then at each interval,
+// invoking fn with each WatchDiff which is not empty.  Run blocks until the
+// context is canceled or a poll returns an error, and returns the context's
+// error or the poll error.
+func (w *Watcher) Run(ctx context.Context, interval time.Duration, fn func(d WatchDiff)) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		d, err := w.Poll()
+		if err != nil {
+			return err
+		}
+
+		if !d.Empty() {
+			fn(d)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package mptcp
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestWatcherPoll verifies that a Watcher reports the connections added and
+// removed between each poll, and retains its previous poll on error.
+func TestWatcherPoll(t *testing.T) {
+	errPoll := errors.New("poll failed")
+
+	var tests = []struct {
+		desc    string
+		conns   []Connection
+		err     error
+		initial bool
+		added   []Connection
+		removed []Connection
+	}{
+		{"initial", []Connection{testSetConnA, testSetConnB}, nil, true, []Connection{testSetConnA, testSetConnB}, nil},
+		{"unchanged", []Connection{testSetConnB, testSetConnA}, nil, false, nil, nil},
+		{"error", nil, errPoll, false, nil, nil},
+		{"added and removed", []Connection{testSetConnB, testSetConnC}, nil, false, []Connection{testSetConnC}, []Connection{testSetConnA}},
+		{"all removed", nil, nil, false, nil, []Connection{testSetConnB, testSetConnC}},
+	}
+
+	var (
+		i        int
+		observed []WatchDiff
+	)
+
+	source := SourceFunc(func() ([]Connection, error) {
+		return tests[i].conns, tests[i].err
+	})
+	w := NewWatcher(NewChecker(WithSources(source)), WithWatchObserver(func(d WatchDiff) {
+		observed = append(observed, d)
+	}))
+
+	for ; i < len(tests); i++ {
+		test := tests[i]
+
+		d, err := w.Poll()
+		if !errors.Is(err, test.err) {
+			t.Fatalf("[%02d] unexpected err: %v != %v [test: %v]", i, err, test.err, test.desc)
+		}
+		if err != nil {
+			continue
+		}
+
+		if d.Initial != test.initial {
+			t.Fatalf("[%02d] unexpected initial: %v != %v [test: %v]", i, d.Initial, test.initial, test.desc)
+		}
+
+		if len(d.Added) != 0 || len(test.added) != 0 {
+			if !reflect.DeepEqual(d.Added, test.added) {
+				t.Fatalf("[%02d] unexpected added:\n- want: %v\n-  got: %v", i, test.added, d.Added)
+			}
+		}
+
+		if len(d.Removed) != 0 || len(test.removed) != 0 {
+			if !reflect.DeepEqual(d.Removed, test.removed) {
+				t.Fatalf("[%02d] unexpected removed:\n- want: %v\n-  got: %v", i, test.removed, d.Removed)
+			}
+		}
+	}
+
+	// Observers are invoked for every successful poll
+	if l := len(observed); l != len(tests)-1 {
+		t.Fatalf("unexpected observed diff count: %v != %v", l, len(tests)-1)
+	}
+}
+
+// TestWatcherRun verifies that Watcher.Run reports only non-empty diffs, and
+// returns when its context is canceled.
+func TestWatcherRun(t *testing.T) {
+	source := SourceFunc(func() ([]Connection, error) {
+		return []Connection{testSetConnA}, nil
+	})
+	w := NewWatcher(NewChecker(WithSources(source)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var diffs []WatchDiff
+	err := w.Run(ctx, time.Millisecond, func(d WatchDiff) {
+		diffs = append(diffs, d)
+
+		// Stop once the initial diff has been reported
+		cancel()
+	})
+	if err != context.Canceled {
+		t.Fatalf("unexpected err: %v != %v", err, context.Canceled)
+	}
+
+	if l := len(diffs); l != 1 {
+		t.Fatalf("unexpected diff count: %v != %v", l, 1)
+	}
+	if !diffs[0].Initial {
+		t.Fatal("expected initial diff")
+	}
+}
+
+// TestWatcherStuckConnections verifies that a Watcher reports connections
+// which remain in the same non-established state for the stuck threshold
+// number of consecutive polls.
+func TestWatcherStuckConnections(t *testing.T) {
+	synSent := testSetConnA
+	synSent.State = StateSynSent
+
+	finWait := testSetConnB
+	finWait.State = StateFinWait1
+
+	established := testSetConnC
+	established.State = StateEstablished
+
+	establishedA := synSent
+	establishedA.State = StateEstablished
+
+	var conns []Connection
+	source := SourceFunc(func() ([]Connection, error) {
+		return conns, nil
+	})
+	w := NewWatcher(NewChecker(WithSources(source)), WithStuckThreshold(3))
+
+	var tests = []struct {
+		desc  string
+		conns []Connection
+		stuck []Connection
+	}{
+		{"first poll", []Connection{synSent, established}, nil},
+		{"second poll", []Connection{synSent, established}, nil},
+		{"stuck in SYN_SENT", []Connection{synSent, established, finWait}, []Connection{synSent}},
+		{"still stuck", []Connection{synSent, established, finWait}, []Connection{synSent}},
+		{"both stuck", []Connection{synSent, established, finWait}, []Connection{synSent, finWait}},
+		{"handshake completes", []Connection{establishedA, established, finWait}, []Connection{finWait}},
+		{"state change resets", []Connection{synSent, established}, nil},
+	}
+
+	for i, test := range tests {
+		conns = test.conns
+		if _, err := w.Poll(); err != nil {
+			t.Fatalf("[%02d] unexpected err: %v", i, err)
+		}
+
+		stuck := w.StuckConnections()
+		if len(stuck) != 0 || len(test.stuck) != 0 {
+			if !reflect.DeepEqual(stuck, test.stuck) {
+				t.Fatalf("[%02d] unexpected stuck connections:\n- want: %v\n-  got: %v [test: %v]", i, test.stuck, stuck, test.desc)
+			}
+		}
+	}
+}
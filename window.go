@@ -0,0 +1,102 @@
+package mptcp
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// A WindowTracker records the remote peers of multipath TCP connections
+// observed by a Watcher over a rolling time window, so that short-lived
+// connections missed by a point-in-time Check can still be detected.  A
+// WindowTracker is safe for concurrent use.
+//
+// To record observations, pass the Observe method to a Watcher using
+// WithWatchObserver.
+type WindowTracker struct {
+	window time.Duration
+
+	// now is the clock used to record observations, swappable for tests.
+	now func() time.Time
+
+	mu     sync.Mutex
+	active map[string]int
+	seen   map[string]time.Time
+}
+
+// NewWindowTracker creates a new WindowTracker which retains observations
+// for the input window of time.
+func NewWindowTracker(window time.Duration) *WindowTracker {
+	return &WindowTracker{
+		window: window,
+		now:    time.Now,
+		active: make(map[string]int),
+		seen:   make(map[string]time.Time),
+	}
+}
+
+// Observe records the remote peers of the connections in the input WatchDiff.
+// Peers with connections which remain active are considered seen until their
+// last connection is removed.  Observations older than the window are
+// discarded.
+func (wt *WindowTracker) Observe(d WatchDiff) {
+	now := wt.now()
+
+	wt.mu.Lock()
+	defer wt.mu.Unlock()
+
+	for _, c := range d.Added {
+		if c.RemoteAddr == nil {
+			continue
+		}
+
+		host := c.RemoteAddr.IP.String()
+		wt.active[host]++
+		wt.seen[host] = now
+	}
+
+	for _, c := range d.Removed {
+		if c.RemoteAddr == nil {
+			continue
+		}
+
+		host := c.RemoteAddr.IP.String()
+		if wt.active[host]--; wt.active[host] <= 0 {
+			delete(wt.active, host)
+		}
+		wt.seen[host] = now
+	}
+
+	// Discard peers which have not been seen within the window
+	for host, t := range wt.seen {
+		if _, ok := wt.active[host]; !ok && now.Sub(t) > wt.window {
+			delete(wt.seen, host)
+		}
+	}
+}
+
+// SeenMPTCP reports whether a multipath TCP connection from the input host
+// has been observed within the input duration, or is still active.  The
+// duration is limited to the WindowTracker's window.  If host is not a valid
+// IP address, SeenMPTCP returns false.
+func (wt *WindowTracker) SeenMPTCP(host string, within time.Duration) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	key := ip.String()
+
+	if within > wt.window {
+		within = wt.window
+	}
+
+	wt.mu.Lock()
+	defer wt.mu.Unlock()
+
+	if _, ok := wt.active[key]; ok {
+		return true
+	}
+
+	t, ok := wt.seen[key]
+	return ok && wt.now().Sub(t) <= within
+}
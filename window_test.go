@@ -0,0 +1,61 @@
+package mptcp
+
+import (
+	"testing"
+	"time"
+)
+
+// TestWindowTracker verifies that a WindowTracker driven by a Watcher reports
+// recently seen peers, and expires observations older than its window, using
+// a fake clock.
+func TestWindowTracker(t *testing.T) {
+	var conns []Connection
+	source := SourceFunc(func() ([]Connection, error) {
+		return conns, nil
+	})
+
+	// Fake clock, advanced manually by the test
+	now := time.Date(2016, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	wt := NewWindowTracker(time.Minute)
+	wt.now = func() time.Time { return now }
+
+	w := NewWatcher(NewChecker(WithSources(source)), WithWatchObserver(wt.Observe))
+
+	var tests = []struct {
+		desc    string
+		advance time.Duration
+		conns   []Connection
+		host    string
+		within  time.Duration
+		seen    bool
+	}{
+		{"never seen", 0, nil, ipv4HostOne, time.Minute, false},
+		{"active connection", time.Second, []Connection{testSetConnA}, ipv4HostOne, time.Minute, true},
+		{"still active after window", 2 * time.Minute, []Connection{testSetConnA}, ipv4HostOne, time.Second, true},
+		{"short-lived connection", time.Second, []Connection{testSetConnB}, ipv4HostTwo, time.Minute, true},
+		{"removed connection in window", 10 * time.Second, nil, ipv4HostOne, time.Minute, true},
+		{"removed connection outside duration", 10 * time.Second, nil, ipv4HostOne, 5 * time.Second, false},
+		{"duration limited to window", 55 * time.Second, nil, ipv4HostOne, time.Hour, false},
+		{"expired observation", time.Minute, nil, ipv4HostTwo, time.Hour, false},
+		{"invalid host", 0, nil, badIPHostOne, time.Minute, false},
+	}
+
+	for i, test := range tests {
+		now = now.Add(test.advance)
+		conns = test.conns
+
+		if _, err := w.Poll(); err != nil {
+			t.Fatalf("[%02d] unexpected err: %v", i, err)
+		}
+
+		if seen := wt.SeenMPTCP(test.host, test.within); seen != test.seen {
+			t.Fatalf("[%02d] unexpected seen: %v != %v [test: %v]", i, seen, test.seen, test.desc)
+		}
+	}
+
+	// Expired observations are discarded
+	if l := len(wt.seen); l != 0 {
+		t.Fatalf("unexpected retained observations: %v != %v", l, 0)
+	}
+}